@@ -0,0 +1,15 @@
+package dao
+
+import (
+	"context"
+	"wxcloudrun-golang/db/model"
+)
+
+// AdminAuditDao 后台操作审计日志数据访问接口
+type AdminAuditDao interface {
+	// Create 记录审计日志
+	Create(ctx context.Context, log *model.AdminAuditLogModel) error
+
+	// GetList 分页获取审计日志，operatorId为0、action为空时不过滤
+	GetList(ctx context.Context, operatorId int64, action string, page, pageSize int) ([]*model.AdminAuditLogModel, int64, error)
+}
@@ -0,0 +1,49 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"wxcloudrun-golang/db"
+	"wxcloudrun-golang/db/model"
+)
+
+// AdminAuditDaoImpl 后台操作审计日志数据访问实现
+type AdminAuditDaoImpl struct {
+	db *gorm.DB
+}
+
+// NewAdminAuditDao 创建审计日志DAO实例
+func NewAdminAuditDao() AdminAuditDao {
+	return &AdminAuditDaoImpl{db: db.GetDB()}
+}
+
+// Create 记录审计日志
+func (d *AdminAuditDaoImpl) Create(ctx context.Context, log *model.AdminAuditLogModel) error {
+	return d.db.WithContext(ctx).Create(log).Error
+}
+
+// GetList 分页获取审计日志
+func (d *AdminAuditDaoImpl) GetList(ctx context.Context, operatorId int64, action string, page, pageSize int) ([]*model.AdminAuditLogModel, int64, error) {
+	var logs []*model.AdminAuditLogModel
+	var total int64
+
+	query := d.db.WithContext(ctx).Model(&model.AdminAuditLogModel{})
+	if operatorId > 0 {
+		query = query.Where("operator_id = ?", operatorId)
+	}
+	if action != "" {
+		query = query.Where("action = ?", action)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&logs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return logs, total, nil
+}
@@ -0,0 +1,27 @@
+package dao
+
+import (
+	"context"
+	"wxcloudrun-golang/db/model"
+)
+
+// BlockedKeywordDao 关键词词库数据访问接口
+type BlockedKeywordDao interface {
+	// Create 添加关键词
+	Create(ctx context.Context, keyword *model.BlockedKeywordModel) error
+
+	// GetById 根据ID获取关键词
+	GetById(ctx context.Context, id int64) (*model.BlockedKeywordModel, error)
+
+	// Update 更新关键词
+	Update(ctx context.Context, keyword *model.BlockedKeywordModel) error
+
+	// Delete 删除关键词
+	Delete(ctx context.Context, id int64) error
+
+	// GetAll 获取全部关键词，用于构建匹配器
+	GetAll(ctx context.Context) ([]*model.BlockedKeywordModel, error)
+
+	// GetList 分页查询关键词，word不为空时按关键词或变体模糊匹配
+	GetList(ctx context.Context, word string, page, pageSize int) ([]*model.BlockedKeywordModel, int64, error)
+}
@@ -0,0 +1,68 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"wxcloudrun-golang/db"
+	"wxcloudrun-golang/db/model"
+)
+
+// BlockedKeywordDaoImpl 关键词词库数据访问实现
+type BlockedKeywordDaoImpl struct {
+	db *gorm.DB
+}
+
+// NewBlockedKeywordDao 创建关键词词库DAO实例
+func NewBlockedKeywordDao() BlockedKeywordDao {
+	return &BlockedKeywordDaoImpl{db: db.GetDB()}
+}
+
+// Create 添加关键词
+func (d *BlockedKeywordDaoImpl) Create(ctx context.Context, keyword *model.BlockedKeywordModel) error {
+	return d.db.WithContext(ctx).Create(keyword).Error
+}
+
+// GetById 根据ID获取关键词
+func (d *BlockedKeywordDaoImpl) GetById(ctx context.Context, id int64) (*model.BlockedKeywordModel, error) {
+	var keyword model.BlockedKeywordModel
+	if err := d.db.WithContext(ctx).Where("id = ?", id).First(&keyword).Error; err != nil {
+		return nil, err
+	}
+	return &keyword, nil
+}
+
+// Update 更新关键词
+func (d *BlockedKeywordDaoImpl) Update(ctx context.Context, keyword *model.BlockedKeywordModel) error {
+	return d.db.WithContext(ctx).Save(keyword).Error
+}
+
+// Delete 删除关键词
+func (d *BlockedKeywordDaoImpl) Delete(ctx context.Context, id int64) error {
+	return d.db.WithContext(ctx).Where("id = ?", id).Delete(&model.BlockedKeywordModel{}).Error
+}
+
+// GetAll 获取全部关键词
+func (d *BlockedKeywordDaoImpl) GetAll(ctx context.Context) ([]*model.BlockedKeywordModel, error) {
+	var keywords []*model.BlockedKeywordModel
+	err := d.db.WithContext(ctx).Order("id ASC").Find(&keywords).Error
+	return keywords, err
+}
+
+// GetList 分页查询关键词
+func (d *BlockedKeywordDaoImpl) GetList(ctx context.Context, word string, page, pageSize int) ([]*model.BlockedKeywordModel, int64, error) {
+	var keywords []*model.BlockedKeywordModel
+	var total int64
+
+	query := d.db.WithContext(ctx).Model(&model.BlockedKeywordModel{})
+	if word != "" {
+		like := "%" + word + "%"
+		query = query.Where("word LIKE ? OR variants LIKE ?", like, like)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&keywords).Error
+	return keywords, total, err
+}
@@ -1,35 +1,48 @@
 package dao
 
 import (
+	"context"
 	"wxcloudrun-golang/db/model"
 )
 
 // CategoryDao 分类数据访问接口
 type CategoryDao interface {
 	// 创建分类
-	Create(category *model.CategoryModel) error
+	Create(ctx context.Context, category *model.CategoryModel) error
 	
 	// 根据ID获取分类
-	GetById(id string) (*model.CategoryModel, error)
+	GetById(ctx context.Context, id string) (*model.CategoryModel, error)
 	
 	// 根据代码获取分类
-	GetByCode(code string) (*model.CategoryModel, error)
+	GetByCode(ctx context.Context, code string) (*model.CategoryModel, error)
 	
 	// 获取所有分类
-	GetAll() ([]*model.CategoryModel, error)
+	GetAll(ctx context.Context) ([]*model.CategoryModel, error)
+	
+	// 获取全部分类（含已停用），用于后台管理
+	GetAllForAdmin(ctx context.Context) ([]*model.CategoryModel, error)
 	
 	// 获取可用于发布的分类
-	GetForPublish() ([]*model.CategoryModel, error)
+	GetForPublish(ctx context.Context) ([]*model.CategoryModel, error)
 	
 	// 更新分类
-	Update(category *model.CategoryModel) error
+	Update(ctx context.Context, category *model.CategoryModel) error
 	
 	// 删除分类
-	Delete(id string) error
+	Delete(ctx context.Context, id string) error
+	
+	// 重命名分类，同时更新帖子中冗余的分类名称
+	Rename(ctx context.Context, code, name string) error
+	
+	// 按给定顺序重新设置分类排序值
+	UpdateSort(ctx context.Context, codes []string) error
+	
+	// 将分类下的帖子全部移动到目标分类并删除原分类
+	MergeInto(ctx context.Context, from *model.CategoryModel, to *model.CategoryModel) (int64, error)
 	
 	// 增加帖子数量
-	IncrementPostCount(code string) error
+	IncrementPostCount(ctx context.Context, code string) error
 	
 	// 减少帖子数量
-	DecrementPostCount(code string) error
+	DecrementPostCount(ctx context.Context, code string) error
 } 
\ No newline at end of file
@@ -1,6 +1,7 @@
 package dao
 
 import (
+	"context"
 	"gorm.io/gorm"
 	"wxcloudrun-golang/db"
 	"wxcloudrun-golang/db/model"
@@ -17,14 +18,14 @@ func NewCategoryDao() CategoryDao {
 }
 
 // Create 创建分类
-func (dao *CategoryDaoImpl) Create(category *model.CategoryModel) error {
-	return dao.db.Create(category).Error
+func (dao *CategoryDaoImpl) Create(ctx context.Context, category *model.CategoryModel) error {
+	return dao.db.WithContext(ctx).Create(category).Error
 }
 
 // GetById 根据ID获取分类
-func (dao *CategoryDaoImpl) GetById(id string) (*model.CategoryModel, error) {
+func (dao *CategoryDaoImpl) GetById(ctx context.Context, id string) (*model.CategoryModel, error) {
 	var category model.CategoryModel
-	err := dao.db.Where("id = ?", id).First(&category).Error
+	err := dao.db.WithContext(ctx).Where("id = ?", id).First(&category).Error
 	if err != nil {
 		return nil, err
 	}
@@ -32,9 +33,9 @@ func (dao *CategoryDaoImpl) GetById(id string) (*model.CategoryModel, error) {
 }
 
 // GetByCode 根据代码获取分类
-func (dao *CategoryDaoImpl) GetByCode(code string) (*model.CategoryModel, error) {
+func (dao *CategoryDaoImpl) GetByCode(ctx context.Context, code string) (*model.CategoryModel, error) {
 	var category model.CategoryModel
-	err := dao.db.Where("code = ?", code).First(&category).Error
+	err := dao.db.WithContext(ctx).Where("code = ?", code).First(&category).Error
 	if err != nil {
 		return nil, err
 	}
@@ -42,9 +43,19 @@ func (dao *CategoryDaoImpl) GetByCode(code string) (*model.CategoryModel, error)
 }
 
 // GetAll 获取所有分类
-func (dao *CategoryDaoImpl) GetAll() ([]*model.CategoryModel, error) {
+func (dao *CategoryDaoImpl) GetAll(ctx context.Context) ([]*model.CategoryModel, error) {
 	var categories []*model.CategoryModel
-	err := dao.db.Where("is_active = ?", true).Order("sort ASC, post_count DESC").Find(&categories).Error
+	err := dao.db.WithContext(ctx).Where("is_active = ?", true).Order("sort ASC, post_count DESC").Find(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// GetAllForAdmin 获取全部分类（含已停用）
+func (dao *CategoryDaoImpl) GetAllForAdmin(ctx context.Context) ([]*model.CategoryModel, error) {
+	var categories []*model.CategoryModel
+	err := dao.db.WithContext(ctx).Order("sort ASC, id ASC").Find(&categories).Error
 	if err != nil {
 		return nil, err
 	}
@@ -52,9 +63,9 @@ func (dao *CategoryDaoImpl) GetAll() ([]*model.CategoryModel, error) {
 }
 
 // GetForPublish 获取可用于发布的分类
-func (dao *CategoryDaoImpl) GetForPublish() ([]*model.CategoryModel, error) {
+func (dao *CategoryDaoImpl) GetForPublish(ctx context.Context) ([]*model.CategoryModel, error) {
 	var categories []*model.CategoryModel
-	err := dao.db.Where("is_active = ? AND code != ?", true, "all").Order("sort ASC").Find(&categories).Error
+	err := dao.db.WithContext(ctx).Where("is_active = ? AND code != ?", true, "all").Order("sort ASC").Find(&categories).Error
 	if err != nil {
 		return nil, err
 	}
@@ -62,21 +73,68 @@ func (dao *CategoryDaoImpl) GetForPublish() ([]*model.CategoryModel, error) {
 }
 
 // Update 更新分类
-func (dao *CategoryDaoImpl) Update(category *model.CategoryModel) error {
-	return dao.db.Save(category).Error
+func (dao *CategoryDaoImpl) Update(ctx context.Context, category *model.CategoryModel) error {
+	return dao.db.WithContext(ctx).Save(category).Error
 }
 
 // Delete 删除分类
-func (dao *CategoryDaoImpl) Delete(id string) error {
-	return dao.db.Where("id = ?", id).Delete(&model.CategoryModel{}).Error
+func (dao *CategoryDaoImpl) Delete(ctx context.Context, id string) error {
+	return dao.db.WithContext(ctx).Where("id = ?", id).Delete(&model.CategoryModel{}).Error
+}
+
+// Rename 重命名分类，同时更新帖子中冗余的分类名称
+func (dao *CategoryDaoImpl) Rename(ctx context.Context, code, name string) error {
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.CategoryModel{}).Where("code = ?", code).Update("name", name).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.PostModel{}).Where("category = ?", code).Update("category_name", name).Error
+	})
+}
+
+// UpdateSort 按给定顺序重新设置分类排序值（从1开始）
+func (dao *CategoryDaoImpl) UpdateSort(ctx context.Context, codes []string) error {
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i, code := range codes {
+			result := tx.Model(&model.CategoryModel{}).Where("code = ?", code).Update("sort", i+1)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return gorm.ErrRecordNotFound
+			}
+		}
+		return nil
+	})
+}
+
+// MergeInto 将分类下的帖子全部移动到目标分类并删除原分类，返回移动的帖子数
+func (dao *CategoryDaoImpl) MergeInto(ctx context.Context, from *model.CategoryModel, to *model.CategoryModel) (int64, error) {
+	var moved int64
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.PostModel{}).Where("category = ?", from.Code).
+			Updates(map[string]interface{}{"category": to.Code, "category_name": to.Name})
+		if result.Error != nil {
+			return result.Error
+		}
+		moved = result.RowsAffected
+
+		err := tx.Model(&model.CategoryModel{}).Where("code = ?", to.Code).
+			UpdateColumn("post_count", gorm.Expr("post_count + ?", from.PostCount)).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("code = ?", from.Code).Delete(&model.CategoryModel{}).Error
+	})
+	return moved, err
 }
 
 // IncrementPostCount 增加帖子数量
-func (dao *CategoryDaoImpl) IncrementPostCount(code string) error {
-	return dao.db.Model(&model.CategoryModel{}).Where("code = ?", code).UpdateColumn("post_count", gorm.Expr("post_count + ?", 1)).Error
+func (dao *CategoryDaoImpl) IncrementPostCount(ctx context.Context, code string) error {
+	return dao.db.WithContext(ctx).Model(&model.CategoryModel{}).Where("code = ?", code).UpdateColumn("post_count", gorm.Expr("post_count + ?", 1)).Error
 }
 
 // DecrementPostCount 减少帖子数量
-func (dao *CategoryDaoImpl) DecrementPostCount(code string) error {
-	return dao.db.Model(&model.CategoryModel{}).Where("code = ?", code).UpdateColumn("post_count", gorm.Expr("post_count - ?", 1)).Error
+func (dao *CategoryDaoImpl) DecrementPostCount(ctx context.Context, code string) error {
+	return dao.db.WithContext(ctx).Model(&model.CategoryModel{}).Where("code = ?", code).UpdateColumn("post_count", gorm.Expr("post_count - ?", 1)).Error
 } 
\ No newline at end of file
@@ -1,29 +1,43 @@
 package dao
 
 import (
+	"context"
+	"time"
 	"wxcloudrun-golang/db/model"
 )
 
 // CommentDao 评论数据访问接口
 type CommentDao interface {
 	// 创建评论
-	Create(comment *model.CommentModel) error
+	Create(ctx context.Context, comment *model.CommentModel) error
+	
+	// 在同一事务中创建语音评论、语音检测记录和检测任务，newJob根据已写入的检测记录生成任务
+	CreateWithMediaCheck(ctx context.Context, comment *model.CommentModel, check *model.MediaCheckModel, newJob func(check *model.MediaCheckModel) (*model.JobModel, error)) error
 	
 	// 根据ID获取评论
-	GetById(id int64) (*model.CommentModel, error)
+	GetById(ctx context.Context, id int64) (*model.CommentModel, error)
 	
-	// 获取帖子评论列表
-	GetByPostId(postId int64, page, pageSize int) ([]*model.CommentModel, int64, error)
+	// 获取帖子评论列表，viewerId为当前用户ID，用于展示其本人待审核/静默隐藏的评论并排除其拉黑用户的评论
+	GetByPostId(ctx context.Context, postId int64, viewerId int64, page, pageSize int) ([]*model.CommentModel, int64, error)
 	
 	// 更新评论
-	Update(comment *model.CommentModel) error
+	Update(ctx context.Context, comment *model.CommentModel) error
+	
+	// 更新审核状态
+	UpdateModerationStatus(ctx context.Context, id int64, status int, reason string) error
+	
+	// 将待审核的评论改为正常状态，返回false表示评论已不是待审核状态（例如已被其他审核结论公开）
+	ReleasePending(ctx context.Context, id int64) (bool, error)
 	
 	// 删除评论
-	Delete(id int64) error
+	Delete(ctx context.Context, id int64) error
 	
 	// 增加点赞数
-	IncrementLikes(id int64) error
+	IncrementLikes(ctx context.Context, id int64) error
 	
 	// 减少点赞数
-	DecrementLikes(id int64) error
+	DecrementLikes(ctx context.Context, id int64) error
+	
+	// 获取用户最近发表的评论（含待审核/隐藏）
+	GetRecentByAuthor(ctx context.Context, authorId int64, since time.Time, limit int) ([]*model.CommentModel, error)
 } 
\ No newline at end of file
@@ -1,6 +1,8 @@
 package dao
 
 import (
+	"context"
+	"time"
 	"gorm.io/gorm"
 	"wxcloudrun-golang/db"
 	"wxcloudrun-golang/db/model"
@@ -17,14 +19,33 @@ func NewCommentDao() CommentDao {
 }
 
 // Create 创建评论
-func (dao *CommentDaoImpl) Create(comment *model.CommentModel) error {
-	return dao.db.Create(comment).Error
+func (dao *CommentDaoImpl) Create(ctx context.Context, comment *model.CommentModel) error {
+	return dao.db.WithContext(ctx).Create(comment).Error
+}
+
+// CreateWithMediaCheck 在同一事务中创建评论、语音检测记录和检测任务
+func (dao *CommentDaoImpl) CreateWithMediaCheck(ctx context.Context, comment *model.CommentModel, check *model.MediaCheckModel, newJob func(check *model.MediaCheckModel) (*model.JobModel, error)) error {
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+		check.PostId = comment.PostId
+		check.CommentId = comment.Id
+		if err := tx.Create(check).Error; err != nil {
+			return err
+		}
+		job, err := newJob(check)
+		if err != nil {
+			return err
+		}
+		return tx.Create(job).Error
+	})
 }
 
 // GetById 根据ID获取评论
-func (dao *CommentDaoImpl) GetById(id int64) (*model.CommentModel, error) {
+func (dao *CommentDaoImpl) GetById(ctx context.Context, id int64) (*model.CommentModel, error) {
 	var comment model.CommentModel
-	err := dao.db.Where("id = ?", id).First(&comment).Error
+	err := dao.db.WithContext(ctx).Where("id = ?", id).First(&comment).Error
 	if err != nil {
 		return nil, err
 	}
@@ -32,11 +53,20 @@ func (dao *CommentDaoImpl) GetById(id int64) (*model.CommentModel, error) {
 }
 
 // GetByPostId 获取帖子评论列表
-func (dao *CommentDaoImpl) GetByPostId(postId int64, page, pageSize int) ([]*model.CommentModel, int64, error) {
+func (dao *CommentDaoImpl) GetByPostId(ctx context.Context, postId int64, viewerId int64, page, pageSize int) ([]*model.CommentModel, int64, error) {
 	var comments []*model.CommentModel
 	var total int64
 	
-	query := dao.db.Model(&model.CommentModel{}).Where("post_id = ? AND parent_id IS NULL", postId)
+	query := dao.db.WithContext(ctx).Model(&model.CommentModel{}).Where("post_id = ? AND parent_id IS NULL", postId)
+	
+	// 待审核/静默隐藏的评论只对作者本人可见
+	query = query.Where("(moderation_status = ? OR (moderation_status IN ? AND author_id = ?))",
+		model.ModerationStatusNormal, []int{model.ModerationStatusPending, model.ModerationStatusShadowHidden}, viewerId)
+	
+	// 不展示当前用户拉黑的作者的评论
+	if viewerId > 0 {
+		query = query.Where("author_id NOT IN (?)", blockedAuthorIds(dao.db.WithContext(ctx), viewerId))
+	}
 	
 	// 获取总数
 	err := query.Count(&total).Error
@@ -55,21 +85,49 @@ func (dao *CommentDaoImpl) GetByPostId(postId int64, page, pageSize int) ([]*mod
 }
 
 // Update 更新评论
-func (dao *CommentDaoImpl) Update(comment *model.CommentModel) error {
-	return dao.db.Save(comment).Error
+func (dao *CommentDaoImpl) Update(ctx context.Context, comment *model.CommentModel) error {
+	return dao.db.WithContext(ctx).Save(comment).Error
+}
+
+// UpdateModerationStatus 更新审核状态
+func (dao *CommentDaoImpl) UpdateModerationStatus(ctx context.Context, id int64, status int, reason string) error {
+	return dao.db.WithContext(ctx).Model(&model.CommentModel{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"moderation_status": status, "moderation_reason": reason}).Error
+}
+
+// ReleasePending 将待审核的评论改为正常状态
+func (dao *CommentDaoImpl) ReleasePending(ctx context.Context, id int64) (bool, error) {
+	result := dao.db.WithContext(ctx).Model(&model.CommentModel{}).
+		Where("id = ? AND moderation_status = ?", id, model.ModerationStatusPending).
+		Updates(map[string]interface{}{"moderation_status": model.ModerationStatusNormal, "moderation_reason": ""})
+	return result.RowsAffected > 0, result.Error
 }
 
 // Delete 删除评论
-func (dao *CommentDaoImpl) Delete(id int64) error {
-	return dao.db.Where("id = ?", id).Delete(&model.CommentModel{}).Error
+func (dao *CommentDaoImpl) Delete(ctx context.Context, id int64) error {
+	return dao.db.WithContext(ctx).Where("id = ?", id).Delete(&model.CommentModel{}).Error
 }
 
 // IncrementLikes 增加点赞数
-func (dao *CommentDaoImpl) IncrementLikes(id int64) error {
-	return dao.db.Model(&model.CommentModel{}).Where("id = ?", id).UpdateColumn("likes", gorm.Expr("likes + ?", 1)).Error
+func (dao *CommentDaoImpl) IncrementLikes(ctx context.Context, id int64) error {
+	return dao.db.WithContext(ctx).Model(&model.CommentModel{}).Where("id = ?", id).UpdateColumn("likes", gorm.Expr("likes + ?", 1)).Error
 }
 
 // DecrementLikes 减少点赞数
-func (dao *CommentDaoImpl) DecrementLikes(id int64) error {
-	return dao.db.Model(&model.CommentModel{}).Where("id = ?", id).UpdateColumn("likes", gorm.Expr("likes - ?", 1)).Error
-} 
\ No newline at end of file
+func (dao *CommentDaoImpl) DecrementLikes(ctx context.Context, id int64) error {
+	return dao.db.WithContext(ctx).Model(&model.CommentModel{}).Where("id = ?", id).UpdateColumn("likes", gorm.Expr("likes - ?", 1)).Error
+} 
+
+// GetRecentByAuthor 获取用户最近发表的评论（含待审核/隐藏）
+func (dao *CommentDaoImpl) GetRecentByAuthor(ctx context.Context, authorId int64, since time.Time, limit int) ([]*model.CommentModel, error) {
+	var comments []*model.CommentModel
+	err := dao.db.WithContext(ctx).
+		Where("author_id = ? AND created_at >= ?", authorId, since).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&comments).Error
+	if err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
@@ -0,0 +1,28 @@
+package dao
+
+import (
+	"context"
+	"wxcloudrun-golang/db/model"
+)
+
+// ContentCheckFilter 内容检测记录查询条件，零值字段不过滤
+type ContentCheckFilter struct {
+	UserId     int64
+	PostId     int64
+	TargetType string
+	TargetId   int64
+	Kind       string
+	Suggest    string
+}
+
+// ContentCheckDao 内容检测记录数据访问接口
+type ContentCheckDao interface {
+	// CreateBatch 批量写入检测记录
+	CreateBatch(ctx context.Context, checks []*model.ContentCheckModel) error
+
+	// UpdateResultByTraceId 根据trace_id写入异步检测结果，并计算从提交到收到结果的耗时
+	UpdateResultByTraceId(ctx context.Context, traceId, suggest string, label int, prob float64, errcode int, errmsg string) error
+
+	// GetList 分页查询检测记录
+	GetList(ctx context.Context, filter ContentCheckFilter, page, pageSize int) ([]*model.ContentCheckModel, int64, error)
+}
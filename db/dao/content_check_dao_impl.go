@@ -0,0 +1,83 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"time"
+	"wxcloudrun-golang/db"
+	"wxcloudrun-golang/db/model"
+)
+
+// ContentCheckDaoImpl 内容检测记录数据访问实现
+type ContentCheckDaoImpl struct {
+	db *gorm.DB
+}
+
+// NewContentCheckDao 创建内容检测记录DAO实例
+func NewContentCheckDao() ContentCheckDao {
+	return &ContentCheckDaoImpl{db: db.GetDB()}
+}
+
+// CreateBatch 批量写入检测记录
+func (d *ContentCheckDaoImpl) CreateBatch(ctx context.Context, checks []*model.ContentCheckModel) error {
+	if len(checks) == 0 {
+		return nil
+	}
+	return d.db.WithContext(ctx).Create(&checks).Error
+}
+
+// UpdateResultByTraceId 根据trace_id写入异步检测结果
+func (d *ContentCheckDaoImpl) UpdateResultByTraceId(ctx context.Context, traceId, suggest string, label int, prob float64, errcode int, errmsg string) error {
+	var check model.ContentCheckModel
+	err := d.db.WithContext(ctx).Where("trace_id = ?", traceId).Order("id DESC").First(&check).Error
+	if err != nil {
+		return err
+	}
+
+	return d.db.WithContext(ctx).Model(&check).Updates(map[string]interface{}{
+		"suggest":           suggest,
+		"label":             label,
+		"prob":              prob,
+		"errcode":           errcode,
+		"errmsg":            errmsg,
+		"result_latency_ms": time.Since(check.CreatedAt).Milliseconds(),
+	}).Error
+}
+
+// GetList 分页查询检测记录
+func (d *ContentCheckDaoImpl) GetList(ctx context.Context, filter ContentCheckFilter, page, pageSize int) ([]*model.ContentCheckModel, int64, error) {
+	var checks []*model.ContentCheckModel
+	var total int64
+
+	query := d.db.WithContext(ctx).Model(&model.ContentCheckModel{})
+	if filter.UserId > 0 {
+		query = query.Where("user_id = ?", filter.UserId)
+	}
+	if filter.PostId > 0 {
+		query = query.Where("post_id = ?", filter.PostId)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetId > 0 {
+		query = query.Where("target_id = ?", filter.TargetId)
+	}
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
+	}
+	if filter.Suggest != "" {
+		query = query.Where("suggest = ?", filter.Suggest)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(pageSize).Find(&checks).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return checks, total, nil
+}
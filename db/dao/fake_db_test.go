@@ -0,0 +1,246 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeDB 测试用的内存数据库，只支持DAO生成的单表 INSERT/UPDATE/DELETE，
+// WHERE 条件仅支持以 AND 连接的 列 =/<>/</> ? 与 列 IN (?,...)
+type fakeDB struct {
+	mu      sync.Mutex
+	tables  map[string][]map[string]driver.Value
+	nextId  int64
+	queries []string
+}
+
+// rows 返回表中的所有行
+func (f *fakeDB) rows(table string) []map[string]driver.Value {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.tables[table]
+}
+
+// insert 直接写入一行测试数据
+func (f *fakeDB) insert(table string, row map[string]driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tables[table] = append(f.tables[table], row)
+}
+
+var fakeDrivers sync.Map
+
+// newFakeGorm 创建连接到内存数据库的GORM实例
+func newFakeGorm(t *testing.T) (*gorm.DB, *fakeDB) {
+	t.Helper()
+	fake := &fakeDB{tables: make(map[string][]map[string]driver.Value), nextId: 1000}
+	name := fmt.Sprintf("fakedb-%s-%d", t.Name(), time.Now().UnixNano())
+	fakeDrivers.Store(name, fake)
+	sql.Register(name, fakeDriver{})
+
+	sqlDB, err := sql.Open(name, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	gdb, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}),
+		&gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gdb, fake
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fake, ok := fakeDrivers.Load(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown fake db %s", name)
+	}
+	return &fakeConn{db: fake.(*fakeDB)}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("prepare not supported: %s", query)
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+// fakeTx 事务不做回滚，测试只关心提交后的结果
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+var (
+	updatePattern = regexp.MustCompile("^UPDATE `(\\w+)` SET (.+?) WHERE (.+)$")
+	deletePattern = regexp.MustCompile("^DELETE FROM `(\\w+)` WHERE (.+)$")
+	insertPattern = regexp.MustCompile("^INSERT INTO `(\\w+)` \\((.+?)\\) VALUES (.+)$")
+	condPattern   = regexp.MustCompile("^`?(\\w+)`? (=|<>|<|>|IN) (\\?|\\([?,]+\\))$")
+)
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, named []driver.NamedValue) (driver.Result, error) {
+	args := make([]driver.Value, len(named))
+	for i, v := range named {
+		args[i] = v.Value
+	}
+	f := c.db
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries = append(f.queries, query)
+
+	if m := updatePattern.FindStringSubmatch(query); m != nil {
+		sets := strings.Split(m[2], ",")
+		match, err := compileWhere(m[3], args[len(sets):])
+		if err != nil {
+			return nil, err
+		}
+		var affected int64
+		for _, row := range f.tables[m[1]] {
+			if !match(row) {
+				continue
+			}
+			for i, set := range sets {
+				column := strings.Trim(strings.TrimSuffix(strings.TrimSpace(set), "=?"), "`")
+				row[column] = args[i]
+			}
+			affected++
+		}
+		return driver.RowsAffected(affected), nil
+	}
+
+	if m := deletePattern.FindStringSubmatch(query); m != nil {
+		match, err := compileWhere(m[2], args)
+		if err != nil {
+			return nil, err
+		}
+		var kept []map[string]driver.Value
+		for _, row := range f.tables[m[1]] {
+			if !match(row) {
+				kept = append(kept, row)
+			}
+		}
+		affected := int64(len(f.tables[m[1]]) - len(kept))
+		f.tables[m[1]] = kept
+		return driver.RowsAffected(affected), nil
+	}
+
+	if m := insertPattern.FindStringSubmatch(query); m != nil {
+		columns := strings.Split(strings.ReplaceAll(m[2], "`", ""), ",")
+		count := len(args) / len(columns)
+		firstId := f.nextId
+		for i := 0; i < count; i++ {
+			row := map[string]driver.Value{"id": f.nextId}
+			for j, column := range columns {
+				row[column] = args[i*len(columns)+j]
+			}
+			f.tables[m[1]] = append(f.tables[m[1]], row)
+			f.nextId++
+		}
+		return fakeResult{lastId: firstId, affected: int64(count)}, nil
+	}
+	return nil, fmt.Errorf("unsupported query: %s", query)
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	return nil, fmt.Errorf("unsupported query: %s", query)
+}
+
+type fakeResult struct {
+	lastId   int64
+	affected int64
+}
+
+func (r fakeResult) LastInsertId() (int64, error) { return r.lastId, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return r.affected, nil }
+
+// compileWhere 解析WHERE条件，返回行匹配函数
+func compileWhere(where string, args []driver.Value) (func(map[string]driver.Value) bool, error) {
+	var conds []func(map[string]driver.Value) bool
+	for _, part := range strings.Split(where, " AND ") {
+		m := condPattern.FindStringSubmatch(strings.TrimSpace(part))
+		if m == nil {
+			return nil, fmt.Errorf("unsupported condition: %s", part)
+		}
+		column, op := m[1], m[2]
+		n := strings.Count(m[3], "?")
+		if len(args) < n {
+			return nil, fmt.Errorf("missing args for: %s", part)
+		}
+		values := args[:n]
+		args = args[n:]
+		conds = append(conds, func(row map[string]driver.Value) bool {
+			c := compareValues(row[column], values[0])
+			switch op {
+			case "=":
+				return c == 0
+			case "<>":
+				return c != 0
+			case "<":
+				return c < 0
+			case ">":
+				return c > 0
+			}
+			for _, v := range values {
+				if compareValues(row[column], v) == 0 {
+					return true
+				}
+			}
+			return false
+		})
+	}
+	return func(row map[string]driver.Value) bool {
+		for _, cond := range conds {
+			if !cond(row) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// compareValues 比较两个驱动值，整数统一按int64比较，其余按字符串比较
+func compareValues(a, b driver.Value) int {
+	ai, aok := toInt64(a)
+	bi, bok := toInt64(b)
+	if aok && bok {
+		switch {
+		case ai < bi:
+			return -1
+		case ai > bi:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func toInt64(v driver.Value) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case nil:
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,32 @@
+package dao
+
+import (
+	"context"
+	"time"
+	"wxcloudrun-golang/db/model"
+)
+
+// JobDao 异步任务数据访问接口
+type JobDao interface {
+	// Create 创建任务
+	Create(ctx context.Context, job *model.JobModel) error
+
+	// ClaimNext 抢占一个到期的任务：待执行且到达执行时间，或执行中但抢占已超过lockTimeout（worker异常退出）。
+	// 抢占成功后任务状态为执行中、执行次数加一；没有可执行的任务时返回nil
+	ClaimNext(ctx context.Context, workerId string, now time.Time, lockTimeout time.Duration) (*model.JobModel, error)
+
+	// Complete 标记任务完成。只更新workerId仍持有的任务，返回false表示抢占已超时、任务已被其他worker重新抢占
+	Complete(ctx context.Context, id int64, workerId string) (bool, error)
+
+	// Retry 记录失败原因并在runAt重新执行，返回false表示任务已不由workerId持有
+	Retry(ctx context.Context, id int64, workerId string, lastError string, runAt time.Time) (bool, error)
+
+	// Abandon 记录失败原因并放弃任务，返回false表示任务已不由workerId持有
+	Abandon(ctx context.Context, id int64, workerId string, lastError string) (bool, error)
+
+	// DeleteDone 删除before之前完成的任务，每次最多删除limit条，返回删除的条数
+	DeleteDone(ctx context.Context, before time.Time, limit int) (int64, error)
+
+	// CountByStatus 统计指定状态的任务数
+	CountByStatus(ctx context.Context, status int) (int64, error)
+}
@@ -0,0 +1,116 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"time"
+	"wxcloudrun-golang/db"
+	"wxcloudrun-golang/db/model"
+)
+
+// jobClaimCandidates 每次抢占时读取的候选任务数，多个worker并发抢占时减少冲突
+const jobClaimCandidates = 5
+
+// JobDaoImpl 异步任务数据访问实现
+type JobDaoImpl struct {
+	db *gorm.DB
+}
+
+// NewJobDao 创建异步任务DAO实例
+func NewJobDao() JobDao {
+	return &JobDaoImpl{db: db.GetDB()}
+}
+
+// Create 创建任务
+func (d *JobDaoImpl) Create(ctx context.Context, job *model.JobModel) error {
+	return d.db.WithContext(ctx).Create(job).Error
+}
+
+// ClaimNext 抢占一个到期的任务
+func (d *JobDaoImpl) ClaimNext(ctx context.Context, workerId string, now time.Time, lockTimeout time.Duration) (*model.JobModel, error) {
+	var candidates []*model.JobModel
+	err := d.db.WithContext(ctx).
+		Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_at < ?)",
+			model.JobStatusPending, now, model.JobStatusRunning, now.Add(-lockTimeout)).
+		Order("run_at ASC").Limit(jobClaimCandidates).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// 以状态和执行次数作为版本号条件更新，只有一个worker能抢占成功
+	for _, job := range candidates {
+		result := d.db.WithContext(ctx).Model(&model.JobModel{}).
+			Where("id = ? AND status = ? AND attempts = ?", job.Id, job.Status, job.Attempts).
+			Updates(map[string]interface{}{
+				"status":    model.JobStatusRunning,
+				"attempts":  job.Attempts + 1,
+				"locked_by": workerId,
+				"locked_at": now,
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			job.Status = model.JobStatusRunning
+			job.Attempts++
+			job.LockedBy = workerId
+			job.LockedAt = &now
+			return job, nil
+		}
+	}
+	return nil, nil
+}
+
+// Complete 标记任务完成
+func (d *JobDaoImpl) Complete(ctx context.Context, id int64, workerId string) (bool, error) {
+	return d.release(ctx, id, workerId, map[string]interface{}{
+		"status": model.JobStatusDone,
+	})
+}
+
+// Retry 记录失败原因并在runAt重新执行
+func (d *JobDaoImpl) Retry(ctx context.Context, id int64, workerId string, lastError string, runAt time.Time) (bool, error) {
+	return d.release(ctx, id, workerId, map[string]interface{}{
+		"status":     model.JobStatusPending,
+		"run_at":     runAt,
+		"last_error": lastError,
+	})
+}
+
+// Abandon 记录失败原因并放弃任务
+func (d *JobDaoImpl) Abandon(ctx context.Context, id int64, workerId string, lastError string) (bool, error) {
+	return d.release(ctx, id, workerId, map[string]interface{}{
+		"status":     model.JobStatusAbandoned,
+		"last_error": lastError,
+	})
+}
+
+// release 释放workerId持有的执行中任务并更新状态，任务已被其他worker重新抢占时不更新
+func (d *JobDaoImpl) release(ctx context.Context, id int64, workerId string, updates map[string]interface{}) (bool, error) {
+	updates["locked_by"] = ""
+	updates["locked_at"] = nil
+	result := d.db.WithContext(ctx).Model(&model.JobModel{}).
+		Where("id = ? AND status = ? AND locked_by = ?", id, model.JobStatusRunning, workerId).
+		Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteDone 删除before之前完成的任务
+func (d *JobDaoImpl) DeleteDone(ctx context.Context, before time.Time, limit int) (int64, error) {
+	result := d.db.WithContext(ctx).
+		Where("status = ? AND updated_at < ?", model.JobStatusDone, before).
+		Limit(limit).
+		Delete(&model.JobModel{})
+	return result.RowsAffected, result.Error
+}
+
+// CountByStatus 统计指定状态的任务数
+func (d *JobDaoImpl) CountByStatus(ctx context.Context, status int) (int64, error) {
+	var count int64
+	err := d.db.WithContext(ctx).Model(&model.JobModel{}).Where("status = ?", status).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,61 @@
+package dao
+
+import (
+	"context"
+	"time"
+	"wxcloudrun-golang/db/model"
+)
+
+// MediaCheckDao 媒体检测数据访问接口
+type MediaCheckDao interface {
+	// Create 创建媒体检测记录
+	Create(ctx context.Context, mediaCheck *model.MediaCheckModel) error
+	
+	// GetById 根据ID获取检测记录
+	GetById(ctx context.Context, id int64) (*model.MediaCheckModel, error)
+	
+	// MarkSubmitted 记录待检测记录的提交结果，状态变为检测中，返回false表示记录已不是待检测状态
+	MarkSubmitted(ctx context.Context, id int64, traceId string) (bool, error)
+	
+	// GetByTraceId 根据trace_id获取检测记录
+	GetByTraceId(ctx context.Context, traceId string) (*model.MediaCheckModel, error)
+	
+	// ApplyResult 写入推送时间为createTime的回调检测结论及各检测策略的结果，仅当记录尚未得到结论（待检测或检测中）
+	// 且已有结果的推送时间更早时才更新，返回false表示回调重复、已有更新的结果或已超时处理、人工审核
+	ApplyResult(ctx context.Context, id int64, createTime int64, status int, suggest string, errcode int, errmsg string, details []*model.MediaCheckDetailModel) (bool, error)
+	
+	// GetDetails 获取媒体检测记录的各检测策略结果
+	GetDetails(ctx context.Context, mediaCheckId int64) ([]*model.MediaCheckDetailModel, error)
+	
+	// SetStatus 仅更新检测状态（人工审核结论）
+	SetStatus(ctx context.Context, id int64, status int) error
+	
+	// SetStatusByTraceId 按trace_id更新检测状态，用于未记录检测记录ID的旧审核项，trace_id为空时不更新
+	SetStatusByTraceId(ctx context.Context, traceId string, status int) error
+	
+	// GetByPostId 获取帖子本身（不含评论）的所有媒体检测记录
+	GetByPostId(ctx context.Context, postId int64) ([]*model.MediaCheckModel, error)
+	
+	// GetByCommentId 获取评论的所有媒体检测记录
+	GetByCommentId(ctx context.Context, commentId int64) ([]*model.MediaCheckModel, error)
+	
+	// GetOverdueChecks 获取已提交（检测中且有trace_id）、最近一次提交早于before仍未得到检测结果的记录，最多limit条。
+	// 待检测的记录由异步任务负责提交和重试，不在此列
+	GetOverdueChecks(ctx context.Context, before time.Time, limit int) ([]*model.MediaCheckModel, error)
+	
+	// ClaimRetry 以attempts为版本号抢占一次已提交记录的重新提交，成功后attempts加一并刷新提交时间，
+	// 返回false表示记录已被其他实例处理、已收到检测结果或尚未提交
+	ClaimRetry(ctx context.Context, id int64, attempts int) (bool, error)
+	
+	// UpdateTraceId 更新重新提交后的trace_id
+	UpdateTraceId(ctx context.Context, id int64, traceId string) error
+	
+	// ExpirePending 将仍未得到检测结果的记录设置为指定状态，返回false表示记录已收到检测结果
+	ExpirePending(ctx context.Context, id int64, status int, errmsg string) (bool, error)
+	
+	// CountPending 统计尚未得到检测结果（待检测或检测中）的记录数
+	CountPending(ctx context.Context) (int64, error)
+	
+	// DeleteByPostId 删除帖子的所有检测记录
+	DeleteByPostId(ctx context.Context, postId int64) error
+}
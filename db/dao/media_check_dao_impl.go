@@ -0,0 +1,185 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"time"
+	"wxcloudrun-golang/db"
+	"wxcloudrun-golang/db/model"
+)
+
+// MediaCheckDaoImpl 媒体检测数据访问实现
+type MediaCheckDaoImpl struct {
+	db *gorm.DB
+}
+
+// NewMediaCheckDao 创建媒体检测DAO实例
+func NewMediaCheckDao() MediaCheckDao {
+	return &MediaCheckDaoImpl{db: db.GetDB()}
+}
+
+// Create 创建媒体检测记录
+func (d *MediaCheckDaoImpl) Create(ctx context.Context, mediaCheck *model.MediaCheckModel) error {
+	return d.db.WithContext(ctx).Create(mediaCheck).Error
+}
+
+// GetById 根据ID获取检测记录
+func (d *MediaCheckDaoImpl) GetById(ctx context.Context, id int64) (*model.MediaCheckModel, error) {
+	var mediaCheck model.MediaCheckModel
+	err := d.db.WithContext(ctx).Where("id = ?", id).First(&mediaCheck).Error
+	if err != nil {
+		return nil, err
+	}
+	return &mediaCheck, nil
+}
+
+// MarkSubmitted 记录待检测记录的提交结果
+func (d *MediaCheckDaoImpl) MarkSubmitted(ctx context.Context, id int64, traceId string) (bool, error) {
+	result := d.db.WithContext(ctx).Model(&model.MediaCheckModel{}).
+		Where("id = ? AND status = ?", id, model.MediaCheckStatusPending).
+		Updates(map[string]interface{}{
+			"trace_id":     traceId,
+			"status":       model.MediaCheckStatusChecking,
+			"attempts":     gorm.Expr("attempts + 1"),
+			"submitted_at": time.Now(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetByTraceId 根据trace_id获取检测记录
+func (d *MediaCheckDaoImpl) GetByTraceId(ctx context.Context, traceId string) (*model.MediaCheckModel, error) {
+	var mediaCheck model.MediaCheckModel
+	err := d.db.WithContext(ctx).Where("trace_id = ?", traceId).First(&mediaCheck).Error
+	if err != nil {
+		return nil, err
+	}
+	return &mediaCheck, nil
+}
+
+// ApplyResult 在事务中写入回调检测结论并替换各检测策略的结果，以result_time作为条件保证重复或乱序到达的回调不会覆盖更新的结果；
+// 只更新尚未得到结论的记录，超时处理或人工审核已给出结论的记录不再被迟到的回调覆盖
+func (d *MediaCheckDaoImpl) ApplyResult(ctx context.Context, id int64, createTime int64, status int, suggest string, errcode int, errmsg string, details []*model.MediaCheckDetailModel) (bool, error) {
+	applied := false
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.MediaCheckModel{}).
+			Where("id = ? AND status IN ? AND result_time < ?", id,
+				[]int{model.MediaCheckStatusPending, model.MediaCheckStatusChecking}, createTime).
+			Updates(map[string]interface{}{
+				"status":      status,
+				"suggest":     suggest,
+				"errcode":     errcode,
+				"errmsg":      errmsg,
+				"result_time": createTime,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		applied = true
+
+		if err := tx.Where("image_check_id = ?", id).Delete(&model.MediaCheckDetailModel{}).Error; err != nil {
+			return err
+		}
+		if len(details) == 0 {
+			return nil
+		}
+		for _, detail := range details {
+			detail.MediaCheckId = id
+		}
+		return tx.Create(&details).Error
+	})
+	return applied && err == nil, err
+}
+
+// GetDetails 获取媒体检测记录的各检测策略结果
+func (d *MediaCheckDaoImpl) GetDetails(ctx context.Context, mediaCheckId int64) ([]*model.MediaCheckDetailModel, error) {
+	var details []*model.MediaCheckDetailModel
+	err := d.db.WithContext(ctx).Where("image_check_id = ?", mediaCheckId).Order("id ASC").Find(&details).Error
+	return details, err
+}
+
+// SetStatus 仅更新检测状态（人工审核结论）
+func (d *MediaCheckDaoImpl) SetStatus(ctx context.Context, id int64, status int) error {
+	return d.db.WithContext(ctx).Model(&model.MediaCheckModel{}).
+		Where("id = ?", id).
+		Update("status", status).Error
+}
+
+// SetStatusByTraceId 按trace_id更新检测状态，trace_id为空时不更新（未提交的记录trace_id均为空）
+func (d *MediaCheckDaoImpl) SetStatusByTraceId(ctx context.Context, traceId string, status int) error {
+	if traceId == "" {
+		return errors.New("trace_id为空")
+	}
+	return d.db.WithContext(ctx).Model(&model.MediaCheckModel{}).
+		Where("trace_id = ?", traceId).
+		Update("status", status).Error
+}
+
+// GetByPostId 获取帖子本身（不含评论）的所有媒体检测记录
+func (d *MediaCheckDaoImpl) GetByPostId(ctx context.Context, postId int64) ([]*model.MediaCheckModel, error) {
+	var mediaChecks []*model.MediaCheckModel
+	err := d.db.WithContext(ctx).Where("post_id = ? AND comment_id = 0", postId).Find(&mediaChecks).Error
+	return mediaChecks, err
+}
+
+// GetByCommentId 获取评论的所有媒体检测记录
+func (d *MediaCheckDaoImpl) GetByCommentId(ctx context.Context, commentId int64) ([]*model.MediaCheckModel, error) {
+	var mediaChecks []*model.MediaCheckModel
+	err := d.db.WithContext(ctx).Where("comment_id = ?", commentId).Find(&mediaChecks).Error
+	return mediaChecks, err
+}
+
+// GetOverdueChecks 获取已提交但超时仍未得到检测结果的记录
+func (d *MediaCheckDaoImpl) GetOverdueChecks(ctx context.Context, before time.Time, limit int) ([]*model.MediaCheckModel, error) {
+	var mediaChecks []*model.MediaCheckModel
+	err := d.db.WithContext(ctx).
+		Where("status = ? AND trace_id <> ''", model.MediaCheckStatusChecking).
+		Where("COALESCE(submitted_at, created_at) < ?", before).
+		Order("id ASC").Limit(limit).
+		Find(&mediaChecks).Error
+	return mediaChecks, err
+}
+
+// ClaimRetry 抢占一次重新提交
+func (d *MediaCheckDaoImpl) ClaimRetry(ctx context.Context, id int64, attempts int) (bool, error) {
+	result := d.db.WithContext(ctx).Model(&model.MediaCheckModel{}).
+		Where("id = ? AND attempts = ? AND status = ? AND trace_id <> ''", id, attempts, model.MediaCheckStatusChecking).
+		Updates(map[string]interface{}{
+			"attempts":     attempts + 1,
+			"submitted_at": time.Now(),
+			"status":       model.MediaCheckStatusChecking,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// UpdateTraceId 更新重新提交后的trace_id
+func (d *MediaCheckDaoImpl) UpdateTraceId(ctx context.Context, id int64, traceId string) error {
+	return d.db.WithContext(ctx).Model(&model.MediaCheckModel{}).
+		Where("id = ?", id).
+		Update("trace_id", traceId).Error
+}
+
+// ExpirePending 将仍未得到检测结果的记录设置为指定状态
+func (d *MediaCheckDaoImpl) ExpirePending(ctx context.Context, id int64, status int, errmsg string) (bool, error) {
+	result := d.db.WithContext(ctx).Model(&model.MediaCheckModel{}).
+		Where("id = ? AND status IN ?", id, []int{model.MediaCheckStatusPending, model.MediaCheckStatusChecking}).
+		Updates(map[string]interface{}{
+			"status": status,
+			"errmsg": errmsg,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountPending 统计尚未得到检测结果（待检测或检测中）的记录数
+func (d *MediaCheckDaoImpl) CountPending(ctx context.Context) (int64, error) {
+	var count int64
+	err := d.db.WithContext(ctx).Model(&model.MediaCheckModel{}).
+		Where("status IN ?", []int{model.MediaCheckStatusPending, model.MediaCheckStatusChecking}).
+		Count(&count).Error
+	return count, err
+}
+
+// DeleteByPostId 删除帖子的所有检测记录
+func (d *MediaCheckDaoImpl) DeleteByPostId(ctx context.Context, postId int64) error {
+	return d.db.WithContext(ctx).Where("post_id = ?", postId).Delete(&model.MediaCheckModel{}).Error
+}
@@ -0,0 +1,62 @@
+package dao
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"wxcloudrun-golang/db/model"
+)
+
+func TestMediaCheckDaoApplyResult(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		resultTime  int64
+		createTime  int64
+		wantApplied bool
+		wantStatus  int64
+	}{
+		{"检测中的记录写入结论", model.MediaCheckStatusChecking, 0, 100, true, model.MediaCheckStatusFailed},
+		{"重复推送不覆盖", model.MediaCheckStatusChecking, 100, 100, false, model.MediaCheckStatusChecking},
+		{"更早的推送不覆盖", model.MediaCheckStatusChecking, 100, 90, false, model.MediaCheckStatusChecking},
+		{"超时转人工审核后迟到的回调不覆盖", model.MediaCheckStatusReview, 0, 100, false, model.MediaCheckStatusReview},
+		{"超时判定失败后迟到的回调不覆盖", model.MediaCheckStatusFailed, 0, 100, false, model.MediaCheckStatusFailed},
+		{"人工审核通过后迟到的回调不覆盖", model.MediaCheckStatusPassed, 0, 100, false, model.MediaCheckStatusPassed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gdb, fake := newFakeGorm(t)
+			fake.insert("image_checks", map[string]driver.Value{
+				"id": int64(1), "status": int64(tt.status), "result_time": tt.resultTime,
+			})
+			fake.insert("image_check_details", map[string]driver.Value{
+				"id": int64(1), "image_check_id": int64(1), "strategy": "old",
+			})
+			d := &MediaCheckDaoImpl{db: gdb}
+
+			details := []*model.MediaCheckDetailModel{{Strategy: "porn", Suggest: "risky", Label: 20001, Prob: 90}}
+			applied, err := d.ApplyResult(context.Background(), 1, tt.createTime, model.MediaCheckStatusFailed,
+				"risky", 0, "ok", details)
+			if err != nil {
+				t.Fatalf("ApplyResult() error = %v", err)
+			}
+			if applied != tt.wantApplied {
+				t.Errorf("ApplyResult() = %v, want %v", applied, tt.wantApplied)
+			}
+
+			row := fake.rows("image_checks")[0]
+			if compareValues(row["status"], tt.wantStatus) != 0 {
+				t.Errorf("status = %v, want %d", row["status"], tt.wantStatus)
+			}
+			stored := fake.rows("image_check_details")
+			wantStrategy := "old"
+			if tt.wantApplied {
+				wantStrategy = "porn"
+			}
+			if len(stored) != 1 || stored[0]["strategy"] != wantStrategy {
+				t.Errorf("检测策略结果 = %v, want %s", stored, wantStrategy)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package dao
+
+import (
+	"context"
+	"wxcloudrun-golang/db/model"
+)
+
+// ModerationQueueDao 人工审核队列数据访问接口
+type ModerationQueueDao interface {
+	// Create 创建审核队列项
+	Create(ctx context.Context, item *model.ModerationQueueModel) error
+
+	// GetById 根据ID获取审核队列项
+	GetById(ctx context.Context, id int64) (*model.ModerationQueueModel, error)
+
+	// GetList 分页获取审核队列，status小于0时不按状态过滤，targetType为空时不按类型过滤
+	GetList(ctx context.Context, status int, targetType string, page, pageSize int) ([]*model.ModerationQueueModel, int64, error)
+
+	// Resolve 处理待审核的队列项，返回false表示该项已被处理
+	Resolve(ctx context.Context, id int64, status int, reviewerId int64, note string) (bool, error)
+
+	// CountPendingByTarget 统计审核对象尚未处理的队列项数量，不统计excludeId对应的队列项（正在处理的审核项）
+	CountPendingByTarget(ctx context.Context, targetType string, targetId int64, excludeId int64) (int64, error)
+}
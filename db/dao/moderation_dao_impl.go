@@ -0,0 +1,87 @@
+package dao
+
+import (
+	"context"
+	"time"
+	"gorm.io/gorm"
+	"wxcloudrun-golang/db"
+	"wxcloudrun-golang/db/model"
+)
+
+// ModerationQueueDaoImpl 人工审核队列数据访问实现
+type ModerationQueueDaoImpl struct {
+	db *gorm.DB
+}
+
+// NewModerationQueueDao 创建人工审核队列DAO实例
+func NewModerationQueueDao() ModerationQueueDao {
+	return &ModerationQueueDaoImpl{db: db.GetDB()}
+}
+
+// Create 创建审核队列项
+func (d *ModerationQueueDaoImpl) Create(ctx context.Context, item *model.ModerationQueueModel) error {
+	return d.db.WithContext(ctx).Create(item).Error
+}
+
+// GetById 根据ID获取审核队列项
+func (d *ModerationQueueDaoImpl) GetById(ctx context.Context, id int64) (*model.ModerationQueueModel, error) {
+	var item model.ModerationQueueModel
+	err := d.db.WithContext(ctx).Where("id = ?", id).First(&item).Error
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// GetList 分页获取审核队列
+func (d *ModerationQueueDaoImpl) GetList(ctx context.Context, status int, targetType string, page, pageSize int) ([]*model.ModerationQueueModel, int64, error) {
+	var items []*model.ModerationQueueModel
+	var total int64
+
+	query := d.db.WithContext(ctx).Model(&model.ModerationQueueModel{})
+	if status >= 0 {
+		query = query.Where("status = ?", status)
+	}
+	if targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 待审核的按进入队列先后处理
+	offset := (page - 1) * pageSize
+	err := query.Order("created_at ASC").Offset(offset).Limit(pageSize).Find(&items).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return items, total, nil
+}
+
+// Resolve 处理待审核的队列项
+func (d *ModerationQueueDaoImpl) Resolve(ctx context.Context, id int64, status int, reviewerId int64, note string) (bool, error) {
+	now := time.Now()
+	result := d.db.WithContext(ctx).Model(&model.ModerationQueueModel{}).
+		Where("id = ? AND status = ?", id, model.ModerationQueuePending).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"reviewer_id": reviewerId,
+			"review_note": note,
+			"reviewed_at": &now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CountPendingByTarget 统计审核对象尚未处理的队列项数量
+func (d *ModerationQueueDaoImpl) CountPendingByTarget(ctx context.Context, targetType string, targetId int64, excludeId int64) (int64, error) {
+	var count int64
+	err := d.db.WithContext(ctx).Model(&model.ModerationQueueModel{}).
+		Where("target_type = ? AND target_id = ? AND status = ? AND id <> ?", targetType, targetId, model.ModerationQueuePending, excludeId).
+		Count(&count).Error
+	return count, err
+}
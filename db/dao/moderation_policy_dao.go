@@ -0,0 +1,15 @@
+package dao
+
+import (
+	"context"
+	"wxcloudrun-golang/db/model"
+)
+
+// ModerationPolicyDao 审核策略数据访问接口
+type ModerationPolicyDao interface {
+	// Create 写入新版本的审核策略
+	Create(ctx context.Context, policy *model.ModerationPolicyModel) error
+
+	// GetLatest 获取当前生效（最新）的审核策略，没有配置时返回nil
+	GetLatest(ctx context.Context) (*model.ModerationPolicyModel, error)
+}
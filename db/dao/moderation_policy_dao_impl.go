@@ -0,0 +1,37 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"wxcloudrun-golang/db"
+	"wxcloudrun-golang/db/model"
+)
+
+// ModerationPolicyDaoImpl 审核策略数据访问实现
+type ModerationPolicyDaoImpl struct {
+	db *gorm.DB
+}
+
+// NewModerationPolicyDao 创建审核策略DAO实例
+func NewModerationPolicyDao() ModerationPolicyDao {
+	return &ModerationPolicyDaoImpl{db: db.GetDB()}
+}
+
+// Create 写入新版本的审核策略
+func (d *ModerationPolicyDaoImpl) Create(ctx context.Context, policy *model.ModerationPolicyModel) error {
+	return d.db.WithContext(ctx).Create(policy).Error
+}
+
+// GetLatest 获取当前生效（最新）的审核策略
+func (d *ModerationPolicyDaoImpl) GetLatest(ctx context.Context) (*model.ModerationPolicyModel, error) {
+	var policy model.ModerationPolicyModel
+	err := d.db.WithContext(ctx).Order("id DESC").First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
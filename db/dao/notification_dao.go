@@ -0,0 +1,21 @@
+package dao
+
+import (
+	"context"
+	"wxcloudrun-golang/db/model"
+)
+
+// NotificationDao 站内通知数据访问接口
+type NotificationDao interface {
+	// Create 创建通知
+	Create(ctx context.Context, notification *model.NotificationModel) error
+
+	// GetByUserId 分页获取用户的通知
+	GetByUserId(ctx context.Context, userId int64, page, pageSize int) ([]*model.NotificationModel, int64, error)
+
+	// CountUnread 统计用户未读通知数
+	CountUnread(ctx context.Context, userId int64) (int64, error)
+
+	// MarkRead 将用户的通知标记为已读，ids为空时标记全部
+	MarkRead(ctx context.Context, userId int64, ids []int64) error
+}
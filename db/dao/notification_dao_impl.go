@@ -0,0 +1,60 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"wxcloudrun-golang/db"
+	"wxcloudrun-golang/db/model"
+)
+
+// NotificationDaoImpl 站内通知数据访问实现
+type NotificationDaoImpl struct {
+	db *gorm.DB
+}
+
+// NewNotificationDao 创建站内通知DAO实例
+func NewNotificationDao() NotificationDao {
+	return &NotificationDaoImpl{db: db.GetDB()}
+}
+
+// Create 创建通知
+func (d *NotificationDaoImpl) Create(ctx context.Context, notification *model.NotificationModel) error {
+	return d.db.WithContext(ctx).Create(notification).Error
+}
+
+// GetByUserId 分页获取用户的通知
+func (d *NotificationDaoImpl) GetByUserId(ctx context.Context, userId int64, page, pageSize int) ([]*model.NotificationModel, int64, error) {
+	var notifications []*model.NotificationModel
+	var total int64
+
+	query := d.db.WithContext(ctx).Model(&model.NotificationModel{}).Where("user_id = ?", userId)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&notifications).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return notifications, total, nil
+}
+
+// CountUnread 统计用户未读通知数
+func (d *NotificationDaoImpl) CountUnread(ctx context.Context, userId int64) (int64, error) {
+	var count int64
+	err := d.db.WithContext(ctx).Model(&model.NotificationModel{}).
+		Where("user_id = ? AND is_read = ?", userId, false).
+		Count(&count).Error
+	return count, err
+}
+
+// MarkRead 将用户的通知标记为已读
+func (d *NotificationDaoImpl) MarkRead(ctx context.Context, userId int64, ids []int64) error {
+	query := d.db.WithContext(ctx).Model(&model.NotificationModel{}).Where("user_id = ? AND is_read = ?", userId, false)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+	return query.Update("is_read", true).Error
+}
@@ -1,53 +1,70 @@
 package dao
 
 import (
+	"context"
+	"time"
 	"wxcloudrun-golang/db/model"
 )
 
 // PostDao 帖子数据访问接口
 type PostDao interface {
 	// 创建帖子
-	Create(post *model.PostModel) error
+	Create(ctx context.Context, post *model.PostModel) error
+	
+	// 在同一事务中创建帖子、视频附件（可为nil）、媒体（图片、语音、视频）检测记录和对应的检测任务，newJob根据已写入的检测记录生成任务
+	CreateWithMediaChecks(ctx context.Context, post *model.PostModel, video *model.PostVideoModel, checks []*model.MediaCheckModel, newJob func(check *model.MediaCheckModel) (*model.JobModel, error)) error
 	
 	// 根据ID获取帖子
-	GetById(id int64) (*model.PostModel, error)
+	GetById(ctx context.Context, id int64) (*model.PostModel, error)
 	
 	// 获取帖子列表
-	GetList(page, pageSize int, category, sort string) ([]*model.PostModel, int64, error)
+	GetList(ctx context.Context, page, pageSize int, category, sort string) ([]*model.PostModel, int64, error)
 	
-	// 获取图片检测通过的帖子列表
-	GetListWithImageCheck(page, pageSize int, category, sort string) ([]*model.PostModel, int64, error)
+	// 获取图片检测通过的帖子列表，viewerId为当前用户ID，用于展示其本人待审核/静默隐藏的帖子并排除其拉黑用户的帖子
+	GetListWithImageCheck(ctx context.Context, page, pageSize int, category, sort string, viewerId int64) ([]*model.PostModel, int64, error)
 	
 	// 更新帖子
-	Update(post *model.PostModel) error
+	Update(ctx context.Context, post *model.PostModel) error
 	
 	// 删除帖子（物理删除）
-	Delete(id int64) error
+	Delete(ctx context.Context, id int64) error
 	
 	// 逻辑删除帖子
-	SoftDelete(id int64) error
+	SoftDelete(ctx context.Context, id int64) error
 	
 	// 恢复帖子
-	Restore(id int64) error
+	Restore(ctx context.Context, id int64) error
 	
 	// 增加浏览量
-	IncrementViews(id int64) error
+	IncrementViews(ctx context.Context, id int64) error
 	
 	// 增加点赞数
-	IncrementLikes(id int64) error
+	IncrementLikes(ctx context.Context, id int64) error
 	
 	// 减少点赞数
-	DecrementLikes(id int64) error
+	DecrementLikes(ctx context.Context, id int64) error
 	
 	// 增加评论数
-	IncrementComments(id int64) error
+	IncrementComments(ctx context.Context, id int64) error
 	
 	// 减少评论数
-	DecrementComments(id int64) error
+	DecrementComments(ctx context.Context, id int64) error
 	
 	// 更新图片检测状态
-	UpdateImageCheckStatus(id int64, status int) error
+	UpdateImageCheckStatus(ctx context.Context, id int64, status int) error
+	// SyncMediaCheckStatus 锁定帖子及其媒体检测记录后由aggregate汇总状态，ok为true时更新帖子的媒体检测状态（image_check_status），
+	// 并发的回调按帖子串行执行，后提交的一方总能看到所有已写入的检测结果
+	SyncMediaCheckStatus(ctx context.Context, id int64, aggregate func(checks []*model.MediaCheckModel) (status int, ok bool)) error
+	
+	// 更新审核状态
+	UpdateModerationStatus(ctx context.Context, id int64, status int, reason string) error
+	
+	// 统计分类下未删除的帖子数
+	CountByCategory(ctx context.Context, category string) (int64, error)
 	
 	// 获取用户发布的帖子列表（未删除）
-	GetUserPosts(userId int64, page, pageSize int) ([]*model.PostModel, int64, error)
+	GetUserPosts(ctx context.Context, userId int64, page, pageSize int) ([]*model.PostModel, int64, error)
+	
+	// 获取用户最近发布的帖子（含待审核/隐藏，不含已删除）
+	GetRecentByAuthor(ctx context.Context, authorId int64, since time.Time, limit int) ([]*model.PostModel, error)
 } 
\ No newline at end of file
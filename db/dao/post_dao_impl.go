@@ -1,7 +1,10 @@
 package dao
 
 import (
+	"context"
+	"time"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"wxcloudrun-golang/db"
 	"wxcloudrun-golang/db/model"
 )
@@ -17,14 +20,43 @@ func NewPostDao() PostDao {
 }
 
 // Create 创建帖子
-func (dao *PostDaoImpl) Create(post *model.PostModel) error {
-	return dao.db.Create(post).Error
+func (dao *PostDaoImpl) Create(ctx context.Context, post *model.PostModel) error {
+	return dao.db.WithContext(ctx).Create(post).Error
+}
+
+// CreateWithMediaChecks 在同一事务中创建帖子、视频附件、媒体检测记录和检测任务
+func (dao *PostDaoImpl) CreateWithMediaChecks(ctx context.Context, post *model.PostModel, video *model.PostVideoModel, checks []*model.MediaCheckModel, newJob func(check *model.MediaCheckModel) (*model.JobModel, error)) error {
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(post).Error; err != nil {
+			return err
+		}
+		if video != nil {
+			video.PostId = post.Id
+			if err := tx.Create(video).Error; err != nil {
+				return err
+			}
+		}
+		for _, check := range checks {
+			check.PostId = post.Id
+			if err := tx.Create(check).Error; err != nil {
+				return err
+			}
+			job, err := newJob(check)
+			if err != nil {
+				return err
+			}
+			if err := tx.Create(job).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 // GetById 根据ID获取帖子
-func (dao *PostDaoImpl) GetById(id int64) (*model.PostModel, error) {
+func (dao *PostDaoImpl) GetById(ctx context.Context, id int64) (*model.PostModel, error) {
 	var post model.PostModel
-	err := dao.db.Where("id = ? AND is_deleted = ?", id, false).First(&post).Error
+	err := dao.db.WithContext(ctx).Where("id = ? AND is_deleted = ?", id, false).First(&post).Error
 	if err != nil {
 		return nil, err
 	}
@@ -32,11 +64,12 @@ func (dao *PostDaoImpl) GetById(id int64) (*model.PostModel, error) {
 }
 
 // GetList 获取帖子列表
-func (dao *PostDaoImpl) GetList(page, pageSize int, category, sort string) ([]*model.PostModel, int64, error) {
+func (dao *PostDaoImpl) GetList(ctx context.Context, page, pageSize int, category, sort string) ([]*model.PostModel, int64, error) {
 	var posts []*model.PostModel
 	var total int64
 	
-	query := dao.db.Model(&model.PostModel{}).Where("is_public = ? AND is_deleted = ?", true, false)
+	query := dao.db.WithContext(ctx).Model(&model.PostModel{}).Where("is_public = ? AND is_deleted = ? AND moderation_status = ?",
+		true, false, model.ModerationStatusNormal)
 	
 	// 分类筛选
 	if category != "" && category != "all" {
@@ -70,14 +103,23 @@ func (dao *PostDaoImpl) GetList(page, pageSize int, category, sort string) ([]*m
 }
 
 // GetListWithImageCheck 获取图片检测通过的帖子列表
-func (dao *PostDaoImpl) GetListWithImageCheck(page, pageSize int, category, sort string) ([]*model.PostModel, int64, error) {
+func (dao *PostDaoImpl) GetListWithImageCheck(ctx context.Context, page, pageSize int, category, sort string, viewerId int64) ([]*model.PostModel, int64, error) {
 	var posts []*model.PostModel
 	var total int64
 	
 	// 只显示图片检测通过的帖子（状态为2）或没有图片的帖子（状态为0）
-	query := dao.db.Model(&model.PostModel{}).Where("is_public = ? AND is_deleted = ? AND (image_check_status = ? OR image_check_status = ?)", 
+	query := dao.db.WithContext(ctx).Model(&model.PostModel{}).Where("is_public = ? AND is_deleted = ? AND (image_check_status = ? OR image_check_status = ?)", 
 		true, false, 0, 2)
 	
+	// 待审核/静默隐藏的帖子只对作者本人可见
+	query = query.Where("(moderation_status = ? OR (moderation_status IN ? AND author_id = ?))",
+		model.ModerationStatusNormal, []int{model.ModerationStatusPending, model.ModerationStatusShadowHidden}, viewerId)
+	
+	// 不展示当前用户拉黑的作者的帖子
+	if viewerId > 0 {
+		query = query.Where("author_id NOT IN (?)", blockedAuthorIds(dao.db.WithContext(ctx), viewerId))
+	}
+	
 	// 分类筛选
 	if category != "" && category != "all" {
 		query = query.Where("category = ?", category)
@@ -110,62 +152,94 @@ func (dao *PostDaoImpl) GetListWithImageCheck(page, pageSize int, category, sort
 }
 
 // Update 更新帖子
-func (dao *PostDaoImpl) Update(post *model.PostModel) error {
-	return dao.db.Save(post).Error
+func (dao *PostDaoImpl) Update(ctx context.Context, post *model.PostModel) error {
+	return dao.db.WithContext(ctx).Save(post).Error
 }
 
 // Delete 删除帖子（物理删除）
-func (dao *PostDaoImpl) Delete(id int64) error {
-	return dao.db.Where("id = ?", id).Delete(&model.PostModel{}).Error
+func (dao *PostDaoImpl) Delete(ctx context.Context, id int64) error {
+	return dao.db.WithContext(ctx).Where("id = ?", id).Delete(&model.PostModel{}).Error
 }
 
 // SoftDelete 逻辑删除帖子
-func (dao *PostDaoImpl) SoftDelete(id int64) error {
-	return dao.db.Model(&model.PostModel{}).Where("id = ?", id).Update("is_deleted", true).Error
+func (dao *PostDaoImpl) SoftDelete(ctx context.Context, id int64) error {
+	return dao.db.WithContext(ctx).Model(&model.PostModel{}).Where("id = ?", id).Update("is_deleted", true).Error
 }
 
 // Restore 恢复帖子
-func (dao *PostDaoImpl) Restore(id int64) error {
-	return dao.db.Model(&model.PostModel{}).Where("id = ?", id).Update("is_deleted", false).Error
+func (dao *PostDaoImpl) Restore(ctx context.Context, id int64) error {
+	return dao.db.WithContext(ctx).Model(&model.PostModel{}).Where("id = ?", id).Update("is_deleted", false).Error
 }
 
 // IncrementViews 增加浏览量
-func (dao *PostDaoImpl) IncrementViews(id int64) error {
-	return dao.db.Model(&model.PostModel{}).Where("id = ?", id).UpdateColumn("views", gorm.Expr("views + ?", 1)).Error
+func (dao *PostDaoImpl) IncrementViews(ctx context.Context, id int64) error {
+	return dao.db.WithContext(ctx).Model(&model.PostModel{}).Where("id = ?", id).UpdateColumn("views", gorm.Expr("views + ?", 1)).Error
 }
 
 // IncrementLikes 增加点赞数
-func (dao *PostDaoImpl) IncrementLikes(id int64) error {
-	return dao.db.Model(&model.PostModel{}).Where("id = ?", id).UpdateColumn("likes", gorm.Expr("likes + ?", 1)).Error
+func (dao *PostDaoImpl) IncrementLikes(ctx context.Context, id int64) error {
+	return dao.db.WithContext(ctx).Model(&model.PostModel{}).Where("id = ?", id).UpdateColumn("likes", gorm.Expr("likes + ?", 1)).Error
 }
 
 // DecrementLikes 减少点赞数
-func (dao *PostDaoImpl) DecrementLikes(id int64) error {
-	return dao.db.Model(&model.PostModel{}).Where("id = ?", id).UpdateColumn("likes", gorm.Expr("likes - ?", 1)).Error
+func (dao *PostDaoImpl) DecrementLikes(ctx context.Context, id int64) error {
+	return dao.db.WithContext(ctx).Model(&model.PostModel{}).Where("id = ?", id).UpdateColumn("likes", gorm.Expr("likes - ?", 1)).Error
 }
 
 // IncrementComments 增加评论数
-func (dao *PostDaoImpl) IncrementComments(id int64) error {
-	return dao.db.Model(&model.PostModel{}).Where("id = ?", id).UpdateColumn("comments", gorm.Expr("comments + ?", 1)).Error
+func (dao *PostDaoImpl) IncrementComments(ctx context.Context, id int64) error {
+	return dao.db.WithContext(ctx).Model(&model.PostModel{}).Where("id = ?", id).UpdateColumn("comments", gorm.Expr("comments + ?", 1)).Error
 }
 
 // DecrementComments 减少评论数
-func (dao *PostDaoImpl) DecrementComments(id int64) error {
-	return dao.db.Model(&model.PostModel{}).Where("id = ?", id).UpdateColumn("comments", gorm.Expr("comments - ?", 1)).Error
+func (dao *PostDaoImpl) DecrementComments(ctx context.Context, id int64) error {
+	return dao.db.WithContext(ctx).Model(&model.PostModel{}).Where("id = ?", id).UpdateColumn("comments", gorm.Expr("comments - ?", 1)).Error
 }
 
 // UpdateImageCheckStatus 更新图片检测状态
-func (dao *PostDaoImpl) UpdateImageCheckStatus(id int64, status int) error {
-	return dao.db.Model(&model.PostModel{}).Where("id = ?", id).Update("image_check_status", status).Error
+func (dao *PostDaoImpl) UpdateImageCheckStatus(ctx context.Context, id int64, status int) error {
+	return dao.db.WithContext(ctx).Model(&model.PostModel{}).Where("id = ?", id).Update("image_check_status", status).Error
+}
+
+// SyncMediaCheckStatus 在事务中锁定帖子行和帖子本身的媒体检测记录（不含语音评论）后汇总并更新帖子的媒体检测状态
+func (dao *PostDaoImpl) SyncMediaCheckStatus(ctx context.Context, id int64, aggregate func(checks []*model.MediaCheckModel) (int, bool)) error {
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var post model.PostModel
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", id).First(&post).Error; err != nil {
+			return err
+		}
+		var checks []*model.MediaCheckModel
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("post_id = ? AND comment_id = 0", id).Find(&checks).Error; err != nil {
+			return err
+		}
+		status, ok := aggregate(checks)
+		if !ok {
+			return nil
+		}
+		return tx.Model(&model.PostModel{}).Where("id = ?", id).Update("image_check_status", status).Error
+	})
+}
+
+// UpdateModerationStatus 更新审核状态
+func (dao *PostDaoImpl) UpdateModerationStatus(ctx context.Context, id int64, status int, reason string) error {
+	return dao.db.WithContext(ctx).Model(&model.PostModel{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"moderation_status": status, "moderation_reason": reason}).Error
+}
+
+// CountByCategory 统计分类下未删除的帖子数
+func (dao *PostDaoImpl) CountByCategory(ctx context.Context, category string) (int64, error) {
+	var count int64
+	err := dao.db.WithContext(ctx).Model(&model.PostModel{}).Where("category = ? AND is_deleted = ?", category, false).Count(&count).Error
+	return count, err
 }
 
 // GetUserPosts 获取用户发布的帖子列表（未删除）
-func (dao *PostDaoImpl) GetUserPosts(userId int64, page, pageSize int) ([]*model.PostModel, int64, error) {
+func (dao *PostDaoImpl) GetUserPosts(ctx context.Context, userId int64, page, pageSize int) ([]*model.PostModel, int64, error) {
 	var posts []*model.PostModel
 	var total int64
 	
 	// 查询用户发布的未删除帖子
-	query := dao.db.Model(&model.PostModel{}).Where("author_id = ? AND is_deleted = ?", userId, false)
+	query := dao.db.WithContext(ctx).Model(&model.PostModel{}).Where("author_id = ? AND is_deleted = ?", userId, false)
 	
 	// 获取总数
 	err := query.Count(&total).Error
@@ -184,4 +258,18 @@ func (dao *PostDaoImpl) GetUserPosts(userId int64, page, pageSize int) ([]*model
 	}
 	
 	return posts, total, nil
-} 
\ No newline at end of file
+} 
+
+// GetRecentByAuthor 获取用户最近发布的帖子（含待审核/隐藏，不含已删除）
+func (dao *PostDaoImpl) GetRecentByAuthor(ctx context.Context, authorId int64, since time.Time, limit int) ([]*model.PostModel, error) {
+	var posts []*model.PostModel
+	err := dao.db.WithContext(ctx).
+		Where("author_id = ? AND is_deleted = ? AND created_at >= ?", authorId, false, since).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&posts).Error
+	if err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
@@ -0,0 +1,15 @@
+package dao
+
+import (
+	"context"
+	"wxcloudrun-golang/db/model"
+)
+
+// PostVideoDao 帖子视频附件数据访问接口，视频附件随帖子在 PostDao.CreateWithMediaChecks 中创建
+type PostVideoDao interface {
+	// GetByPostId 获取帖子的视频附件，没有视频时返回 gorm.ErrRecordNotFound
+	GetByPostId(ctx context.Context, postId int64) (*model.PostVideoModel, error)
+
+	// GetByPostIds 批量获取帖子的视频附件，按帖子ID索引
+	GetByPostIds(ctx context.Context, postIds []int64) (map[int64]*model.PostVideoModel, error)
+}
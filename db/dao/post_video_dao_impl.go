@@ -0,0 +1,44 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"wxcloudrun-golang/db"
+	"wxcloudrun-golang/db/model"
+)
+
+// PostVideoDaoImpl 帖子视频附件数据访问实现
+type PostVideoDaoImpl struct {
+	db *gorm.DB
+}
+
+// NewPostVideoDao 创建帖子视频附件DAO实例
+func NewPostVideoDao() PostVideoDao {
+	return &PostVideoDaoImpl{db: db.GetDB()}
+}
+
+// GetByPostId 获取帖子的视频附件
+func (d *PostVideoDaoImpl) GetByPostId(ctx context.Context, postId int64) (*model.PostVideoModel, error) {
+	var video model.PostVideoModel
+	err := d.db.WithContext(ctx).Where("post_id = ?", postId).First(&video).Error
+	if err != nil {
+		return nil, err
+	}
+	return &video, nil
+}
+
+// GetByPostIds 批量获取帖子的视频附件
+func (d *PostVideoDaoImpl) GetByPostIds(ctx context.Context, postIds []int64) (map[int64]*model.PostVideoModel, error) {
+	videos := make(map[int64]*model.PostVideoModel)
+	if len(postIds) == 0 {
+		return videos, nil
+	}
+	var list []*model.PostVideoModel
+	if err := d.db.WithContext(ctx).Where("post_id IN ?", postIds).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	for _, video := range list {
+		videos[video.PostId] = video
+	}
+	return videos, nil
+}
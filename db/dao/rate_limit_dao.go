@@ -0,0 +1,18 @@
+package dao
+
+import (
+	"context"
+	"time"
+)
+
+// RateLimitDao 限流计数数据访问接口
+type RateLimitDao interface {
+	// Increment 窗口计数加一并返回加一后的计数
+	Increment(ctx context.Context, bucketKey string, windowStart int64, expiresAt time.Time) (int, error)
+	
+	// Get 获取窗口计数，没有计数时返回0
+	Get(ctx context.Context, bucketKey string, windowStart int64) (int, error)
+	
+	// DeleteExpired 删除已过期的计数
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
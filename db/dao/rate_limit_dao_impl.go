@@ -0,0 +1,57 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"time"
+	"wxcloudrun-golang/db"
+	"wxcloudrun-golang/db/model"
+)
+
+// RateLimitDaoImpl 限流计数DAO实现
+type RateLimitDaoImpl struct {
+	db *gorm.DB
+}
+
+// NewRateLimitDao 创建限流计数DAO实例
+func NewRateLimitDao() RateLimitDao {
+	return &RateLimitDaoImpl{db: db.GetDB()}
+}
+
+// Increment 窗口计数加一并返回加一后的计数
+func (dao *RateLimitDaoImpl) Increment(ctx context.Context, bucketKey string, windowStart int64, expiresAt time.Time) (int, error) {
+	err := dao.db.WithContext(ctx).Exec(
+		"INSERT INTO rate_limit_counters (bucket_key, window_start, count, expires_at) VALUES (?, ?, 1, ?) "+
+			"ON DUPLICATE KEY UPDATE count = count + 1",
+		bucketKey, windowStart, expiresAt,
+	).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var counter model.RateLimitCounterModel
+	err = dao.db.WithContext(ctx).
+		Where("bucket_key = ? AND window_start = ?", bucketKey, windowStart).
+		First(&counter).Error
+	if err != nil {
+		return 0, err
+	}
+	return counter.Count, nil
+}
+
+// Get 获取窗口计数，没有计数时返回0
+func (dao *RateLimitDaoImpl) Get(ctx context.Context, bucketKey string, windowStart int64) (int, error) {
+	var counters []*model.RateLimitCounterModel
+	err := dao.db.WithContext(ctx).
+		Where("bucket_key = ? AND window_start = ?", bucketKey, windowStart).
+		Limit(1).Find(&counters).Error
+	if err != nil || len(counters) == 0 {
+		return 0, err
+	}
+	return counters[0].Count, nil
+}
+
+// DeleteExpired 删除已过期的计数
+func (dao *RateLimitDaoImpl) DeleteExpired(ctx context.Context, now time.Time) error {
+	return dao.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&model.RateLimitCounterModel{}).Error
+}
@@ -0,0 +1,30 @@
+package dao
+
+import (
+	"context"
+	"wxcloudrun-golang/db/model"
+)
+
+// ReportDao 举报数据访问接口
+type ReportDao interface {
+	// Create 创建举报，同一举报人对同一对象已有待处理举报时返回 ErrDuplicateReport
+	Create(ctx context.Context, report *model.ReportModel) error
+
+	// GetById 根据ID获取举报
+	GetById(ctx context.Context, id int64) (*model.ReportModel, error)
+
+	// CountPendingByTarget 统计对象待处理的举报数
+	CountPendingByTarget(ctx context.Context, targetType string, targetId int64) (int64, error)
+
+	// GetList 分页获取举报，status小于0时不按状态过滤，targetType为空时不按类型过滤
+	GetList(ctx context.Context, status int, targetType string, page, pageSize int) ([]*model.ReportModel, int64, error)
+
+	// GetByReporter 分页获取举报人的举报记录
+	GetByReporter(ctx context.Context, reporterId int64, page, pageSize int) ([]*model.ReportModel, int64, error)
+
+	// CountByReporterAndStatus 按处理状态统计举报人的举报数
+	CountByReporterAndStatus(ctx context.Context, reporterId int64) (map[int]int64, error)
+
+	// ResolveByTarget 处理对象的全部待处理举报，返回待处理举报的举报人ID
+	ResolveByTarget(ctx context.Context, targetType string, targetId int64, status int, resolverId int64, note string) ([]int64, error)
+}
@@ -0,0 +1,146 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"time"
+	"gorm.io/gorm"
+	"wxcloudrun-golang/db"
+	"wxcloudrun-golang/db/model"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ErrDuplicateReport 重复举报
+var ErrDuplicateReport = errors.New("duplicate report")
+
+// mysqlErrDuplicateEntry MySQL唯一键冲突错误码
+const mysqlErrDuplicateEntry = 1062
+
+// ReportDaoImpl 举报数据访问实现
+type ReportDaoImpl struct {
+	db *gorm.DB
+}
+
+// NewReportDao 创建举报DAO实例
+func NewReportDao() ReportDao {
+	return &ReportDaoImpl{db: db.GetDB()}
+}
+
+// Create 创建举报，待处理举报带上pending_flag，由唯一键保证同一举报人对同一对象只有一个待处理举报
+func (d *ReportDaoImpl) Create(ctx context.Context, report *model.ReportModel) error {
+	if report.Status == model.ReportStatusPending {
+		pending := 1
+		report.PendingFlag = &pending
+	}
+	err := d.db.WithContext(ctx).Create(report).Error
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+		return ErrDuplicateReport
+	}
+	return err
+}
+
+// GetById 根据ID获取举报
+func (d *ReportDaoImpl) GetById(ctx context.Context, id int64) (*model.ReportModel, error) {
+	var report model.ReportModel
+	err := d.db.WithContext(ctx).Where("id = ?", id).First(&report).Error
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// CountPendingByTarget 统计对象待处理的举报数
+func (d *ReportDaoImpl) CountPendingByTarget(ctx context.Context, targetType string, targetId int64) (int64, error) {
+	var count int64
+	err := d.db.WithContext(ctx).Model(&model.ReportModel{}).
+		Where("target_type = ? AND target_id = ? AND status = ?", targetType, targetId, model.ReportStatusPending).
+		Count(&count).Error
+	return count, err
+}
+
+// GetList 分页获取举报
+func (d *ReportDaoImpl) GetList(ctx context.Context, status int, targetType string, page, pageSize int) ([]*model.ReportModel, int64, error) {
+	query := d.db.WithContext(ctx).Model(&model.ReportModel{})
+	if status >= 0 {
+		query = query.Where("status = ?", status)
+	}
+	if targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	return d.paginate(query, page, pageSize)
+}
+
+// GetByReporter 分页获取举报人的举报记录
+func (d *ReportDaoImpl) GetByReporter(ctx context.Context, reporterId int64, page, pageSize int) ([]*model.ReportModel, int64, error) {
+	query := d.db.WithContext(ctx).Model(&model.ReportModel{}).Where("reporter_id = ?", reporterId)
+	return d.paginate(query, page, pageSize)
+}
+
+// CountByReporterAndStatus 按处理状态统计举报人的举报数
+func (d *ReportDaoImpl) CountByReporterAndStatus(ctx context.Context, reporterId int64) (map[int]int64, error) {
+	var rows []struct {
+		Status int
+		Count  int64
+	}
+	err := d.db.WithContext(ctx).Model(&model.ReportModel{}).
+		Select("status, COUNT(*) AS count").
+		Where("reporter_id = ?", reporterId).
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// ResolveByTarget 处理对象的全部待处理举报，清除pending_flag后举报人可以再次举报该对象
+func (d *ReportDaoImpl) ResolveByTarget(ctx context.Context, targetType string, targetId int64, status int, resolverId int64, note string) ([]int64, error) {
+	var reporterIds []int64
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&model.ReportModel{}).
+			Where("target_type = ? AND target_id = ? AND status = ?", targetType, targetId, model.ReportStatusPending)
+		if err := query.Pluck("reporter_id", &reporterIds).Error; err != nil {
+			return err
+		}
+		if len(reporterIds) == 0 {
+			return nil
+		}
+
+		now := time.Now()
+		return tx.Model(&model.ReportModel{}).
+			Where("target_type = ? AND target_id = ? AND status = ?", targetType, targetId, model.ReportStatusPending).
+			Updates(map[string]interface{}{
+				"status":          status,
+				"resolver_id":     resolverId,
+				"resolution_note": note,
+				"resolved_at":     &now,
+				"pending_flag":    nil,
+			}).Error
+	})
+	return reporterIds, err
+}
+
+// paginate 按创建时间倒序分页查询
+func (d *ReportDaoImpl) paginate(query *gorm.DB, page, pageSize int) ([]*model.ReportModel, int64, error) {
+	var reports []*model.ReportModel
+	var total int64
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&reports).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return reports, total, nil
+}
@@ -0,0 +1,39 @@
+package dao
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"wxcloudrun-golang/db/model"
+)
+
+func TestReportDaoCreatePendingFlag(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		wantFlag driver.Value
+	}{
+		{"待处理举报带上pending_flag", model.ReportStatusPending, int64(1)},
+		{"已处理举报不占用唯一键", model.ReportStatusDismissed, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gdb, fake := newFakeGorm(t)
+			d := &ReportDaoImpl{db: gdb}
+
+			report := &model.ReportModel{ReporterId: 1, TargetType: model.ReportTargetPost, TargetId: 2, Reason: "spam", Status: tt.status}
+			if err := d.Create(context.Background(), report); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+
+			rows := fake.rows("reports")
+			if len(rows) != 1 {
+				t.Fatalf("rows = %d, want 1", len(rows))
+			}
+			if got := rows[0]["pending_flag"]; got != tt.wantFlag {
+				t.Errorf("pending_flag = %v, want %v", got, tt.wantFlag)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package dao
+
+import (
+	"context"
+	"wxcloudrun-golang/db/model"
+)
+
+// UserBlockDao 用户拉黑数据访问接口
+type UserBlockDao interface {
+	// Block 拉黑用户，已拉黑时不报错
+	Block(ctx context.Context, blockerId, blockedId int64) error
+
+	// Unblock 取消拉黑
+	Unblock(ctx context.Context, blockerId, blockedId int64) error
+
+	// IsBlocked 判断blockerId是否拉黑了blockedId
+	IsBlocked(ctx context.Context, blockerId, blockedId int64) (bool, error)
+
+	// GetBlockedIds 获取用户拉黑的全部用户ID
+	GetBlockedIds(ctx context.Context, blockerId int64) ([]int64, error)
+
+	// GetList 分页获取用户的拉黑列表
+	GetList(ctx context.Context, blockerId int64, page, pageSize int) ([]*model.UserBlockModel, int64, error)
+
+	// Count 统计用户拉黑的人数
+	Count(ctx context.Context, blockerId int64) (int64, error)
+}
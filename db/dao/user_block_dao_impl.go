@@ -0,0 +1,80 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"wxcloudrun-golang/db"
+	"wxcloudrun-golang/db/model"
+)
+
+// UserBlockDaoImpl 用户拉黑数据访问实现
+type UserBlockDaoImpl struct {
+	db *gorm.DB
+}
+
+// NewUserBlockDao 创建用户拉黑DAO实例
+func NewUserBlockDao() UserBlockDao {
+	return &UserBlockDaoImpl{db: db.GetDB()}
+}
+
+// Block 拉黑用户
+func (d *UserBlockDaoImpl) Block(ctx context.Context, blockerId, blockedId int64) error {
+	block := &model.UserBlockModel{BlockerId: blockerId, BlockedId: blockedId}
+	return d.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(block).Error
+}
+
+// Unblock 取消拉黑
+func (d *UserBlockDaoImpl) Unblock(ctx context.Context, blockerId, blockedId int64) error {
+	return d.db.WithContext(ctx).Where("blocker_id = ? AND blocked_id = ?", blockerId, blockedId).
+		Delete(&model.UserBlockModel{}).Error
+}
+
+// IsBlocked 判断blockerId是否拉黑了blockedId
+func (d *UserBlockDaoImpl) IsBlocked(ctx context.Context, blockerId, blockedId int64) (bool, error) {
+	var count int64
+	err := d.db.WithContext(ctx).Model(&model.UserBlockModel{}).
+		Where("blocker_id = ? AND blocked_id = ?", blockerId, blockedId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetBlockedIds 获取用户拉黑的全部用户ID
+func (d *UserBlockDaoImpl) GetBlockedIds(ctx context.Context, blockerId int64) ([]int64, error) {
+	var ids []int64
+	err := d.db.WithContext(ctx).Model(&model.UserBlockModel{}).
+		Where("blocker_id = ?", blockerId).
+		Pluck("blocked_id", &ids).Error
+	return ids, err
+}
+
+// GetList 分页获取用户的拉黑列表
+func (d *UserBlockDaoImpl) GetList(ctx context.Context, blockerId int64, page, pageSize int) ([]*model.UserBlockModel, int64, error) {
+	var blocks []*model.UserBlockModel
+	var total int64
+
+	query := d.db.WithContext(ctx).Model(&model.UserBlockModel{}).Where("blocker_id = ?", blockerId)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&blocks).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return blocks, total, nil
+}
+
+// Count 统计用户拉黑的人数
+func (d *UserBlockDaoImpl) Count(ctx context.Context, blockerId int64) (int64, error) {
+	var count int64
+	err := d.db.WithContext(ctx).Model(&model.UserBlockModel{}).Where("blocker_id = ?", blockerId).Count(&count).Error
+	return count, err
+}
+
+// blockedAuthorIds 构造用户拉黑列表子查询，用于列表查询中排除被拉黑用户的内容
+func blockedAuthorIds(tx *gorm.DB, blockerId int64) *gorm.DB {
+	return tx.Model(&model.UserBlockModel{}).Select("blocked_id").Where("blocker_id = ?", blockerId)
+}
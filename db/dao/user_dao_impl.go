@@ -1,19 +1,23 @@
 package dao
 
 import (
+	"context"
+	"fmt"
+	"time"
+	"gorm.io/gorm"
 	"wxcloudrun-golang/db"
 	"wxcloudrun-golang/db/model"
 )
 
 // CreateUser 创建用户
-func (dao *UserDaoImpl) CreateUser(user *model.UserModel) error {
-	return db.GetDB().Create(user).Error
+func (dao *UserDaoImpl) CreateUser(ctx context.Context, user *model.UserModel) error {
+	return db.GetDB().WithContext(ctx).Create(user).Error
 }
 
 // GetUserByUsername 根据用户名查询用户
-func (dao *UserDaoImpl) GetUserByUsername(username string) (*model.UserModel, error) {
+func (dao *UserDaoImpl) GetUserByUsername(ctx context.Context, username string) (*model.UserModel, error) {
 	var user model.UserModel
-	err := db.GetDB().Where("username = ?", username).First(&user).Error
+	err := db.GetDB().WithContext(ctx).Where("username = ?", username).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -21,9 +25,9 @@ func (dao *UserDaoImpl) GetUserByUsername(username string) (*model.UserModel, er
 }
 
 // GetById 根据ID查询用户
-func (dao *UserDaoImpl) GetById(id int64) (*model.UserModel, error) {
+func (dao *UserDaoImpl) GetById(ctx context.Context, id int64) (*model.UserModel, error) {
 	var user model.UserModel
-	err := db.GetDB().Where("id = ?", id).First(&user).Error
+	err := db.GetDB().WithContext(ctx).Where("id = ?", id).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -31,9 +35,9 @@ func (dao *UserDaoImpl) GetById(id int64) (*model.UserModel, error) {
 }
 
 // GetUserByOpenId 根据OpenId查询用户
-func (dao *UserDaoImpl) GetUserByOpenId(openId string) (*model.UserModel, error) {
+func (dao *UserDaoImpl) GetUserByOpenId(ctx context.Context, openId string) (*model.UserModel, error) {
 	var user model.UserModel
-	err := db.GetDB().Where("openid = ?", openId).First(&user).Error
+	err := db.GetDB().WithContext(ctx).Where("openid = ?", openId).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -41,9 +45,9 @@ func (dao *UserDaoImpl) GetUserByOpenId(openId string) (*model.UserModel, error)
 }
 
 // GetUserByUnionId 根据UnionId查询用户
-func (dao *UserDaoImpl) GetUserByUnionId(unionId string) (*model.UserModel, error) {
+func (dao *UserDaoImpl) GetUserByUnionId(ctx context.Context, unionId string) (*model.UserModel, error) {
 	var user model.UserModel
-	err := db.GetDB().Where("unionid = ?", unionId).First(&user).Error
+	err := db.GetDB().WithContext(ctx).Where("unionid = ?", unionId).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -51,19 +55,19 @@ func (dao *UserDaoImpl) GetUserByUnionId(unionId string) (*model.UserModel, erro
 }
 
 // GetUsersByPage 分页查询用户列表
-func (dao *UserDaoImpl) GetUsersByPage(page, pageSize int) ([]*model.UserModel, int64, error) {
+func (dao *UserDaoImpl) GetUsersByPage(ctx context.Context, page, pageSize int) ([]*model.UserModel, int64, error) {
 	var users []*model.UserModel
 	var total int64
 
 	// 获取总数
-	err := db.GetDB().Model(&model.UserModel{}).Count(&total).Error
+	err := db.GetDB().WithContext(ctx).Model(&model.UserModel{}).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// 分页查询
 	offset := (page - 1) * pageSize
-	err = db.GetDB().Offset(offset).Limit(pageSize).Order("created_at DESC").Find(&users).Error
+	err = db.GetDB().WithContext(ctx).Offset(offset).Limit(pageSize).Order("created_at DESC").Find(&users).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -72,11 +76,69 @@ func (dao *UserDaoImpl) GetUsersByPage(page, pageSize int) ([]*model.UserModel,
 }
 
 // UpdateUser 更新用户信息
-func (dao *UserDaoImpl) UpdateUser(user *model.UserModel) error {
-	return db.GetDB().Save(user).Error
+func (dao *UserDaoImpl) UpdateUser(ctx context.Context, user *model.UserModel) error {
+	return db.GetDB().WithContext(ctx).Save(user).Error
+}
+
+// UpdateRole 更新用户角色
+func (dao *UserDaoImpl) UpdateRole(ctx context.Context, id int64, role string) error {
+	return db.GetDB().WithContext(ctx).Model(&model.UserModel{}).Where("id = ?", id).Update("role", role).Error
+}
+
+// UpdateStatus 更新账号状态，until为空表示永久
+func (dao *UserDaoImpl) UpdateStatus(ctx context.Context, id int64, status, reason string, until *time.Time, operatorId int64) error {
+	return db.GetDB().WithContext(ctx).Model(&model.UserModel{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":             status,
+		"status_reason":      reason,
+		"status_until":       until,
+		"status_operator_id": operatorId,
+	}).Error
+}
+
+// UpdateProfile 更新资料字段，头像检测记录和检测任务在同一事务中写入
+func (dao *UserDaoImpl) UpdateProfile(ctx context.Context, id int64, fields map[string]interface{}, avatarCheck *model.MediaCheckModel, newJob func(check *model.MediaCheckModel) (*model.JobModel, error)) error {
+	return db.GetDB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(fields) > 0 {
+			if err := tx.Model(&model.UserModel{}).Where("id = ?", id).Updates(fields).Error; err != nil {
+				return err
+			}
+		}
+		if avatarCheck == nil {
+			return nil
+		}
+		avatarCheck.UserId = id
+		if err := tx.Create(avatarCheck).Error; err != nil {
+			return err
+		}
+		job, err := newJob(avatarCheck)
+		if err != nil {
+			return err
+		}
+		return tx.Create(job).Error
+	})
+}
+
+// ApplyPendingField 以待审核值替换当前值或丢弃待审核值
+func (dao *UserDaoImpl) ApplyPendingField(ctx context.Context, id int64, field, value string, approved bool) (bool, error) {
+	switch field {
+	case model.ProfileFieldNickname, model.ProfileFieldAvatar, model.ProfileFieldBio:
+	default:
+		return false, fmt.Errorf("unknown profile field: %s", field)
+	}
+	pendingColumn := "pending_" + field
+	updates := map[string]interface{}{pendingColumn: ""}
+	if approved {
+		updates[field] = value
+	}
+	result := db.GetDB().WithContext(ctx).Model(&model.UserModel{}).
+		Where("id = ? AND "+pendingColumn+" = ?", id, value).Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
 }
 
 // DeleteUser 删除用户
-func (dao *UserDaoImpl) DeleteUser(id int64) error {
-	return db.GetDB().Where("id = ?", id).Delete(&model.UserModel{}).Error
+func (dao *UserDaoImpl) DeleteUser(ctx context.Context, id int64) error {
+	return db.GetDB().WithContext(ctx).Where("id = ?", id).Delete(&model.UserModel{}).Error
 } 
\ No newline at end of file
@@ -1,19 +1,28 @@
 package dao
 
 import (
+	"context"
+	"time"
 	"wxcloudrun-golang/db/model"
 )
 
 // UserDao 用户数据访问接口
 type UserDao interface {
-	CreateUser(user *model.UserModel) error
-	GetUserByUsername(username string) (*model.UserModel, error)
-	GetById(id int64) (*model.UserModel, error)
-	GetUserByOpenId(openId string) (*model.UserModel, error)
-	GetUserByUnionId(unionId string) (*model.UserModel, error)
-	GetUsersByPage(page, pageSize int) ([]*model.UserModel, int64, error)
-	UpdateUser(user *model.UserModel) error
-	DeleteUser(id int64) error
+	CreateUser(ctx context.Context, user *model.UserModel) error
+	GetUserByUsername(ctx context.Context, username string) (*model.UserModel, error)
+	GetById(ctx context.Context, id int64) (*model.UserModel, error)
+	GetUserByOpenId(ctx context.Context, openId string) (*model.UserModel, error)
+	GetUserByUnionId(ctx context.Context, unionId string) (*model.UserModel, error)
+	GetUsersByPage(ctx context.Context, page, pageSize int) ([]*model.UserModel, int64, error)
+	UpdateUser(ctx context.Context, user *model.UserModel) error
+	UpdateRole(ctx context.Context, id int64, role string) error
+	UpdateStatus(ctx context.Context, id int64, status, reason string, until *time.Time, operatorId int64) error
+	// UpdateProfile 更新资料字段；avatarCheck不为空时在同一事务中写入头像检测记录和检测任务
+	UpdateProfile(ctx context.Context, id int64, fields map[string]interface{}, avatarCheck *model.MediaCheckModel, newJob func(check *model.MediaCheckModel) (*model.JobModel, error)) error
+	// ApplyPendingField 处理待审核的资料字段：approved为true时以待审核值替换当前值，否则丢弃待审核值。
+	// 仅当待审核值仍为value时才更新，返回false表示用户已再次修改该字段
+	ApplyPendingField(ctx context.Context, id int64, field, value string, approved bool) (bool, error)
+	DeleteUser(ctx context.Context, id int64) error
 }
 
 // UserDaoImpl 用户数据访问实现
@@ -1,6 +1,7 @@
 package dao
 
 import (
+	"context"
 	"gorm.io/gorm"
 	"wxcloudrun-golang/db"
 	"wxcloudrun-golang/db/model"
@@ -17,19 +18,19 @@ func NewUserLikeDao() UserLikeDao {
 }
 
 // Create 创建点赞记录
-func (dao *UserLikeDaoImpl) Create(userLike *model.UserLikeModel) error {
-	return dao.db.Create(userLike).Error
+func (dao *UserLikeDaoImpl) Create(ctx context.Context, userLike *model.UserLikeModel) error {
+	return dao.db.WithContext(ctx).Create(userLike).Error
 }
 
 // Delete 删除点赞记录
-func (dao *UserLikeDaoImpl) Delete(userId, postId int64) error {
-	return dao.db.Where("user_id = ? AND post_id = ?", userId, postId).Delete(&model.UserLikeModel{}).Error
+func (dao *UserLikeDaoImpl) Delete(ctx context.Context, userId, postId int64) error {
+	return dao.db.WithContext(ctx).Where("user_id = ? AND post_id = ?", userId, postId).Delete(&model.UserLikeModel{}).Error
 }
 
 // IsLiked 检查用户是否点赞
-func (dao *UserLikeDaoImpl) IsLiked(userId, postId int64) (bool, error) {
+func (dao *UserLikeDaoImpl) IsLiked(ctx context.Context, userId, postId int64) (bool, error) {
 	var count int64
-	err := dao.db.Model(&model.UserLikeModel{}).Where("user_id = ? AND post_id = ?", userId, postId).Count(&count).Error
+	err := dao.db.WithContext(ctx).Model(&model.UserLikeModel{}).Where("user_id = ? AND post_id = ?", userId, postId).Count(&count).Error
 	if err != nil {
 		return false, err
 	}
@@ -37,9 +38,9 @@ func (dao *UserLikeDaoImpl) IsLiked(userId, postId int64) (bool, error) {
 }
 
 // GetUserLikedPostIds 获取用户点赞的帖子ID列表
-func (dao *UserLikeDaoImpl) GetUserLikedPostIds(userId int64) ([]int64, error) {
+func (dao *UserLikeDaoImpl) GetUserLikedPostIds(ctx context.Context, userId int64) ([]int64, error) {
 	var postIds []int64
-	err := dao.db.Model(&model.UserLikeModel{}).Where("user_id = ?", userId).Pluck("post_id", &postIds).Error
+	err := dao.db.WithContext(ctx).Model(&model.UserLikeModel{}).Where("user_id = ?", userId).Pluck("post_id", &postIds).Error
 	if err != nil {
 		return nil, err
 	}
@@ -2,9 +2,13 @@ package db
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"time"
 	"wxcloudrun-golang/db/model"
+	"wxcloudrun-golang/logger"
+	"wxcloudrun-golang/metrics"
+	"wxcloudrun-golang/tracing"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -25,20 +29,33 @@ func Init() error {
 		dataBase = "golang_demo"
 	}
 	source = fmt.Sprintf(source, user, pwd, addr, dataBase)
-	fmt.Println("start init mysql with ", source)
+	slog.Info("start init mysql", "addr", addr, "database", dataBase)
 
 	db, err := gorm.Open(mysql.Open(source), &gorm.Config{
 		NamingStrategy: schema.NamingStrategy{
 			SingularTable: true, // use singular table name, table for `User` would be `user` with this option enabled
-		}})
+		},
+		Logger: logger.NewGormLogger(),
+	})
 	if err != nil {
-		fmt.Println("DB Open error,err=", err.Error())
+		slog.Error("DB Open error", "error", err)
+		return err
+	}
+
+	// 注册数据库操作耗时指标
+	if err := db.Use(metrics.NewGormPlugin()); err != nil {
+		slog.Error("DB register metrics plugin error", "error", err)
+		return err
+	}
+	// 注册数据库操作链路追踪
+	if err := db.Use(tracing.NewGormPlugin()); err != nil {
+		slog.Error("DB register tracing plugin error", "error", err)
 		return err
 	}
 
 	sqlDB, err := db.DB()
 	if err != nil {
-		fmt.Println("DB Init error,err=", err.Error())
+		slog.Error("DB Init error", "error", err)
 		return err
 	}
 
@@ -58,16 +75,33 @@ func Init() error {
 		&model.CommentModel{},
 		&model.CategoryModel{},
 		&model.UserLikeModel{},
+		&model.RateLimitCounterModel{},
+		&model.ModerationQueueModel{},
+		&model.NotificationModel{},
+		&model.AdminAuditLogModel{},
+		&model.ReportModel{},
+		&model.UserBlockModel{},
+		&model.ContentCheckModel{},
+		&model.JobModel{},
+		&model.MediaCheckDetailModel{},
+		&model.ModerationPolicyModel{},
+		&model.BlockedKeywordModel{},
+		&model.PostVideoModel{},
 	)
 	if err != nil {
-		fmt.Println("AutoMigrate error,err=", err.Error())
+		slog.Error("AutoMigrate error", "error", err)
+		return err
+	}
+
+	if err := migrateReportPendingIndex(db); err != nil {
+		slog.Error("migrate reports error", "error", err)
 		return err
 	}
 
 	// 初始化默认分类数据
 	initDefaultCategories(db)
 
-	fmt.Println("finish init mysql with ", source)
+	slog.Info("finish init mysql", "addr", addr, "database", dataBase)
 	return nil
 }
 
@@ -81,6 +115,20 @@ func GetDB() *gorm.DB {
 	return dbInstance
 }
 
+// migrateReportPendingIndex 举报唯一键改为只约束待处理举报：删除旧的 uk_reporter_target，
+// 并为升级前的待处理举报补上pending_flag，可重复执行
+func migrateReportPendingIndex(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if migrator.HasIndex(&model.ReportModel{}, "uk_reporter_target") {
+		if err := migrator.DropIndex(&model.ReportModel{}, "uk_reporter_target"); err != nil {
+			return err
+		}
+	}
+	return db.Model(&model.ReportModel{}).
+		Where("status = ? AND pending_flag IS NULL", model.ReportStatusPending).
+		Update("pending_flag", 1).Error
+}
+
 // initDefaultCategories 初始化默认分类数据
 func initDefaultCategories(db *gorm.DB) {
 	var count int64
@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// AdminAuditLogModel 后台操作审计日志模型
+type AdminAuditLogModel struct {
+	Id         int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	OperatorId int64     `gorm:"column:operator_id;not null;index" json:"operatorId"`     // 操作人ID
+	Role       string    `gorm:"column:role;type:varchar(20)" json:"role"`                // 操作时的角色
+	Action     string    `gorm:"column:action;type:varchar(50);not null;index" json:"action"` // 操作类型
+	TargetType string    `gorm:"column:target_type;type:varchar(20)" json:"targetType"`   // 操作对象类型
+	TargetId   int64     `gorm:"column:target_id;default:0" json:"targetId"`              // 操作对象ID
+	Detail     string    `gorm:"column:detail;type:text" json:"detail"`                   // 操作详情（JSON）
+	IP         string    `gorm:"column:ip;type:varchar(64)" json:"ip"`                    // 操作人IP
+	RequestId  string    `gorm:"column:request_id;type:varchar(64)" json:"requestId"`     // 请求ID
+	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime;index" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (AdminAuditLogModel) TableName() string {
+	return "admin_audit_logs"
+}
+
+// 审计操作类型常量
+const (
+	AuditActionSetUserRole       = "user.set_role"
+	AuditActionSetUserStatus     = "user.set_status"
+	AuditActionTakedownPost      = "post.takedown"
+	AuditActionCreateCategory    = "category.create"
+	AuditActionUpdateCategory    = "category.update"
+	AuditActionReorderCategories = "category.reorder"
+	AuditActionDeleteCategory    = "category.delete"
+	AuditActionMergeCategory     = "category.merge"
+	AuditActionModerationApprove = "moderation.approve"
+	AuditActionModerationReject  = "moderation.reject"
+	AuditActionResolveReport     = "report.resolve"
+	AuditActionUpdatePolicy      = "moderation.update_policy"
+	AuditActionCreateKeyword     = "keyword.create"
+	AuditActionUpdateKeyword     = "keyword.update"
+	AuditActionDeleteKeyword     = "keyword.delete"
+)
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// BlockedKeywordModel 本地关键词过滤词库，发帖和评论在调用微信内容安全接口前先按词库匹配
+type BlockedKeywordModel struct {
+	Id         int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Word       string    `gorm:"column:word;type:varchar(100);not null;uniqueIndex" json:"word"` // 关键词
+	Variants   string    `gorm:"column:variants;type:varchar(500)" json:"variants"`              // 变体（拼音、缩写、谐音等），逗号分隔
+	Action     string    `gorm:"column:action;type:varchar(20);not null" json:"action"`          // 命中后的处理：reject/review
+	Note       string    `gorm:"column:note;type:varchar(200)" json:"note"`                      // 备注
+	OperatorId int64     `gorm:"column:operator_id;default:0" json:"operatorId"`                 // 最后修改人ID
+	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt  time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (BlockedKeywordModel) TableName() string {
+	return "blocked_keywords"
+}
+
+// 关键词命中后的处理
+const (
+	KeywordActionReject = "reject" // 直接拒绝
+	KeywordActionReview = "review" // 发布为待审核
+)
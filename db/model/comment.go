@@ -10,6 +10,10 @@ type CommentModel struct {
 	PostId    int64     `gorm:"column:post_id;not null;index" json:"postId"`
 	ParentId  *int64    `gorm:"column:parent_id;index" json:"parentId"`
 	Likes     int       `gorm:"column:likes;default:0" json:"likes"`
+	Audio         string `gorm:"column:audio;type:varchar(500)" json:"audio"`        // 语音评论地址（cloud:// 文件ID或URL）
+	AudioDuration int    `gorm:"column:audio_duration;default:0" json:"audioDuration"` // 语音时长（秒）
+	ModerationStatus int    `gorm:"column:moderation_status;default:0;index" json:"moderationStatus"` // 审核状态，取值同帖子
+	ModerationReason string `gorm:"column:moderation_reason;type:varchar(200)" json:"-"`              // 审核原因
 	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
 	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
 }
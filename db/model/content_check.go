@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+// ContentCheckModel 内容安全检测记录模型，记录每一次文本和图片/音频检测，用于申诉处理和检测策略调优
+type ContentCheckModel struct {
+	Id              int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	TargetType      string    `gorm:"column:target_type;type:varchar(20);not null;index:idx_content_check_target" json:"targetType"` // 检测对象类型：post/comment/user
+	TargetId        int64     `gorm:"column:target_id;default:0;index:idx_content_check_target" json:"targetId"`                     // 检测对象ID，内容被拒绝未创建时为0
+	PostId          int64     `gorm:"column:post_id;default:0;index" json:"postId"`                                                  // 所属帖子ID，评论检测时为评论所在帖子
+	UserId          int64     `gorm:"column:user_id;not null;index" json:"userId"`                                                   // 内容作者ID
+	Kind            string    `gorm:"column:kind;type:varchar(10);not null" json:"kind"`                                             // 检测类型：text/image/audio/video
+	Field           string    `gorm:"column:field;type:varchar(20)" json:"field"`                                                    // 检测字段：title/content/image等
+	Scene           int       `gorm:"column:scene;default:0" json:"scene"`                                                           // 检测场景
+	Content         string    `gorm:"column:content;type:varchar(500)" json:"content"`                                               // 文本内容摘要或媒体URL
+	Suggest         string    `gorm:"column:suggest;type:varchar(20);index" json:"suggest"`                                          // 检测建议：pass/review/risky，异步检测结果返回前为空
+	Label           int       `gorm:"column:label;default:0" json:"label"`                                                           // 检测标签
+	Prob            float64   `gorm:"column:prob;type:decimal(5,2);default:0" json:"prob"`                                           // 置信度
+	Keywords        string    `gorm:"column:keywords;type:varchar(500)" json:"keywords"`                                             // 命中的关键词，逗号分隔
+	TraceId         string    `gorm:"column:trace_id;type:varchar(100);index" json:"traceId"`                                        // 微信检测追踪ID
+	Errcode         int       `gorm:"column:errcode;default:0" json:"errcode"`                                                       // 错误码，-1表示请求失败
+	Errmsg          string    `gorm:"column:errmsg;type:varchar(200)" json:"errmsg"`                                                 // 错误信息
+	LatencyMs       int64     `gorm:"column:latency_ms;default:0" json:"latencyMs"`                                                  // 检测接口调用耗时
+	ResultLatencyMs int64     `gorm:"column:result_latency_ms;default:0" json:"resultLatencyMs"`                                     // 异步检测从提交到收到结果的耗时
+	CreatedAt       time.Time `gorm:"column:created_at;autoCreateTime;index" json:"createdAt"`
+	UpdatedAt       time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (ContentCheckModel) TableName() string {
+	return "content_checks"
+}
+
+// 内容检测类型常量
+const (
+	ContentCheckKindText    = "text"
+	ContentCheckKindImage   = "image"
+	ContentCheckKindAudio   = "audio"
+	ContentCheckKindVideo   = "video"
+	ContentCheckKindKeyword = "keyword" // 本地关键词过滤
+)
+
+// 内容检测对象类型常量
+const (
+	ContentCheckTargetPost    = "post"
+	ContentCheckTargetComment = "comment"
+	ContentCheckTargetUser    = "user"
+)
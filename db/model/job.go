@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// JobModel 异步任务模型，任务持久化在数据库中，由后台worker抢占执行并按退避策略重试
+type JobModel struct {
+	Id          int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Type        string     `gorm:"column:type;type:varchar(50);not null;index" json:"type"`           // 任务类型
+	Payload     string     `gorm:"column:payload;type:text" json:"payload"`                           // 任务参数（JSON）
+	Status      int        `gorm:"column:status;default:0;index:idx_job_status_run_at" json:"status"` // 状态：0-待执行 1-执行中 2-已完成 3-已放弃
+	Attempts    int        `gorm:"column:attempts;default:0" json:"attempts"`                         // 已执行次数
+	MaxAttempts int        `gorm:"column:max_attempts;default:5" json:"maxAttempts"`                  // 最多执行次数
+	RunAt       time.Time  `gorm:"column:run_at;not null;index:idx_job_status_run_at" json:"runAt"`   // 最早执行时间
+	LockedBy    string     `gorm:"column:locked_by;type:varchar(64)" json:"lockedBy"`                 // 执行中的worker标识
+	LockedAt    *time.Time `gorm:"column:locked_at" json:"lockedAt"`                                  // 抢占时间，超时未完成的任务会被重新抢占
+	LastError   string     `gorm:"column:last_error;type:varchar(500)" json:"lastError"`              // 最近一次失败原因
+	CreatedAt   time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (JobModel) TableName() string {
+	return "jobs"
+}
+
+// 任务状态常量
+const (
+	JobStatusPending   = 0 // 待执行
+	JobStatusRunning   = 1 // 执行中
+	JobStatusDone      = 2 // 已完成
+	JobStatusAbandoned = 3 // 超出重试次数已放弃
+)
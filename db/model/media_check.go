@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// MediaCheckModel 媒体（图片、语音）异步检测记录模型。表名和 image_url 列沿用图片检测时期的命名
+type MediaCheckModel struct {
+	Id          int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	PostId      int64     `gorm:"column:post_id;not null;index" json:"postId"`      // 关联的帖子ID，头像检测为0
+	CommentId   int64     `gorm:"column:comment_id;default:0;index" json:"commentId"` // 语音评论检测关联的评论ID，帖子媒体和头像检测为0
+	UserId      int64     `gorm:"column:user_id;default:0;index" json:"userId"`     // 头像检测关联的用户ID，帖子图片检测为0
+	MediaType   int       `gorm:"column:media_type;default:2" json:"mediaType"`     // 媒体类型：1-语音 2-图片（取值同微信 media_type） 3-视频
+	MediaURL    string    `gorm:"column:image_url;type:varchar(500);not null" json:"mediaUrl"` // 媒体URL或云存储文件ID
+	TraceId     string    `gorm:"column:trace_id;type:varchar(100);not null;index" json:"traceId"` // 微信检测追踪ID
+	Status      int       `gorm:"column:status;default:0" json:"status"` // 检测状态：0-待检测 1-检测中 2-检测通过 3-检测失败 4-待人工审核
+	Suggest     string    `gorm:"column:suggest;type:varchar(20)" json:"suggest"` // 综合所有检测策略后的结论：pass/review/risky，各策略结果见 image_check_details
+	Errcode     int       `gorm:"column:errcode;default:0" json:"errcode"` // 错误码
+	Errmsg      string    `gorm:"column:errmsg;type:varchar(200)" json:"errmsg"` // 错误信息
+	Attempts    int       `gorm:"column:attempts;default:0" json:"attempts"` // 已提交检测的次数，首次提交成功后为1
+	SubmittedAt *time.Time `gorm:"column:submitted_at" json:"submittedAt"` // 最近一次提交检测的时间，为空时以创建时间为准
+	ResultTime  int64     `gorm:"column:result_time;default:0" json:"resultTime"` // 当前检测结果对应回调的推送时间（CreateTime），用于忽略重复和过期的回调
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (MediaCheckModel) TableName() string {
+	return "image_checks"
+}
+
+// 媒体类型常量，语音和图片与 media_check_async 的 media_type 一致
+const (
+	MediaTypeAudio = 1 // 语音
+	MediaTypeImage = 2 // 图片
+	MediaTypeVideo = 3 // 视频，微信不支持视频检测，由本地配置的视频检测器检测
+)
+
+// 媒体检测状态常量
+const (
+	MediaCheckStatusPending = 0 // 待检测
+	MediaCheckStatusChecking = 1 // 检测中
+	MediaCheckStatusPassed = 2   // 检测通过
+	MediaCheckStatusFailed = 3   // 检测失败
+	MediaCheckStatusReview = 4   // 待人工审核
+)
+
+// 检测建议常量
+const (
+	SuggestPass = "pass"   // 通过
+	SuggestReview = "review" // 需要人工审核
+	SuggestRisky = "risky"   // 有风险
+)
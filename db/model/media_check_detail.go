@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// MediaCheckDetailModel 媒体检测回调中每个检测策略的结果，一条媒体检测记录对应多条
+type MediaCheckDetailModel struct {
+	Id           int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	MediaCheckId int64     `gorm:"column:image_check_id;not null;index" json:"mediaCheckId"` // 关联的媒体检测记录ID
+	Strategy     string    `gorm:"column:strategy;type:varchar(50)" json:"strategy"`         // 检测策略
+	Errcode      int       `gorm:"column:errcode;default:0" json:"errcode"`                  // 该策略的错误码
+	Suggest      string    `gorm:"column:suggest;type:varchar(20)" json:"suggest"`           // 微信给出的建议：pass/review/risky
+	Label        int       `gorm:"column:label;default:0" json:"label"`                      // 检测标签
+	Prob         float64   `gorm:"column:prob;type:decimal(5,2);default:0" json:"prob"`      // 置信度
+	Verdict      string    `gorm:"column:verdict;type:varchar(20)" json:"verdict"`           // 按标签阈值判定后的结论：pass/review/risky
+	CreatedAt    time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (MediaCheckDetailModel) TableName() string {
+	return "image_check_details"
+}
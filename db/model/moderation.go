@@ -0,0 +1,56 @@
+package model
+
+import "time"
+
+// ModerationQueueModel 人工审核队列模型
+type ModerationQueueModel struct {
+	Id           int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	TargetType   string     `gorm:"column:target_type;type:varchar(20);not null;index:idx_moderation_target" json:"targetType"` // 审核对象类型：post/comment/user
+	TargetId     int64      `gorm:"column:target_id;not null;index:idx_moderation_target" json:"targetId"`                      // 审核对象ID
+	PostId       int64      `gorm:"column:post_id;not null;index" json:"postId"`                                                // 所属帖子ID
+	AuthorId     int64      `gorm:"column:author_id;not null;index" json:"authorId"`                                            // 内容作者ID
+	Source       string     `gorm:"column:source;type:varchar(20);not null" json:"source"`                                      // 进入审核的来源：text/image/audio/video/spam/keyword/degraded/report
+	Content      string     `gorm:"column:content;type:text" json:"content"`                                                    // 待审核内容快照（文本、图片或语音地址）
+	Reason       string     `gorm:"column:reason;type:varchar(200)" json:"reason"`                                              // 进入审核的原因
+	TraceId      string     `gorm:"column:trace_id;type:varchar(100);index" json:"traceId"`                                     // 微信检测追踪ID
+	MediaCheckId int64      `gorm:"column:media_check_id;default:0" json:"mediaCheckId"`                                        // 媒体检测记录ID，非媒体审核项为0
+	Suggest      string     `gorm:"column:suggest;type:varchar(20)" json:"suggest"`                                             // 检测建议
+	Label        int        `gorm:"column:label;default:0" json:"label"`                                                        // 检测标签
+	Status       int        `gorm:"column:status;default:0;index" json:"status"`                                                // 审核状态：0-待审核 1-已通过 2-已驳回
+	ReviewerId   int64      `gorm:"column:reviewer_id;default:0" json:"reviewerId"`                                             // 审核人ID
+	ReviewNote   string     `gorm:"column:review_note;type:varchar(200)" json:"reviewNote"`                                     // 审核备注
+	ReviewedAt   *time.Time `gorm:"column:reviewed_at" json:"reviewedAt"`                                                       // 审核时间
+	CreatedAt    time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt    time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (ModerationQueueModel) TableName() string {
+	return "moderation_queue"
+}
+
+// 审核对象类型常量
+const (
+	ModerationTargetPost    = "post"
+	ModerationTargetComment = "comment"
+	ModerationTargetUser    = "user" // 用户资料（昵称、简介、头像），PostId为0
+)
+
+// 进入审核的来源常量
+const (
+	ModerationSourceText     = "text"     // 文本内容安全检测建议人工审核
+	ModerationSourceImage    = "image"    // 图片内容安全检测建议人工审核
+	ModerationSourceAudio    = "audio"    // 语音内容安全检测建议人工审核
+	ModerationSourceVideo    = "video"    // 视频检测建议人工审核
+	ModerationSourceSpam     = "spam"     // 反垃圾检测命中
+	ModerationSourceKeyword  = "keyword"  // 本地关键词过滤命中
+	ModerationSourceDegraded = "degraded" // 微信内容安全接口不可用，降级为人工审核
+	ModerationSourceReport   = "report"   // 待处理举报数达到阈值，自动隐藏后等待人工审核
+)
+
+// 审核队列状态常量
+const (
+	ModerationQueuePending  = 0 // 待审核
+	ModerationQueueApproved = 1 // 已通过
+	ModerationQueueRejected = 2 // 已驳回
+)
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// ModerationPolicyModel 审核策略版本，每次修改写入一条新记录，ID最大的记录为当前生效的策略
+type ModerationPolicyModel struct {
+	Id         int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Rules      string    `gorm:"column:rules;type:text;not null" json:"-"`      // 策略规则，JSON数组
+	Note       string    `gorm:"column:note;type:varchar(200)" json:"note"`     // 修改说明
+	OperatorId int64     `gorm:"column:operator_id;not null" json:"operatorId"` // 修改人ID
+	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (ModerationPolicyModel) TableName() string {
+	return "moderation_policies"
+}
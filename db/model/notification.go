@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// NotificationModel 站内通知模型
+type NotificationModel struct {
+	Id         int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserId     int64     `gorm:"column:user_id;not null;index" json:"userId"`              // 接收通知的用户ID
+	Type       string    `gorm:"column:type;type:varchar(30);not null" json:"type"`        // 通知类型
+	Title      string    `gorm:"column:title;type:varchar(100);not null" json:"title"`     // 通知标题
+	Content    string    `gorm:"column:content;type:varchar(500)" json:"content"`          // 通知内容
+	TargetType string    `gorm:"column:target_type;type:varchar(20)" json:"targetType"`    // 关联对象类型：post/comment
+	TargetId   int64     `gorm:"column:target_id;default:0" json:"targetId"`               // 关联对象ID
+	IsRead     bool      `gorm:"column:is_read;default:false;index" json:"isRead"`         // 是否已读
+	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (NotificationModel) TableName() string {
+	return "notifications"
+}
+
+// 通知类型常量
+const (
+	NotificationModerationApproved = "moderation_approved" // 内容审核通过
+	NotificationModerationRejected = "moderation_rejected" // 内容审核未通过
+	NotificationReportResolved     = "report_resolved"     // 举报已处理
+	NotificationAccountStatus      = "account_status"      // 账号状态变更（禁言、停用、封禁、解除）
+)
@@ -13,7 +13,11 @@ type PostModel struct {
 	CategoryName string    `gorm:"column:category_name;type:varchar(50);not null" json:"categoryName"`
 	Tags         string    `gorm:"column:tags;type:text" json:"tags"` // JSON格式存储
 	Images       string    `gorm:"column:images;type:text" json:"images"` // JSON格式存储
-	ImageCheckStatus int    `gorm:"column:image_check_status;default:0" json:"imageCheckStatus"` // 图片检测状态：0-待检测 1-检测中 2-检测通过 3-检测失败
+	Audio         string `gorm:"column:audio;type:varchar(500)" json:"audio"`        // 语音地址（cloud:// 文件ID或URL）
+	AudioDuration int    `gorm:"column:audio_duration;default:0" json:"audioDuration"` // 语音时长（秒）
+	ImageCheckStatus int    `gorm:"column:image_check_status;default:0" json:"imageCheckStatus"` // 媒体（图片、语音）检测状态：0-待检测 1-检测中 2-检测通过 3-检测失败 4-待人工审核
+	ModerationStatus int    `gorm:"column:moderation_status;default:0;index" json:"moderationStatus"` // 审核状态：0-正常 1-待审核 2-仅作者可见 3-已驳回
+	ModerationReason string `gorm:"column:moderation_reason;type:varchar(200)" json:"-"` // 审核原因
 	IsPublic     bool      `gorm:"column:is_public;default:true" json:"isPublic"`
 	IsDeleted    bool      `gorm:"column:is_deleted;default:false;index" json:"isDeleted"`
 	Likes        int       `gorm:"column:likes;default:0" json:"likes"`
@@ -27,4 +31,12 @@ type PostModel struct {
 // TableName 指定表名
 func (PostModel) TableName() string {
 	return "posts"
-} 
\ No newline at end of file
+}
+
+// 帖子/评论审核状态常量
+const (
+	ModerationStatusNormal       = 0 // 正常展示
+	ModerationStatusPending      = 1 // 待审核，仅作者可见
+	ModerationStatusShadowHidden = 2 // 静默隐藏，仅作者可见且不提示
+	ModerationStatusRejected     = 3 // 已驳回
+) 
\ No newline at end of file
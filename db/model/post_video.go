@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// PostVideoModel 帖子视频附件模型，每个帖子最多一个视频。视频和封面的检测记录在 image_checks 中
+type PostVideoModel struct {
+	Id        int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	PostId    int64     `gorm:"column:post_id;not null;uniqueIndex" json:"postId"`       // 所属帖子ID
+	FileId    string    `gorm:"column:file_id;type:varchar(500);not null" json:"fileId"` // 云存储文件ID（cloud://）
+	Duration  int       `gorm:"column:duration;default:0" json:"duration"`               // 视频时长（秒）
+	Cover     string    `gorm:"column:cover;type:varchar(500);not null" json:"cover"`    // 封面图片（cloud:// 文件ID或URL）
+	Width     int       `gorm:"column:width;default:0" json:"width"`                     // 视频宽度（像素）
+	Height    int       `gorm:"column:height;default:0" json:"height"`                   // 视频高度（像素）
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (PostVideoModel) TableName() string {
+	return "post_videos"
+}
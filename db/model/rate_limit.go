@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// RateLimitCounterModel 限流计数模型（固定窗口），用于多实例共享限流状态
+type RateLimitCounterModel struct {
+	Id          int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	BucketKey   string    `gorm:"column:bucket_key;type:varchar(191);not null;uniqueIndex:uk_bucket_window" json:"bucketKey"`
+	WindowStart int64     `gorm:"column:window_start;not null;uniqueIndex:uk_bucket_window" json:"windowStart"` // 窗口开始时间（Unix秒）
+	Count       int       `gorm:"column:count;default:0" json:"count"`
+	ExpiresAt   time.Time `gorm:"column:expires_at;not null;index" json:"expiresAt"`
+}
+
+// TableName 指定表名
+func (RateLimitCounterModel) TableName() string {
+	return "rate_limit_counters"
+}
@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// ReportModel 用户举报模型
+type ReportModel struct {
+	Id             int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	ReporterId     int64      `gorm:"column:reporter_id;not null;uniqueIndex:uk_reporter_pending" json:"reporterId"`                                          // 举报人ID
+	TargetType     string     `gorm:"column:target_type;type:varchar(20);not null;uniqueIndex:uk_reporter_pending;index:idx_report_target" json:"targetType"` // 举报对象类型：post/comment/user
+	TargetId       int64      `gorm:"column:target_id;not null;uniqueIndex:uk_reporter_pending;index:idx_report_target" json:"targetId"`                      // 举报对象ID
+	TargetAuthorId int64      `gorm:"column:target_author_id;default:0;index" json:"targetAuthorId"`                                                          // 被举报内容的作者ID（举报用户时为该用户ID）
+	Reason         string     `gorm:"column:reason;type:varchar(30);not null" json:"reason"`                                                                  // 举报原因代码
+	Description    string     `gorm:"column:description;type:varchar(500)" json:"description"`                                                                // 补充说明
+	Status         int        `gorm:"column:status;default:0;index" json:"status"`                                                                            // 处理状态：0-待处理 1-举报属实 2-已驳回
+	PendingFlag    *int       `gorm:"column:pending_flag;uniqueIndex:uk_reporter_pending" json:"-"`                                                           // 待处理时为1，处理后为NULL，同一举报人对同一对象只能有一个待处理举报
+	ResolverId     int64      `gorm:"column:resolver_id;default:0" json:"resolverId"`                                                                         // 处理人ID
+	ResolutionNote string     `gorm:"column:resolution_note;type:varchar(200)" json:"resolutionNote"`                                                         // 处理备注
+	ResolvedAt     *time.Time `gorm:"column:resolved_at" json:"resolvedAt"`                                                                                   // 处理时间
+	CreatedAt      time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+// TableName 指定表名
+func (ReportModel) TableName() string {
+	return "reports"
+}
+
+// 举报对象类型常量
+const (
+	ReportTargetPost    = "post"
+	ReportTargetComment = "comment"
+	ReportTargetUser    = "user"
+)
+
+// 举报处理状态常量
+const (
+	ReportStatusPending   = 0 // 待处理
+	ReportStatusValid     = 1 // 举报属实
+	ReportStatusDismissed = 2 // 已驳回
+)
+
+// ReportReasons 举报原因代码及说明
+var ReportReasons = map[string]string{
+	"spam":    "垃圾广告",
+	"porn":    "色情低俗",
+	"abuse":   "辱骂攻击",
+	"fraud":   "诈骗信息",
+	"illegal": "违法违规",
+	"privacy": "泄露隐私",
+	"other":   "其他",
+}
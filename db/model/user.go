@@ -4,22 +4,65 @@ import "time"
 
 // UserModel 用户模型
 type UserModel struct {
-	Id          int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
-	Username    string    `gorm:"column:username;uniqueIndex;not null" json:"username"`
-	Nickname    string    `gorm:"column:nickname;type:varchar(50)" json:"nickname"`
-	Avatar      string    `gorm:"column:avatar;type:varchar(500)" json:"avatar"`
-	Bio         string    `gorm:"column:bio;type:varchar(200)" json:"bio"`
-	Level       int       `gorm:"column:level;default:1" json:"level"`
-	IsVerified  bool      `gorm:"column:is_verified;default:false" json:"isVerified"`
-	Password    string    `gorm:"column:password;not null" json:"password"`
-	OpenId      string    `gorm:"column:openid;index" json:"openid"`
-	UnionId     string    `gorm:"column:unionid;index" json:"unionid"`
-	AppId       string    `gorm:"column:appid" json:"appid"`
-	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
-	UpdatedAt   time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+	Id               int64      `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Username         string     `gorm:"column:username;uniqueIndex;not null" json:"username"`
+	Nickname         string     `gorm:"column:nickname;type:varchar(50)" json:"nickname"`
+	Avatar           string     `gorm:"column:avatar;type:varchar(500)" json:"avatar"`
+	Bio              string     `gorm:"column:bio;type:varchar(200)" json:"bio"`
+	PendingNickname  string     `gorm:"column:pending_nickname;type:varchar(50)" json:"pendingNickname,omitempty"` // 待审核的昵称，审核通过前对其他用户展示原昵称
+	PendingAvatar    string     `gorm:"column:pending_avatar;type:varchar(500)" json:"pendingAvatar,omitempty"`    // 检测中的头像，检测通过前对其他用户展示原头像
+	PendingBio       string     `gorm:"column:pending_bio;type:varchar(200)" json:"pendingBio,omitempty"`          // 待审核的简介，审核通过前对其他用户展示原简介
+	AvatarURL        string     `gorm:"-" json:"avatarUrl,omitempty"`                                              // 头像的临时访问地址，请求带 resolveUrls=true 时返回，不入库
+	PendingAvatarURL string     `gorm:"-" json:"pendingAvatarUrl,omitempty"`                                       // 待审核头像的临时访问地址，不入库
+	Level            int        `gorm:"column:level;default:1" json:"level"`
+	IsVerified       bool       `gorm:"column:is_verified;default:false" json:"isVerified"`
+	Role             string     `gorm:"column:role;type:varchar(20);default:'user';index" json:"role"`       // 角色：user/moderator/admin
+	Status           string     `gorm:"column:status;type:varchar(20);default:'active';index" json:"status"` // 账号状态：active/muted/suspended/banned
+	StatusReason     string     `gorm:"column:status_reason;type:varchar(200)" json:"statusReason"`          // 限制原因
+	StatusUntil      *time.Time `gorm:"column:status_until" json:"statusUntil"`                              // 限制到期时间，为空表示永久
+	StatusOperatorId int64      `gorm:"column:status_operator_id;default:0" json:"-"`                        // 设置限制的管理员ID，0为系统自动
+	Password         string     `gorm:"column:password;not null" json:"password"`
+	OpenId           string     `gorm:"column:openid;index" json:"openid"`
+	UnionId          string     `gorm:"column:unionid;index" json:"unionid"`
+	AppId            string     `gorm:"column:appid" json:"appid"`
+	CreatedAt        time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt        time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
 }
 
 // TableName 指定表名
 func (UserModel) TableName() string {
 	return "users"
 }
+
+// 用户角色常量，权限依次递增
+const (
+	RoleUser      = "user"      // 普通用户
+	RoleModerator = "moderator" // 审核员，可处理审核队列、下架帖子
+	RoleAdmin     = "admin"     // 管理员，拥有全部后台权限
+)
+
+// 资料中需要审核的字段，与 pending_ 前缀的列一一对应
+const (
+	ProfileFieldNickname = "nickname"
+	ProfileFieldAvatar   = "avatar"
+	ProfileFieldBio      = "bio"
+)
+
+// 账号状态常量
+const (
+	UserStatusActive    = "active"    // 正常
+	UserStatusMuted     = "muted"     // 禁言：可以浏览，不能发帖、评论、点赞
+	UserStatusSuspended = "suspended" // 停用：到期前不能使用需要登录的接口
+	UserStatusBanned    = "banned"    // 封禁：通常为永久，不能使用需要登录的接口
+)
+
+// EffectiveStatus 返回账号在now时刻的实际状态，限制到期后视为正常
+func (u *UserModel) EffectiveStatus(now time.Time) string {
+	if u.Status == "" || u.Status == UserStatusActive {
+		return UserStatusActive
+	}
+	if u.StatusUntil != nil && !now.Before(*u.StatusUntil) {
+		return UserStatusActive
+	}
+	return u.Status
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// UserBlockModel 用户拉黑关系模型
+type UserBlockModel struct {
+	Id        int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	BlockerId int64     `gorm:"column:blocker_id;not null;uniqueIndex:uk_blocker_blocked" json:"blockerId"`       // 拉黑发起人ID
+	BlockedId int64     `gorm:"column:blocked_id;not null;uniqueIndex:uk_blocker_blocked;index" json:"blockedId"` // 被拉黑用户ID
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+// TableName 指定表名
+func (UserBlockModel) TableName() string {
+	return "user_blocks"
+}
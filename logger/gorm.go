@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// slowQueryThreshold 慢查询阈值
+const slowQueryThreshold = 200 * time.Millisecond
+
+// sqlStringLiteral 匹配GORM填充参数后SQL中的字符串字面量（含转义引号）
+var sqlStringLiteral = regexp.MustCompile(`'(?:[^'\\]|\\.)*'`)
+
+// redactSQL 将SQL中的字符串参数替换为占位符，避免openid、手机号、正文等写入日志
+func redactSQL(sql string) string {
+	return sqlStringLiteral.ReplaceAllString(sql, "?")
+}
+
+// GormLogger 基于slog的GORM日志适配器，SQL日志会携带请求ID
+type GormLogger struct {
+	level gormlogger.LogLevel
+}
+
+// NewGormLogger 创建GORM日志适配器
+func NewGormLogger() *GormLogger {
+	return &GormLogger{level: gormlogger.Warn}
+}
+
+// LogMode 设置日志级别
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	return &GormLogger{level: level}
+}
+
+// Info 输出info日志
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Info {
+		slog.InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+// Warn 输出warn日志
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		slog.WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+// Error 输出error日志
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Error {
+		slog.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+// Trace 输出SQL执行日志：出错时记录error，慢查询记录warn，其余仅在debug级别输出；
+// SQL中的字符串参数会被脱敏
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= gormlogger.Error:
+		sql, rows := fc()
+		slog.ErrorContext(ctx, "SQL执行失败", "sql", redactSQL(sql), "rows", rows, "elapsed_ms", elapsed.Milliseconds(), "error", err)
+	case elapsed > slowQueryThreshold && l.level >= gormlogger.Warn:
+		sql, rows := fc()
+		slog.WarnContext(ctx, "慢查询", "sql", redactSQL(sql), "rows", rows, "elapsed_ms", elapsed.Milliseconds())
+	case slog.Default().Enabled(ctx, slog.LevelDebug):
+		sql, rows := fc()
+		slog.DebugContext(ctx, "SQL执行", "sql", redactSQL(sql), "rows", rows, "elapsed_ms", elapsed.Milliseconds())
+	}
+}
@@ -0,0 +1,40 @@
+package logger
+
+import "testing"
+
+func TestRedactSQL(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "字符串参数被替换",
+			sql:  "SELECT * FROM `users` WHERE openid = 'o6_bmjrPTlm6_2sgVt7hMZOPfL2M' LIMIT 1",
+			want: "SELECT * FROM `users` WHERE openid = ? LIMIT 1",
+		},
+		{
+			name: "转义引号不会截断字面量",
+			sql:  "INSERT INTO `posts` (`content`,`author_id`) VALUES ('it\\'s 13800138000',42)",
+			want: "INSERT INTO `posts` (`content`,`author_id`) VALUES (?,42)",
+		},
+		{
+			name: "时间参数被替换",
+			sql:  "DELETE FROM `rate_limits` WHERE expires_at < '2026-10-19 10:00:00.000'",
+			want: "DELETE FROM `rate_limits` WHERE expires_at < ?",
+		},
+		{
+			name: "没有字符串参数时保持不变",
+			sql:  "UPDATE `posts` SET `status`=1 WHERE id = 7",
+			want: "UPDATE `posts` SET `status`=1 WHERE id = 7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactSQL(tt.sql); got != tt.want {
+				t.Errorf("redactSQL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
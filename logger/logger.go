@@ -0,0 +1,116 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// contextKey 日志上下文键类型
+type contextKey string
+
+// requestIdKey 请求ID在上下文中的键
+const requestIdKey contextKey = "request_id"
+
+// sensitiveKeys 需要脱敏的日志字段
+var sensitiveKeys = map[string]bool{
+	"openid":       true,
+	"unionid":      true,
+	"from_openid":  true,
+	"from_unionid": true,
+	"to_user_name": true,
+	"from_user":    true,
+}
+
+// Init 初始化全局日志，输出JSON格式到标准输出，供云托管日志采集
+func Init() {
+	slog.SetDefault(New(os.Stdout, parseLevel(os.Getenv("LOG_LEVEL"))))
+}
+
+// New 创建日志实例
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactAttr,
+	})
+	return slog.New(&contextHandler{Handler: handler})
+}
+
+// WithRequestId 将请求ID写入上下文
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdKey, requestId)
+}
+
+// RequestIdFromContext 从上下文中获取请求ID
+func RequestIdFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if requestId, ok := ctx.Value(requestIdKey).(string); ok {
+		return requestId
+	}
+	return ""
+}
+
+// MaskId 对openid、unionid等标识做脱敏处理，仅保留首尾各4位
+func MaskId(id string) string {
+	if len(id) <= 8 {
+		return strings.Repeat("*", len(id))
+	}
+	return id[:4] + "****" + id[len(id)-4:]
+}
+
+// contextHandler 自动附加上下文中请求ID的日志处理器
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle 输出日志记录，附加请求ID和链路追踪ID
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestId := RequestIdFromContext(ctx); requestId != "" {
+		record.AddAttrs(slog.String(string(requestIdKey), requestId))
+	}
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", spanCtx.TraceID().String()),
+			slog.String("span_id", spanCtx.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs 返回附加字段后的处理器
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup 返回分组后的处理器
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// redactAttr 对敏感字段脱敏
+func redactAttr(groups []string, attr slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(attr.Key)] && attr.Value.Kind() == slog.KindString {
+		return slog.String(attr.Key, MaskId(attr.Value.String()))
+	}
+	return attr
+}
+
+// parseLevel 解析日志级别，默认为info
+func parseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -1,19 +1,38 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"strings"
 	"wxcloudrun-golang/db"
+	"wxcloudrun-golang/db/dao"
+	"wxcloudrun-golang/db/model"
+	"wxcloudrun-golang/logger"
+	"wxcloudrun-golang/metrics"
 	"wxcloudrun-golang/service"
+	"wxcloudrun-golang/tracing"
 )
 
 func main() {
+	logger.Init()
+
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		panic(fmt.Sprintf("tracing init failed with %+v", err))
+	}
+
 	if err := db.Init(); err != nil {
 		panic(fmt.Sprintf("mysql init failed with %+v", err))
 	}
 
+	callbackVerifier, err := service.NewCallbackVerifier()
+	if err != nil {
+		panic(fmt.Sprintf("callback verifier init failed with %+v", err))
+	}
+
 	// 创建处理器实例
 	postHandler := service.NewPostHandler()
 	categoryHandler := service.NewCategoryHandler()
@@ -21,6 +40,7 @@ func main() {
 	likeHandler := service.NewLikeHandler()
 	userHandler := service.NewUserHandler()
 	authHandler := service.NewAuthHandler()
+	rateLimiter := service.NewRateLimiter()
 
 	// 统一的帖子路由处理函数
 	postsHandler := func(w http.ResponseWriter, r *http.Request) {
@@ -30,11 +50,11 @@ func main() {
 		if path == "/api/posts" || path == "/api/posts/" {
 			switch r.Method {
 			case http.MethodGet:
-				// GET 请求不需要认证，直接处理
-				postHandler.GetPostListHandler(w, r)
+				// GET 请求不需要认证，带有用户身份时按用户过滤拉黑的作者并展示本人待审核的帖子
+				service.OptionalUserMiddleware(postHandler.GetPostListHandler)(w, r)
 			case http.MethodPost:
 				// POST 请求需要认证
-				service.UserMiddleware(postHandler.CreatePostHandler)(w, r)
+				service.UserMiddleware(rateLimiter.Limit(service.RateLimitPostCreate, postHandler.CreatePostHandler))(w, r)
 			default:
 				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			}
@@ -49,14 +69,14 @@ func main() {
 				case http.MethodGet:
 					commentHandler.GetCommentListHandler(w, r)
 				case http.MethodPost:
-					commentHandler.CreateCommentHandler(w, r)
+					rateLimiter.Limit(service.RateLimitCommentCreate, commentHandler.CreateCommentHandler)(w, r)
 				default:
 					http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 				}
 			})(w, r)
 		} else if strings.HasSuffix(path, "/like") {
 			// 点赞操作需要认证
-			service.UserMiddleware(likeHandler.ToggleLikeHandler)(w, r)
+			service.UserMiddleware(rateLimiter.Limit(service.RateLimitLikeToggle, likeHandler.ToggleLikeHandler))(w, r)
 		} else if strings.HasSuffix(path, "/my") {
 			// 获取我的帖子需要认证
 			service.UserMiddleware(postHandler.GetMyPostsHandler)(w, r)
@@ -90,9 +110,53 @@ func main() {
 	// 用户相关接口
 	http.HandleFunc("/api/user/", service.UserMiddleware(userHandler.HandleUserRequests))
 
+	// 后台管理接口：审核员可处理审核队列、下架帖子、查询内容检测记录，其余接口仅管理员可用
+	adminHandler := service.NewAdminHandler()
+	moderationHandler := service.NewModerationHandler()
+	http.HandleFunc("/api/admin/moderation/", service.AdminMiddleware(model.RoleModerator, moderationHandler.HandleModerationRequests))
+	http.HandleFunc("/api/admin/posts/", service.AdminMiddleware(model.RoleModerator, adminHandler.HandlePostRequests))
+	http.HandleFunc("/api/admin/users", service.AdminMiddleware(model.RoleAdmin, adminHandler.HandleUserRequests))
+	http.HandleFunc("/api/admin/users/", service.AdminMiddleware(model.RoleAdmin, adminHandler.HandleUserRequests))
+	http.HandleFunc("/api/admin/categories", service.AdminMiddleware(model.RoleAdmin, adminHandler.HandleCategoryRequests))
+	http.HandleFunc("/api/admin/categories/", service.AdminMiddleware(model.RoleAdmin, adminHandler.HandleCategoryRequests))
+	http.HandleFunc("/api/admin/audit-logs", service.AdminMiddleware(model.RoleAdmin, adminHandler.GetAuditLogsHandler))
+	http.HandleFunc("/api/admin/content-checks", service.AdminMiddleware(model.RoleModerator, adminHandler.GetContentChecksHandler))
+	http.HandleFunc("/api/admin/moderation-policy", service.AdminMiddleware(model.RoleAdmin, adminHandler.ModerationPolicyHandler))
+	http.HandleFunc("/api/admin/keywords", service.AdminMiddleware(model.RoleModerator, adminHandler.HandleKeywordRequests))
+	http.HandleFunc("/api/admin/keywords/", service.AdminMiddleware(model.RoleModerator, adminHandler.HandleKeywordRequests))
+
+	// 举报接口
+	reportHandler := service.NewReportHandler()
+	http.HandleFunc("/api/reports", service.UserMiddleware(rateLimiter.Limit(service.RateLimitReportCreate, reportHandler.CreateReportHandler)))
+	http.HandleFunc("/api/admin/reports", service.AdminMiddleware(model.RoleModerator, reportHandler.HandleAdminReportRequests))
+	http.HandleFunc("/api/admin/reports/", service.AdminMiddleware(model.RoleModerator, reportHandler.HandleAdminReportRequests))
+
+	// 媒体上传接口，供网页端和后台工具上传图片
+	mediaHandler := service.NewMediaHandler()
+	http.HandleFunc("/api/media", service.UserMiddleware(rateLimiter.Limit(service.RateLimitMediaUpload, mediaHandler.UploadHandler)))
+
 	// 微信回调接口（不需要用户中间件）
-	wechatCallbackHandler := service.NewWechatCallbackHandler()
+	wechatCallbackHandler := service.NewWechatCallbackHandler(callbackVerifier)
 	http.HandleFunc("/api/wechat/callback", wechatCallbackHandler.HandleMediaCheckCallback)
 
-	log.Fatal(http.ListenAndServe(":80", nil))
+	// 监控指标接口
+	mediaCheckDao := dao.NewMediaCheckDao()
+	metrics.RegisterPendingMediaChecks(func() (int64, error) {
+		return mediaCheckDao.CountPending(context.Background())
+	})
+	http.Handle("/metrics", metrics.Handler())
+
+	// 后台任务：异步任务队列（提交图片检测等），以及超时未收到回调的图片检测处理
+	jobQueue := service.NewJobQueue()
+	service.NewMediaCheckJobs().Register(jobQueue)
+	jobQueue.Start(context.Background())
+	service.NewMediaCheckSweeper().Start(context.Background())
+
+	slog.Info("server started", "addr", ":80")
+	handler := service.TracingMiddleware(service.RequestIdMiddleware(service.MetricsMiddleware(http.DefaultServeMux)))
+	if err := http.ListenAndServe(":80", handler); err != nil {
+		slog.Error("server stopped", "error", err)
+		shutdownTracing(context.Background())
+		os.Exit(1)
+	}
 }
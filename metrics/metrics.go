@@ -0,0 +1,280 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "community"
+
+var (
+	// httpRequestsTotal HTTP请求数
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP请求总数",
+	}, []string{"route", "method", "status"})
+
+	// httpRequestDuration HTTP请求耗时
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP请求耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	// dbQueryDuration 数据库查询耗时
+	dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "GORM数据库操作耗时",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	// dbQueryErrors 数据库查询错误数
+	dbQueryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_query_errors_total",
+		Help:      "GORM数据库操作错误数（不含记录不存在）",
+	}, []string{"operation", "table"})
+
+	// wechatAPIRequests 微信接口调用数
+	wechatAPIRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "wechat_api_requests_total",
+		Help:      "微信开放接口调用次数，按接口和错误码区分",
+	}, []string{"api", "errcode"})
+
+	// wechatAPIDuration 微信接口调用耗时
+	wechatAPIDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "wechat_api_duration_seconds",
+		Help:      "微信开放接口调用耗时",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"api"})
+
+	// wechatAPIRetries 微信接口临时性失败后的重试次数
+	wechatAPIRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "wechat_api_retries_total",
+		Help:      "微信开放接口临时性失败（网络错误、5xx、系统繁忙）后的重试次数",
+	}, []string{"api"})
+
+	// wechatCircuitState 微信接口熔断器状态
+	wechatCircuitState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "wechat_circuit_state",
+		Help:      "微信开放接口熔断器状态：0正常，1熔断，2半开探测",
+	}, []string{"api"})
+
+	// rateLimitedTotal 被限流的请求数
+	rateLimitedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "被限流拒绝的请求数",
+	}, []string{"route"})
+
+	// callbackTotal 微信回调处理结果数
+	callbackTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "wechat_callback_total",
+		Help:      "微信回调处理结果",
+	}, []string{"event", "outcome"})
+
+	// imageCheckSweepTotal 超时图片检测的处理结果数
+	imageCheckSweepTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "image_check_sweep_total",
+		Help:      "超时未收到回调的图片检测处理结果",
+	}, []string{"outcome"})
+
+	// jobsTotal 异步任务执行结果数
+	jobsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jobs_total",
+		Help:      "异步任务执行结果",
+	}, []string{"type", "outcome"})
+
+	// jobDuration 异步任务执行耗时
+	jobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "异步任务执行耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"type"})
+)
+
+// 微信接口名称
+const (
+	APIMsgSecCheck       = "msg_sec_check"
+	APIMediaCheckAsync   = "media_check_async"
+	APIBatchDownloadFile = "batchdownloadfile"
+	APIUploadFile        = "uploadfile"
+	APIBatchDeleteFile   = "batchdeletefile"
+)
+
+// ErrcodeTransport 请求未拿到微信响应（网络错误、超时、响应无法解析）时使用的错误码标签
+const ErrcodeTransport = "transport_error"
+
+// Handler 返回 /metrics 处理器
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveHTTPRequest 记录一次HTTP请求
+func ObserveHTTPRequest(path, method string, status int, elapsed time.Duration) {
+	route := RouteLabel(path)
+	httpRequestsTotal.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	httpRequestDuration.WithLabelValues(route, method).Observe(elapsed.Seconds())
+}
+
+// ObserveWechatCall 记录一次微信接口调用，err不为空时按传输错误统计
+func ObserveWechatCall(api string, errcode int, err error, elapsed time.Duration) {
+	label := strconv.Itoa(errcode)
+	if err != nil {
+		label = ErrcodeTransport
+	}
+	wechatAPIRequests.WithLabelValues(api, label).Inc()
+	wechatAPIDuration.WithLabelValues(api).Observe(elapsed.Seconds())
+}
+
+// ObserveWechatRetry 记录一次微信接口重试
+func ObserveWechatRetry(api string) {
+	wechatAPIRetries.WithLabelValues(api).Inc()
+}
+
+// ObserveCircuitState 记录微信接口熔断器状态变化
+func ObserveCircuitState(api string, state int) {
+	wechatCircuitState.WithLabelValues(api).Set(float64(state))
+}
+
+// ObserveRateLimited 记录一次被限流的请求
+func ObserveRateLimited(route string) {
+	rateLimitedTotal.WithLabelValues(route).Inc()
+}
+
+// ObserveCallback 记录一次微信回调的处理结果
+func ObserveCallback(event, outcome string) {
+	if event == "" {
+		event = "unknown"
+	}
+	callbackTotal.WithLabelValues(event, outcome).Inc()
+}
+
+// ObserveImageCheckSweep 记录一次超时图片检测的处理结果（resubmitted/failed/review/error）
+func ObserveImageCheckSweep(outcome string) {
+	imageCheckSweepTotal.WithLabelValues(outcome).Inc()
+}
+
+// ObserveJob 记录一次异步任务执行（done/retry/abandoned/lock_lost）
+func ObserveJob(jobType, outcome string, elapsed time.Duration) {
+	jobsTotal.WithLabelValues(jobType, outcome).Inc()
+	jobDuration.WithLabelValues(jobType).Observe(elapsed.Seconds())
+}
+
+// RegisterPendingMediaChecks 注册待完成媒体检测数量指标，抓取时调用count获取最新值，查询失败时为-1
+func RegisterPendingMediaChecks(count func() (int64, error)) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "media_checks_pending",
+		Help:      "尚未得到检测结果（待提交或检测中）的媒体检测记录数，含图片、语音和视频，查询失败时为-1",
+	}, func() float64 {
+		n, err := count()
+		if err != nil {
+			return -1
+		}
+		return float64(n)
+	})
+}
+
+// routes 指标和链路追踪使用的路由模板，:id 匹配数字ID，:code 匹配任意单个路径段；
+// 按顺序匹配，固定路径需排在同级的通配路径之前。新增接口时需同步添加，否则统计为 OtherRoute
+var routes = []string{
+	"/api/posts",
+	"/api/posts/my",
+	"/api/posts/:id",
+	"/api/posts/:id/comments",
+	"/api/posts/:id/like",
+	"/api/categories",
+	"/api/categories/publish",
+	"/api/topics/hot",
+	"/api/auth/register",
+	"/api/auth/login",
+	"/api/auth/check",
+	"/api/user/profile",
+	"/api/user/notifications",
+	"/api/user/notifications/read",
+	"/api/user/blocks",
+	"/api/user/blocks/:id",
+	"/api/user/:id",
+	"/api/reports",
+	"/api/media",
+	"/api/wechat/callback",
+	"/api/admin/moderation/queue",
+	"/api/admin/moderation/queue/:id/approve",
+	"/api/admin/moderation/queue/:id/reject",
+	"/api/admin/posts/:id/takedown",
+	"/api/admin/users",
+	"/api/admin/users/:id/role",
+	"/api/admin/users/:id/status",
+	"/api/admin/categories",
+	"/api/admin/categories/reorder",
+	"/api/admin/categories/:code",
+	"/api/admin/categories/:code/merge",
+	"/api/admin/audit-logs",
+	"/api/admin/content-checks",
+	"/api/admin/moderation-policy",
+	"/api/admin/keywords",
+	"/api/admin/keywords/:id",
+	"/api/admin/reports",
+	"/api/admin/reports/reporters/:id",
+	"/api/admin/reports/:id/resolve",
+	"/metrics",
+}
+
+// OtherRoute 未注册路由的标签，避免扫描等请求的任意路径产生大量标签
+const OtherRoute = "other"
+
+// RouteLabel 将请求路径映射为注册的路由模板，未匹配的路径返回 OtherRoute，避免指标标签基数过高
+func RouteLabel(path string) string {
+	if path != "/" {
+		path = strings.TrimSuffix(path, "/")
+	}
+	segments := strings.Split(path, "/")
+	for _, route := range routes {
+		if matchRoute(strings.Split(route, "/"), segments) {
+			return route
+		}
+	}
+	return OtherRoute
+}
+
+// matchRoute 按路径段匹配路由模板
+func matchRoute(pattern, segments []string) bool {
+	if len(pattern) != len(segments) {
+		return false
+	}
+	for i, p := range pattern {
+		switch p {
+		case ":id":
+			if _, err := strconv.ParseInt(segments[i], 10, 64); err != nil {
+				return false
+			}
+		case ":code":
+			if segments[i] == "" {
+				return false
+			}
+		default:
+			if p != segments[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+	"wxcloudrun-golang/db/dao"
+	"wxcloudrun-golang/db/model"
+)
+
+// 账号受限错误，处理器据此返回403
+var (
+	ErrAccountMuted     = errors.New("账号已被禁言")
+	ErrAccountSuspended = errors.New("账号已被停用")
+	ErrAccountBanned    = errors.New("账号已被封禁")
+)
+
+// accountStatusErrors 账号状态对应的错误
+var accountStatusErrors = map[string]error{
+	model.UserStatusMuted:     ErrAccountMuted,
+	model.UserStatusSuspended: ErrAccountSuspended,
+	model.UserStatusBanned:    ErrAccountBanned,
+}
+
+// accountStatusNames 账号状态的中文名称，用于通知
+var accountStatusNames = map[string]string{
+	model.UserStatusActive:    "恢复正常",
+	model.UserStatusMuted:     "禁言",
+	model.UserStatusSuspended: "停用",
+	model.UserStatusBanned:    "封禁",
+}
+
+// accountRestriction 返回账号当前的限制错误，正常或限制已到期时返回nil。
+// writeOnly为false时只检查停用和封禁（用于登录态校验），为true时禁言也视为受限（用于发帖、评论、点赞）
+func accountRestriction(user *model.UserModel, writeOnly bool) error {
+	if user == nil {
+		return nil
+	}
+	status := user.EffectiveStatus(time.Now())
+	if status == model.UserStatusActive || (status == model.UserStatusMuted && !writeOnly) {
+		return nil
+	}
+	err, ok := accountStatusErrors[status]
+	if !ok {
+		return nil
+	}
+
+	detail := ""
+	if user.StatusReason != "" {
+		detail += "，原因：" + user.StatusReason
+	}
+	if user.StatusUntil != nil {
+		detail += "，解除时间：" + user.StatusUntil.Format("2006-01-02 15:04")
+	}
+	return fmt.Errorf("%w%s", err, detail)
+}
+
+// checkAccountWritable 校验账号可以发帖、评论、点赞
+func checkAccountWritable(user *model.UserModel) error {
+	return accountRestriction(user, true)
+}
+
+// IsAccountRestricted 判断错误是否为账号受限错误
+func IsAccountRestricted(err error) bool {
+	return errors.Is(err, ErrAccountMuted) || errors.Is(err, ErrAccountSuspended) || errors.Is(err, ErrAccountBanned)
+}
+
+// autoRestrictTier 自动限制档位：窗口内违规次数达到Threshold时设置Status，持续Duration
+type autoRestrictTier struct {
+	Threshold int
+	Status    string
+	Duration  time.Duration
+}
+
+// AccountService 账号状态服务
+type AccountService struct {
+	userDao         dao.UserDao
+	rateLimitDao    dao.RateLimitDao
+	notificationDao dao.NotificationDao
+	auditService    *AuditService
+	violationWindow time.Duration
+	tiers           []autoRestrictTier // 按Threshold降序排列
+}
+
+// NewAccountService 创建账号状态服务实例。
+// 自动限制通过环境变量配置：ACCOUNT_VIOLATION_WINDOW（统计窗口，默认24h）、
+// AUTO_MUTE_THRESHOLD/AUTO_MUTE_DURATION（默认3次，禁言24h）、
+// AUTO_SUSPEND_THRESHOLD/AUTO_SUSPEND_DURATION（默认10次，停用168h），阈值为0表示关闭该档
+func NewAccountService() *AccountService {
+	window := envDuration("ACCOUNT_VIOLATION_WINDOW", 24*time.Hour)
+	tiers := []autoRestrictTier{
+		{
+			Threshold: envInt("AUTO_SUSPEND_THRESHOLD", 10),
+			Status:    model.UserStatusSuspended,
+			Duration:  envDuration("AUTO_SUSPEND_DURATION", 7*24*time.Hour),
+		},
+		{
+			Threshold: envInt("AUTO_MUTE_THRESHOLD", 3),
+			Status:    model.UserStatusMuted,
+			Duration:  envDuration("AUTO_MUTE_DURATION", 24*time.Hour),
+		},
+	}
+
+	return &AccountService{
+		userDao:         dao.NewUserDao(),
+		rateLimitDao:    dao.NewRateLimitDao(),
+		notificationDao: dao.NewNotificationDao(),
+		auditService:    NewAuditService(),
+		violationWindow: window,
+		tiers:           tiers,
+	}
+}
+
+// SetUserStatusRequest 设置账号状态请求
+type SetUserStatusRequest struct {
+	Status   string `json:"status"`   // active/muted/suspended/banned
+	Reason   string `json:"reason"`   // 限制原因，会展示给用户
+	Duration string `json:"duration"` // 限制时长，如 "24h"、"168h"，为空表示永久；status为active时忽略
+}
+
+// SetStatus 管理员设置账号状态，status为active时解除限制
+func (s *AccountService) SetStatus(ctx context.Context, operator *UserContext, userId int64, req *SetUserStatusRequest) (*model.UserModel, error) {
+	if _, ok := accountStatusNames[req.Status]; !ok {
+		return nil, fmt.Errorf("无效的账号状态: %s", req.Status)
+	}
+	if operator != nil && operator.User != nil && operator.User.Id == userId {
+		return nil, fmt.Errorf("不能修改自己的账号状态")
+	}
+
+	user, err := s.userDao.GetById(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("用户不存在: %v", err)
+	}
+	if req.Status != model.UserStatusActive && operator != nil && HasRole(user, model.RoleAdmin) {
+		return nil, fmt.Errorf("不能限制管理员账号，请先调整其角色")
+	}
+
+	var until *time.Time
+	reason := req.Reason
+	if req.Status == model.UserStatusActive {
+		reason = ""
+	} else if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("无效的限制时长: %s", req.Duration)
+		}
+		t := time.Now().Add(d)
+		until = &t
+	}
+
+	var operatorId int64
+	if operator != nil && operator.User != nil {
+		operatorId = operator.User.Id
+	}
+	if err := s.applyStatus(ctx, user, req.Status, reason, until, operatorId); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(ctx, operator, model.AuditActionSetUserStatus, "user", userId, map[string]interface{}{
+		"from":     user.EffectiveStatus(time.Now()),
+		"to":       req.Status,
+		"reason":   req.Reason,
+		"duration": req.Duration,
+	})
+
+	user.Status = req.Status
+	user.StatusReason = reason
+	user.StatusUntil = until
+	user.StatusOperatorId = operatorId
+	return user, nil
+}
+
+// RecordViolation 记录一次内容安全违规（内容安全检测拒绝），窗口内违规次数达到阈值时自动限制账号。
+// 记录失败不影响主流程
+func (s *AccountService) RecordViolation(ctx context.Context, userId int64, source string) {
+	if userId == 0 || s.violationWindow <= 0 {
+		return
+	}
+
+	start, _ := windowBounds(time.Now(), s.violationWindow)
+	key := fmt.Sprintf("violation:user:%d", userId)
+	count, err := s.rateLimitDao.Increment(ctx, key, start.Unix(), start.Add(s.violationWindow))
+	if err != nil {
+		slog.ErrorContext(ctx, "记录违规次数失败", "user_id", userId, "source", source, "error", err)
+		return
+	}
+	slog.WarnContext(ctx, "内容安全违规", "user_id", userId, "source", source, "count", count)
+
+	for _, tier := range s.tiers {
+		if tier.Threshold <= 0 || count != tier.Threshold {
+			continue
+		}
+		s.autoRestrict(ctx, userId, tier, count)
+		return
+	}
+}
+
+// RecordPostViolation 记录帖子媒体（图片、语音、视频）的违规，同一帖子只计一次：一次发布多张违规图片时
+// 各媒体的检测结果分别到达，以帖子为键去重，避免单次发布即达到自动限制阈值
+func (s *AccountService) RecordPostViolation(ctx context.Context, userId, postId int64, source string) {
+	if userId == 0 || s.violationWindow <= 0 {
+		return
+	}
+	key := fmt.Sprintf("violation:post:%d", postId)
+	count, err := s.rateLimitDao.Increment(ctx, key, 0, time.Now().Add(s.violationWindow))
+	if err != nil {
+		slog.ErrorContext(ctx, "记录帖子违规失败", "user_id", userId, "post_id", postId, "source", source, "error", err)
+		return
+	}
+	if count > 1 {
+		slog.InfoContext(ctx, "帖子已计入违规，不重复计数", "user_id", userId, "post_id", postId, "source", source)
+		return
+	}
+	s.RecordViolation(ctx, userId, source)
+}
+
+// autoRestrict 按档位自动限制账号，已有更严格或更长的限制时不覆盖
+func (s *AccountService) autoRestrict(ctx context.Context, userId int64, tier autoRestrictTier, count int) {
+	user, err := s.userDao.GetById(ctx, userId)
+	if err != nil {
+		slog.ErrorContext(ctx, "自动限制账号失败", "user_id", userId, "error", err)
+		return
+	}
+	if HasRole(user, model.RoleModerator) {
+		return
+	}
+
+	until := time.Now().Add(tier.Duration)
+	current := user.EffectiveStatus(time.Now())
+	if statusSeverity[current] > statusSeverity[tier.Status] ||
+		(current == tier.Status && (user.StatusUntil == nil || user.StatusUntil.After(until))) {
+		return
+	}
+
+	reason := fmt.Sprintf("%s内多次发布违规内容（%d次）", formatViolationWindow(s.violationWindow), count)
+	if err := s.applyStatus(ctx, user, tier.Status, reason, &until, 0); err != nil {
+		slog.ErrorContext(ctx, "自动限制账号失败", "user_id", userId, "error", err)
+		return
+	}
+	slog.WarnContext(ctx, "账号已被自动限制", "user_id", userId, "status", tier.Status, "until", until)
+}
+
+// statusSeverity 账号状态的严重程度
+var statusSeverity = map[string]int{
+	model.UserStatusActive:    0,
+	model.UserStatusMuted:     1,
+	model.UserStatusSuspended: 2,
+	model.UserStatusBanned:    3,
+}
+
+// applyStatus 更新账号状态并通知用户
+func (s *AccountService) applyStatus(ctx context.Context, user *model.UserModel, status, reason string, until *time.Time, operatorId int64) error {
+	if err := s.userDao.UpdateStatus(ctx, user.Id, status, reason, until, operatorId); err != nil {
+		return fmt.Errorf("更新账号状态失败: %v", err)
+	}
+
+	notification := &model.NotificationModel{
+		UserId:     user.Id,
+		Type:       model.NotificationAccountStatus,
+		TargetType: "user",
+		TargetId:   user.Id,
+	}
+	if status == model.UserStatusActive {
+		notification.Title = "你的账号已恢复正常"
+		notification.Content = "你的账号限制已解除。"
+	} else {
+		notification.Title = fmt.Sprintf("你的账号已被%s", accountStatusNames[status])
+		notification.Content = fmt.Sprintf("你的账号已被%s", accountStatusNames[status])
+		if reason != "" {
+			notification.Content += "，原因：" + reason
+		}
+		if until != nil {
+			notification.Content += "，解除时间：" + until.Format("2006-01-02 15:04")
+		}
+		notification.Content += "。"
+	}
+	if err := s.notificationDao.Create(ctx, notification); err != nil {
+		slog.ErrorContext(ctx, "发送账号状态通知失败", "user_id", user.Id, "error", err)
+	}
+	return nil
+}
+
+// formatViolationWindow 格式化违规统计窗口
+func formatViolationWindow(window time.Duration) string {
+	if window%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%d天", int(window/(24*time.Hour)))
+	}
+	if window%time.Hour == 0 {
+		return fmt.Sprintf("%d小时", int(window/time.Hour))
+	}
+	return window.String()
+}
+
+// envInt 读取整数环境变量，无效时使用默认值
+func envInt(key string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v >= 0 {
+		return v
+	}
+	return def
+}
+
+// envDuration 读取时长环境变量，无效时使用默认值
+func envDuration(key string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil && d >= 0 {
+		return d
+	}
+	return def
+}
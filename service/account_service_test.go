@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAccountServiceRecordPostViolation(t *testing.T) {
+	rateLimitDao := &fakeRateLimitDao{counters: make(map[string]int)}
+	s := &AccountService{rateLimitDao: rateLimitDao, violationWindow: 24 * time.Hour}
+	ctx := context.Background()
+
+	// 同一帖子的三张违规图片和一个违规视频只计一次违规
+	for i := 0; i < 3; i++ {
+		s.RecordPostViolation(ctx, 7, 100, "image")
+	}
+	s.RecordPostViolation(ctx, 7, 100, "video")
+	// 另一个帖子再计一次
+	s.RecordPostViolation(ctx, 7, 101, "image")
+
+	start, _ := windowBounds(time.Now(), s.violationWindow)
+	got, _ := rateLimitDao.Get(ctx, "violation:user:7", start.Unix())
+	if got != 2 {
+		t.Errorf("违规次数 = %d, want 2", got)
+	}
+
+	// 未开启违规统计时不记录
+	disabled := &AccountService{rateLimitDao: rateLimitDao}
+	disabled.RecordPostViolation(ctx, 8, 102, "image")
+	if _, ok := rateLimitDao.counters["violation:post:102@0"]; ok {
+		t.Error("未开启违规统计时不应记录")
+	}
+}
@@ -0,0 +1,419 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"wxcloudrun-golang/db/dao"
+)
+
+// AdminHandler 后台管理处理器
+type AdminHandler struct {
+	adminService   *AdminService
+	auditService   *AuditService
+	accountService *AccountService
+	policyService  *ModerationPolicyService
+	keywordFilter  *KeywordFilterService
+	contentChecks  *ContentCheckService
+}
+
+// NewAdminHandler 创建后台管理处理器实例
+func NewAdminHandler() *AdminHandler {
+	return &AdminHandler{
+		adminService:   NewAdminService(),
+		auditService:   NewAuditService(),
+		accountService: NewAccountService(),
+		policyService:  NewModerationPolicyService(),
+		keywordFilter:  NewKeywordFilterService(),
+		contentChecks:  NewContentCheckService(),
+	}
+}
+
+// writeAdminResponse 输出后台接口的成功响应
+func writeAdminResponse(w http.ResponseWriter, message string, data interface{}) {
+	response := map[string]interface{}{
+		"code":    200,
+		"message": message,
+		"data":    data,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// parsePageParams 解析分页参数
+func parsePageParams(r *http.Request) (int, int) {
+	page := 1
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p > 0 {
+		page = p
+	}
+	pageSize := 20
+	if ps, err := strconv.Atoi(r.URL.Query().Get("pageSize")); err == nil && ps > 0 {
+		pageSize = ps
+	}
+	return page, pageSize
+}
+
+// HandleUserRequests 处理后台用户管理请求
+// GET /api/admin/users              获取用户列表
+// PUT /api/admin/users/{id}/role    设置用户角色
+// PUT /api/admin/users/{id}/status  设置账号状态（禁言、停用、封禁、解除）
+func (h *AdminHandler) HandleUserRequests(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	if path == "/api/admin/users" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		page, pageSize := parsePageParams(r)
+		result, err := h.adminService.GetUsers(r.Context(), page, pageSize)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeAdminResponse(w, "success", result)
+		return
+	}
+
+	// /api/admin/users/{id}/role 或 /api/admin/users/{id}/status
+	pathParts := strings.Split(path, "/")
+	if len(pathParts) != 6 || (pathParts[5] != "role" && pathParts[5] != "status") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPut && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userId, err := strconv.ParseInt(pathParts[4], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if pathParts[5] == "status" {
+		var req SetUserStatusRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		user, err := h.accountService.SetStatus(r.Context(), GetUserFromContext(r), userId, &req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeAdminResponse(w, "设置成功", newAdminUserInfo(user))
+		return
+	}
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.adminService.SetUserRole(r.Context(), GetUserFromContext(r), userId, req.Role); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeAdminResponse(w, "设置成功", nil)
+}
+
+// HandlePostRequests 处理后台帖子管理请求
+// POST /api/admin/posts/{id}/takedown  下架帖子
+func (h *AdminHandler) HandlePostRequests(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	pathParts := strings.Split(strings.TrimSuffix(r.URL.Path, "/"), "/")
+	if len(pathParts) != 6 || pathParts[5] != "takedown" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	postId, err := strconv.ParseInt(pathParts[4], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := h.adminService.TakedownPost(r.Context(), GetUserFromContext(r), postId, req.Reason); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeAdminResponse(w, "下架成功", nil)
+}
+
+// HandleCategoryRequests 处理后台分类管理请求
+// GET    /api/admin/categories                 获取全部分类（含已停用）
+// POST   /api/admin/categories                 创建分类
+// POST   /api/admin/categories/reorder         调整分类顺序
+// PUT    /api/admin/categories/{code}          更新分类（名称、图标、描述、排序、启用状态）
+// DELETE /api/admin/categories/{code}?moveTo=  删除分类，有帖子时需指定移动到的分类
+// POST   /api/admin/categories/{code}/merge    合并到目标分类
+func (h *AdminHandler) HandleCategoryRequests(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	if path != "/api/admin/categories" {
+		h.handleCategoryItemRequests(w, r, strings.Split(strings.TrimPrefix(path, "/api/admin/categories/"), "/"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		categories, err := h.adminService.GetCategories(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeAdminResponse(w, "success", categories)
+	case http.MethodPost:
+		var req CreateCategoryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		category, err := h.adminService.CreateCategory(r.Context(), GetUserFromContext(r), &req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeAdminResponse(w, "创建成功", category)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleCategoryItemRequests 处理单个分类的管理请求
+func (h *AdminHandler) handleCategoryItemRequests(w http.ResponseWriter, r *http.Request, parts []string) {
+	userCtx := GetUserFromContext(r)
+	code := parts[0]
+
+	switch {
+	case len(parts) == 1 && code == "reorder":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Codes []string `json:"codes"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.adminService.ReorderCategories(r.Context(), userCtx, req.Codes); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeAdminResponse(w, "排序成功", nil)
+	case len(parts) == 1:
+		switch r.Method {
+		case http.MethodPut, http.MethodPatch:
+			var req UpdateCategoryRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			category, err := h.adminService.UpdateCategory(r.Context(), userCtx, code, &req)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeAdminResponse(w, "更新成功", category)
+		case http.MethodDelete:
+			if err := h.adminService.DeleteCategory(r.Context(), userCtx, code, r.URL.Query().Get("moveTo")); err != nil {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			writeAdminResponse(w, "删除成功", nil)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	case len(parts) == 2 && parts[1] == "merge":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Target string `json:"target"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.adminService.MergeCategory(r.Context(), userCtx, code, req.Target); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeAdminResponse(w, "合并成功", nil)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// GetAuditLogsHandler 获取审计日志处理器
+// GET /api/admin/audit-logs?operatorId=&action=&page=&pageSize=
+func (h *AdminHandler) GetAuditLogsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var operatorId int64
+	if id, err := strconv.ParseInt(r.URL.Query().Get("operatorId"), 10, 64); err == nil {
+		operatorId = id
+	}
+	page, pageSize := parsePageParams(r)
+
+	result, err := h.auditService.GetLogs(r.Context(), operatorId, r.URL.Query().Get("action"), page, pageSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeAdminResponse(w, "success", result)
+}
+
+// GetContentChecksHandler 查询内容安全检测记录处理器，用于申诉处理和检测策略调优
+// GET /api/admin/content-checks?userId=&postId=&targetType=&targetId=&kind=&suggest=&page=&pageSize=
+func (h *AdminHandler) GetContentChecksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := dao.ContentCheckFilter{
+		TargetType: query.Get("targetType"),
+		Kind:       query.Get("kind"),
+		Suggest:    query.Get("suggest"),
+	}
+	filter.UserId, _ = strconv.ParseInt(query.Get("userId"), 10, 64)
+	filter.PostId, _ = strconv.ParseInt(query.Get("postId"), 10, 64)
+	filter.TargetId, _ = strconv.ParseInt(query.Get("targetId"), 10, 64)
+	if filter.UserId <= 0 && filter.PostId <= 0 && filter.TargetId <= 0 {
+		http.Error(w, "userId, postId or targetId is required", http.StatusBadRequest)
+		return
+	}
+	page, pageSize := parsePageParams(r)
+
+	result, err := h.contentChecks.GetChecks(r.Context(), filter, page, pageSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeAdminResponse(w, "success", result)
+}
+
+// ModerationPolicyHandler 审核策略管理处理器，保存后本实例立即生效，其他实例在 MODERATION_POLICY_TTL 内生效
+// GET /api/admin/moderation-policy  获取当前生效的策略
+// PUT /api/admin/moderation-policy  保存新版本的策略，请求体 {"rules": [...], "note": "修改说明"}
+func (h *AdminHandler) ModerationPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		writeAdminResponse(w, "success", h.policyService.GetPolicy(r.Context()))
+	case http.MethodPut:
+		var req UpdatePolicyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		policy, err := h.policyService.UpdatePolicy(r.Context(), GetUserFromContext(r), &req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeAdminResponse(w, "更新成功", policy)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleKeywordRequests 关键词词库管理，修改后本实例立即生效，其他实例在 KEYWORD_CACHE_TTL 内生效
+// GET    /api/admin/keywords?word=&page=&pageSize=  查询词库
+// POST   /api/admin/keywords                        添加关键词
+// PUT    /api/admin/keywords/{id}                   更新关键词
+// DELETE /api/admin/keywords/{id}                   删除关键词
+func (h *AdminHandler) HandleKeywordRequests(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userCtx := GetUserFromContext(r)
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	if path == "/api/admin/keywords" {
+		switch r.Method {
+		case http.MethodGet:
+			page, pageSize := parsePageParams(r)
+			result, err := h.keywordFilter.GetKeywords(r.Context(), r.URL.Query().Get("word"), page, pageSize)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writeAdminResponse(w, "success", result)
+		case http.MethodPost:
+			var req KeywordRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			keyword, err := h.keywordFilter.CreateKeyword(r.Context(), userCtx, &req)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeAdminResponse(w, "添加成功", keyword)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(path, "/api/admin/keywords/"), 10, 64)
+	if err != nil || id <= 0 {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		var req KeywordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		keyword, err := h.keywordFilter.UpdateKeyword(r.Context(), userCtx, id, &req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeAdminResponse(w, "更新成功", keyword)
+	case http.MethodDelete:
+		if err := h.keywordFilter.DeleteKeyword(r.Context(), userCtx, id); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeAdminResponse(w, "删除成功", nil)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
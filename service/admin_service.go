@@ -0,0 +1,373 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"wxcloudrun-golang/db/dao"
+	"wxcloudrun-golang/db/model"
+	"wxcloudrun-golang/logger"
+)
+
+// AdminService 后台管理服务
+type AdminService struct {
+	userDao         dao.UserDao
+	postDao         dao.PostDao
+	categoryDao     dao.CategoryDao
+	notificationDao dao.NotificationDao
+	auditService    *AuditService
+}
+
+// NewAdminService 创建后台管理服务实例
+func NewAdminService() *AdminService {
+	return &AdminService{
+		userDao:         dao.NewUserDao(),
+		postDao:         dao.NewPostDao(),
+		categoryDao:     dao.NewCategoryDao(),
+		notificationDao: dao.NewNotificationDao(),
+		auditService:    NewAuditService(),
+	}
+}
+
+// AdminUserInfo 后台用户信息，openid只返回脱敏后的值
+type AdminUserInfo struct {
+	Id         int64     `json:"id"`
+	Username   string    `json:"username"`
+	Nickname   string    `json:"nickname"`
+	Avatar     string    `json:"avatar"`
+	Level      int       `json:"level"`
+	IsVerified bool      `json:"isVerified"`
+	Role       string    `json:"role"`
+	OpenId     string    `json:"openid"`
+	CreatedAt  time.Time `json:"createdAt"`
+
+	Status       string     `json:"status"` // 当前实际状态，限制到期后为active
+	StatusReason string     `json:"statusReason,omitempty"`
+	StatusUntil  *time.Time `json:"statusUntil,omitempty"`
+}
+
+// newAdminUserInfo 构造后台用户信息
+func newAdminUserInfo(user *model.UserModel) *AdminUserInfo {
+	role := user.Role
+	if role == "" {
+		role = model.RoleUser
+	}
+	info := &AdminUserInfo{
+		Id:         user.Id,
+		Username:   user.Username,
+		Nickname:   user.Nickname,
+		Avatar:     user.Avatar,
+		Level:      user.Level,
+		IsVerified: user.IsVerified,
+		Role:       role,
+		OpenId:     logger.MaskId(user.OpenId),
+		CreatedAt:  user.CreatedAt,
+		Status:     user.EffectiveStatus(time.Now()),
+	}
+	if info.Status != model.UserStatusActive {
+		info.StatusReason = user.StatusReason
+		info.StatusUntil = user.StatusUntil
+	}
+	return info
+}
+
+// AdminUserListResponse 后台用户列表响应
+type AdminUserListResponse struct {
+	List       []*AdminUserInfo `json:"list"`
+	Pagination Pagination       `json:"pagination"`
+}
+
+// CreateCategoryRequest 创建分类请求
+type CreateCategoryRequest struct {
+	Name        string `json:"name"`
+	Code        string `json:"code"`
+	Icon        string `json:"icon"`
+	Description string `json:"description"`
+	Sort        int    `json:"sort"`
+}
+
+// UpdateCategoryRequest 更新分类请求，未传的字段保持不变
+type UpdateCategoryRequest struct {
+	Name        *string `json:"name"`
+	Icon        *string `json:"icon"`
+	Description *string `json:"description"`
+	Sort        *int    `json:"sort"`
+	IsActive    *bool   `json:"isActive"`
+}
+
+// reservedCategoryCodes 保留的分类代码："all"为列表筛选用的全部分类，"reorder"与后台排序接口路径冲突
+var reservedCategoryCodes = map[string]bool{"all": true, "reorder": true}
+
+// GetUsers 分页获取用户列表
+func (s *AdminService) GetUsers(ctx context.Context, page, pageSize int) (*AdminUserListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	users, total, err := s.userDao.GetUsersByPage(ctx, page, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户列表失败: %v", err)
+	}
+
+	list := make([]*AdminUserInfo, 0, len(users))
+	for _, user := range users {
+		list = append(list, newAdminUserInfo(user))
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+	return &AdminUserListResponse{
+		List: list,
+		Pagination: Pagination{
+			Current:  page,
+			PageSize: pageSize,
+			Total:    total,
+			HasMore:  page < totalPages,
+		},
+	}, nil
+}
+
+// SetUserRole 设置用户角色
+func (s *AdminService) SetUserRole(ctx context.Context, operator *UserContext, userId int64, role string) error {
+	if _, ok := roleRanks[role]; !ok {
+		return fmt.Errorf("无效的角色: %s", role)
+	}
+	if operator.User.Id == userId {
+		return fmt.Errorf("不能修改自己的角色")
+	}
+
+	user, err := s.userDao.GetById(ctx, userId)
+	if err != nil {
+		return fmt.Errorf("用户不存在: %v", err)
+	}
+
+	if err := s.userDao.UpdateRole(ctx, userId, role); err != nil {
+		return fmt.Errorf("更新用户角色失败: %v", err)
+	}
+
+	s.auditService.Record(ctx, operator, model.AuditActionSetUserRole, "user", userId, map[string]string{
+		"from": user.Role,
+		"to":   role,
+	})
+	return nil
+}
+
+// TakedownPost 下架帖子，帖子转为已驳回状态并通知作者
+func (s *AdminService) TakedownPost(ctx context.Context, operator *UserContext, postId int64, reason string) error {
+	post, err := s.postDao.GetById(ctx, postId)
+	if err != nil {
+		return fmt.Errorf("帖子不存在: %v", err)
+	}
+	if reason == "" {
+		reason = "内容不符合社区规范"
+	}
+
+	if err := s.postDao.UpdateModerationStatus(ctx, postId, model.ModerationStatusRejected, reason); err != nil {
+		return fmt.Errorf("下架帖子失败: %v", err)
+	}
+
+	notification := &model.NotificationModel{
+		UserId:     post.AuthorId,
+		Type:       model.NotificationModerationRejected,
+		Title:      "你的帖子已被下架",
+		Content:    fmt.Sprintf("你发布的帖子「%s」已被管理员下架：%s", post.Title, reason),
+		TargetType: model.ModerationTargetPost,
+		TargetId:   postId,
+	}
+	if err := s.notificationDao.Create(ctx, notification); err != nil {
+		slog.ErrorContext(ctx, "发送下架通知失败", "post_id", postId, "user_id", post.AuthorId, "error", err)
+	}
+
+	s.auditService.Record(ctx, operator, model.AuditActionTakedownPost, model.ModerationTargetPost, postId, map[string]interface{}{
+		"reason":          reason,
+		"previous_status": post.ModerationStatus,
+	})
+	return nil
+}
+
+// GetCategories 获取全部分类（含已停用）
+func (s *AdminService) GetCategories(ctx context.Context) ([]*model.CategoryModel, error) {
+	categories, err := s.categoryDao.GetAllForAdmin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取分类列表失败: %v", err)
+	}
+	return categories, nil
+}
+
+// CreateCategory 创建分类
+func (s *AdminService) CreateCategory(ctx context.Context, operator *UserContext, req *CreateCategoryRequest) (*model.CategoryModel, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Code = strings.TrimSpace(req.Code)
+	if req.Name == "" || req.Code == "" {
+		return nil, fmt.Errorf("分类名称和代码不能为空")
+	}
+	if reservedCategoryCodes[req.Code] {
+		return nil, fmt.Errorf("分类代码 %s 为保留代码", req.Code)
+	}
+	if _, err := s.categoryDao.GetByCode(ctx, req.Code); err == nil {
+		return nil, fmt.Errorf("分类代码已存在: %s", req.Code)
+	}
+
+	category := &model.CategoryModel{
+		Name:        req.Name,
+		Code:        req.Code,
+		Icon:        req.Icon,
+		Description: req.Description,
+		Sort:        req.Sort,
+		IsActive:    true,
+	}
+	if err := s.categoryDao.Create(ctx, category); err != nil {
+		return nil, fmt.Errorf("创建分类失败: %v", err)
+	}
+	sharedCategoryCache.Invalidate()
+
+	s.auditService.Record(ctx, operator, model.AuditActionCreateCategory, "category", category.Id, req)
+	return category, nil
+}
+
+// UpdateCategory 更新分类信息，重命名时同步更新帖子中的分类名称
+func (s *AdminService) UpdateCategory(ctx context.Context, operator *UserContext, code string, req *UpdateCategoryRequest) (*model.CategoryModel, error) {
+	category, err := s.categoryDao.GetByCode(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("分类不存在: %v", err)
+	}
+	defer sharedCategoryCache.Invalidate()
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, fmt.Errorf("分类名称不能为空")
+		}
+		if name != category.Name {
+			if err := s.categoryDao.Rename(ctx, code, name); err != nil {
+				return nil, fmt.Errorf("重命名分类失败: %v", err)
+			}
+		}
+		category.Name = name
+	}
+	if req.Icon != nil {
+		category.Icon = *req.Icon
+	}
+	if req.Description != nil {
+		category.Description = *req.Description
+	}
+	if req.Sort != nil {
+		category.Sort = *req.Sort
+	}
+	if req.IsActive != nil {
+		category.IsActive = *req.IsActive
+	}
+
+	if err := s.categoryDao.Update(ctx, category); err != nil {
+		return nil, fmt.Errorf("更新分类失败: %v", err)
+	}
+
+	s.auditService.Record(ctx, operator, model.AuditActionUpdateCategory, "category", category.Id, map[string]interface{}{
+		"code":    code,
+		"changes": req,
+	})
+	return category, nil
+}
+
+// ReorderCategories 按给定的分类代码顺序重新排序
+func (s *AdminService) ReorderCategories(ctx context.Context, operator *UserContext, codes []string) error {
+	if len(codes) == 0 {
+		return fmt.Errorf("分类代码列表不能为空")
+	}
+	seen := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		if seen[code] {
+			return fmt.Errorf("分类代码重复: %s", code)
+		}
+		seen[code] = true
+	}
+
+	if err := s.categoryDao.UpdateSort(ctx, codes); err != nil {
+		return fmt.Errorf("更新分类排序失败: %v", err)
+	}
+	sharedCategoryCache.Invalidate()
+
+	s.auditService.Record(ctx, operator, model.AuditActionReorderCategories, "category", 0, map[string]interface{}{
+		"codes": codes,
+	})
+	return nil
+}
+
+// DeleteCategory 删除分类。分类下仍有帖子时必须指定moveTo，帖子会被移动到目标分类
+func (s *AdminService) DeleteCategory(ctx context.Context, operator *UserContext, code, moveTo string) error {
+	category, err := s.categoryDao.GetByCode(ctx, code)
+	if err != nil {
+		return fmt.Errorf("分类不存在: %v", err)
+	}
+	if category.Code == "all" {
+		return fmt.Errorf("不能删除全部分类")
+	}
+
+	if moveTo != "" {
+		return s.mergeCategory(ctx, operator, category, moveTo, model.AuditActionDeleteCategory)
+	}
+
+	count, err := s.postDao.CountByCategory(ctx, code)
+	if err != nil {
+		return fmt.Errorf("统计分类帖子数失败: %v", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("分类下还有%d篇帖子，请指定要移动到的分类", count)
+	}
+
+	// 分类下只剩已删除的帖子时直接删除分类
+	if err := s.categoryDao.Delete(ctx, strconv.FormatInt(category.Id, 10)); err != nil {
+		return fmt.Errorf("删除分类失败: %v", err)
+	}
+	sharedCategoryCache.Invalidate()
+
+	s.auditService.Record(ctx, operator, model.AuditActionDeleteCategory, "category", category.Id, map[string]interface{}{
+		"code": code,
+	})
+	return nil
+}
+
+// MergeCategory 将分类合并到目标分类：移动全部帖子后删除原分类
+func (s *AdminService) MergeCategory(ctx context.Context, operator *UserContext, code, target string) error {
+	category, err := s.categoryDao.GetByCode(ctx, code)
+	if err != nil {
+		return fmt.Errorf("分类不存在: %v", err)
+	}
+	if category.Code == "all" {
+		return fmt.Errorf("不能合并全部分类")
+	}
+	return s.mergeCategory(ctx, operator, category, target, model.AuditActionMergeCategory)
+}
+
+// mergeCategory 移动帖子到目标分类并删除原分类
+func (s *AdminService) mergeCategory(ctx context.Context, operator *UserContext, from *model.CategoryModel, target, action string) error {
+	if target == from.Code {
+		return fmt.Errorf("目标分类不能与原分类相同")
+	}
+	to, err := s.categoryDao.GetByCode(ctx, target)
+	if err != nil {
+		return fmt.Errorf("目标分类不存在: %v", err)
+	}
+	if to.Code == "all" {
+		return fmt.Errorf("不能将帖子移动到全部分类")
+	}
+
+	moved, err := s.categoryDao.MergeInto(ctx, from, to)
+	if err != nil {
+		return fmt.Errorf("移动分类帖子失败: %v", err)
+	}
+	sharedCategoryCache.Invalidate()
+
+	s.auditService.Record(ctx, operator, action, "category", from.Id, map[string]interface{}{
+		"code":        from.Code,
+		"target":      to.Code,
+		"moved_posts": moved,
+	})
+	return nil
+}
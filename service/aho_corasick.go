@@ -0,0 +1,82 @@
+package service
+
+// ahoCorasick 多模式字符串匹配自动机，按rune匹配，一次扫描找出文本中出现的全部模式
+type ahoCorasick struct {
+	nodes []acNode
+}
+
+// acNode 自动机节点
+type acNode struct {
+	next    map[rune]int // 子节点
+	fail    int          // 失配指针
+	outputs []int        // 以该节点结尾的模式序号（含经失配指针可达的模式）
+}
+
+// newAhoCorasick 根据模式列表构建自动机，空模式会被忽略
+func newAhoCorasick(patterns []string) *ahoCorasick {
+	ac := &ahoCorasick{nodes: []acNode{{next: map[rune]int{}}}}
+	for i, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		state := 0
+		for _, r := range pattern {
+			child, ok := ac.nodes[state].next[r]
+			if !ok {
+				ac.nodes = append(ac.nodes, acNode{next: map[rune]int{}})
+				child = len(ac.nodes) - 1
+				ac.nodes[state].next[r] = child
+			}
+			state = child
+		}
+		ac.nodes[state].outputs = append(ac.nodes[state].outputs, i)
+	}
+
+	// 按层次遍历计算失配指针，并合并失配节点的输出
+	queue := make([]int, 0, len(ac.nodes))
+	for _, child := range ac.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		for r, child := range ac.nodes[state].next {
+			fail := ac.nodes[state].fail
+			for fail > 0 {
+				if _, ok := ac.nodes[fail].next[r]; ok {
+					break
+				}
+				fail = ac.nodes[fail].fail
+			}
+			if target, ok := ac.nodes[fail].next[r]; ok && target != child {
+				ac.nodes[child].fail = target
+			}
+			ac.nodes[child].outputs = append(ac.nodes[child].outputs, ac.nodes[ac.nodes[child].fail].outputs...)
+			queue = append(queue, child)
+		}
+	}
+	return ac
+}
+
+// Match 返回文本中出现的模式序号（去重，按首次出现的顺序）
+func (ac *ahoCorasick) Match(text string) []int {
+	var matched []int
+	seen := make(map[int]bool)
+	state := 0
+	for _, r := range text {
+		for state > 0 {
+			if _, ok := ac.nodes[state].next[r]; ok {
+				break
+			}
+			state = ac.nodes[state].fail
+		}
+		state = ac.nodes[state].next[r] // 根节点没有该子节点时为0，回到根节点
+		for _, output := range ac.nodes[state].outputs {
+			if !seen[output] {
+				seen[output] = true
+				matched = append(matched, output)
+			}
+		}
+	}
+	return matched
+}
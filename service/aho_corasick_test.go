@@ -0,0 +1,67 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAhoCorasickMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		text     string
+		want     []int
+	}{
+		{
+			name:     "重叠的模式全部命中",
+			patterns: []string{"he", "she", "his", "hers"},
+			text:     "ushers",
+			want:     []int{1, 0, 3},
+		},
+		{
+			name:     "失配后经失配指针命中后缀模式",
+			patterns: []string{"abcx", "bcd"},
+			text:     "abcd",
+			want:     []int{1},
+		},
+		{
+			name:     "失配指针上的输出合并到长模式节点",
+			patterns: []string{"abc", "c"},
+			text:     "abc",
+			want:     []int{0, 1},
+		},
+		{
+			name:     "中文按rune匹配",
+			patterns: []string{"赌博", "博彩"},
+			text:     "网上赌博彩票",
+			want:     []int{0, 1},
+		},
+		{
+			name:     "重复出现只返回一次",
+			patterns: []string{"he"},
+			text:     "hehe",
+			want:     []int{0},
+		},
+		{
+			name:     "空模式被忽略",
+			patterns: []string{"", "a"},
+			text:     "a",
+			want:     []int{1},
+		},
+		{
+			name:     "未命中",
+			patterns: []string{"abc"},
+			text:     "ababab",
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newAhoCorasick(tt.patterns).Match(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
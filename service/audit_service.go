@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"wxcloudrun-golang/db/dao"
+	"wxcloudrun-golang/db/model"
+	"wxcloudrun-golang/logger"
+)
+
+// AuditService 后台操作审计服务
+type AuditService struct {
+	auditDao dao.AdminAuditDao
+}
+
+// NewAuditService 创建审计服务实例
+func NewAuditService() *AuditService {
+	return &AuditService{
+		auditDao: dao.NewAdminAuditDao(),
+	}
+}
+
+// AuditLogListResponse 审计日志列表响应
+type AuditLogListResponse struct {
+	List       []*model.AdminAuditLogModel `json:"list"`
+	Pagination Pagination                  `json:"pagination"`
+}
+
+// Record 记录一次后台操作，detail序列化为JSON保存；写入失败只记录日志，不影响操作本身
+func (s *AuditService) Record(ctx context.Context, operator *UserContext, action, targetType string, targetId int64, detail interface{}) {
+	if operator == nil || operator.User == nil {
+		return
+	}
+
+	log := &model.AdminAuditLogModel{
+		OperatorId: operator.User.Id,
+		Role:       operator.User.Role,
+		Action:     action,
+		TargetType: targetType,
+		TargetId:   targetId,
+		IP:         operator.IP,
+		RequestId:  logger.RequestIdFromContext(ctx),
+	}
+	if detail != nil {
+		if data, err := json.Marshal(detail); err == nil {
+			log.Detail = string(data)
+		}
+	}
+
+	if err := s.auditDao.Create(ctx, log); err != nil {
+		slog.ErrorContext(ctx, "记录审计日志失败", "action", action, "operator_id", operator.User.Id,
+			"target_type", targetType, "target_id", targetId, "error", err)
+		return
+	}
+	slog.InfoContext(ctx, "后台操作", "action", action, "operator_id", operator.User.Id,
+		"target_type", targetType, "target_id", targetId)
+}
+
+// GetLogs 分页获取审计日志
+func (s *AuditService) GetLogs(ctx context.Context, operatorId int64, action string, page, pageSize int) (*AuditLogListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	logs, total, err := s.auditDao.GetList(ctx, operatorId, action, page, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("获取审计日志失败: %v", err)
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+	return &AuditLogListResponse{
+		List: logs,
+		Pagination: Pagination{
+			Current:  page,
+			PageSize: pageSize,
+			Total:    total,
+			HasMore:  page < totalPages,
+		},
+	}, nil
+}
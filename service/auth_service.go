@@ -3,6 +3,7 @@ package service
 import (
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"math/rand"
 	"net/http"
 	"time"
@@ -12,13 +13,15 @@ import (
 
 // AuthService 认证服务
 type AuthService struct {
-	userDao dao.UserDao
+	userDao           dao.UserDao
+	profileModeration *ProfileModerationService
 }
 
 // NewAuthService 创建认证服务实例
 func NewAuthService() *AuthService {
 	return &AuthService{
-		userDao: dao.NewUserDao(),
+		userDao:           dao.NewUserDao(),
+		profileModeration: NewProfileModerationService(),
 	}
 }
 
@@ -50,7 +53,7 @@ func (s *AuthService) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 检查用户是否已存在
-	existingUser, err := s.userDao.GetUserByOpenId(openId)
+	existingUser, err := s.userDao.GetUserByOpenId(r.Context(), openId)
 	if err == nil && existingUser != nil {
 		// 用户已存在，返回用户信息
 		response := map[string]interface{}{
@@ -64,11 +67,7 @@ func (s *AuthService) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 解析请求体
-	var registerData struct {
-		Nickname string `json:"nickname"`
-		Avatar   string `json:"avatar"`
-		Bio      string `json:"bio"`
-	}
+	var registerData ProfileUpdate
 
 	if err := json.NewDecoder(r.Body).Decode(&registerData); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
@@ -78,12 +77,10 @@ func (s *AuthService) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	// 生成随机用户名
 	username := s.generateRandomUsername()
 
-	// 创建新用户
+	// 创建新用户，提交的资料在创建后经过内容安全检测再写入
 	user := &model.UserModel{
 		Username: username,
-		Nickname: registerData.Nickname,
-		Avatar:   registerData.Avatar,
-		Bio:      registerData.Bio,
+		Nickname: "用户" + username,
 		OpenId:   openId,
 		AppId:    appId,
 		UnionId:  unionId,
@@ -91,21 +88,23 @@ func (s *AuthService) RegisterUser(w http.ResponseWriter, r *http.Request) {
 		Password: "", // 微信小程序用户不需要密码
 	}
 
-	// 如果昵称为空，使用默认昵称
-	if user.Nickname == "" {
-		user.Nickname = "用户" + username
-	}
-
 	// 保存到数据库
-	if err := s.userDao.CreateUser(user); err != nil {
+	if err := s.userDao.CreateUser(r.Context(), user); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to create user: %v", err), http.StatusInternalServerError)
 		return
 	}
 
+	// 资料未通过检测时仍完成注册，使用默认昵称，由用户稍后修改
+	msg := "User registered successfully"
+	if err := s.profileModeration.UpdateProfile(r.Context(), user, openId, &registerData); err != nil {
+		slog.WarnContext(r.Context(), "注册资料未通过检测，使用默认资料", "user_id", user.Id, "error", err)
+		msg = err.Error()
+	}
+
 	// 返回创建成功的用户信息
 	response := map[string]interface{}{
 		"code": 0,
-		"msg":  "User registered successfully",
+		"msg":  msg,
 		"data": user,
 	}
 
@@ -131,7 +130,7 @@ func (s *AuthService) LoginUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 查询用户是否存在
-	user, err := s.userDao.GetUserByOpenId(openId)
+	user, err := s.userDao.GetUserByOpenId(r.Context(), openId)
 	if err != nil {
 		// 用户不存在
 		response := map[string]interface{}{
@@ -173,7 +172,7 @@ func (s *AuthService) CheckUserExists(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 查询用户是否存在
-	user, _ := s.userDao.GetUserByOpenId(openId)
+	user, _ := s.userDao.GetUserByOpenId(r.Context(), openId)
 
 	if user == nil {
 		http.Error(w, "user missing register first!", http.StatusUnauthorized)
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"wxcloudrun-golang/db/dao"
+)
+
+// ErrCallbackUnauthorized 回调来源校验失败
+var ErrCallbackUnauthorized = errors.New("回调来源校验失败")
+
+// CallbackVerifier 微信回调来源校验：
+//   - 云托管消息推送：请求经云托管网关转发，带有 X-WX-SOURCE 请求头，APPID请求头必须存在且与 WECHAT_APPID 一致；
+//     请求头可被直接访问服务的调用方伪造，仅适用于服务只能通过云托管网关访问的部署，需设置 WECHAT_CALLBACK_CLOUDRUN=true 开启
+//   - 消息推送签名：配置 WECHAT_CALLBACK_TOKEN 后校验URL中的 signature/timestamp/nonce，
+//     同时配置 WECHAT_CALLBACK_AES_KEY（EncodingAESKey）时支持安全模式（encrypt_type=aes）的加密消息
+//
+// 签名模式下timestamp超出 WECHAT_CALLBACK_MAX_SKEW（默认5m）或nonce重复的请求视为重放。POST回调的nonce在处理成功后
+// 才通过 ConsumeNonce 记录，处理失败时微信重试的同一推送仍可通过校验
+type CallbackVerifier struct {
+	appId         string
+	token         string
+	aesKey        []byte
+	trustCloudRun bool
+	maxSkew       time.Duration
+	nonceDao      dao.RateLimitDao
+}
+
+// NewCallbackVerifier 根据环境变量创建回调来源校验器，WECHAT_CALLBACK_AES_KEY 无效时返回错误
+func NewCallbackVerifier() (*CallbackVerifier, error) {
+	v := &CallbackVerifier{
+		appId:         os.Getenv("WECHAT_APPID"),
+		token:         os.Getenv("WECHAT_CALLBACK_TOKEN"),
+		trustCloudRun: os.Getenv("WECHAT_CALLBACK_CLOUDRUN") == "true",
+		maxSkew:       envDuration("WECHAT_CALLBACK_MAX_SKEW", 5*time.Minute),
+		nonceDao:      dao.NewRateLimitDao(),
+	}
+	if encodingKey := os.Getenv("WECHAT_CALLBACK_AES_KEY"); encodingKey != "" {
+		key, err := base64.StdEncoding.DecodeString(encodingKey + "=")
+		if err != nil || len(key) != 32 {
+			return nil, errors.New("WECHAT_CALLBACK_AES_KEY 无效，应为43位EncodingAESKey")
+		}
+		v.aesKey = key
+	}
+	if v.trustCloudRun && v.appId == "" {
+		slog.Warn("未配置WECHAT_APPID，云托管请求头方式无法通过校验")
+	}
+	return v, nil
+}
+
+// AppId 配置的小程序AppID，为空时不校验回调中的appid
+func (v *CallbackVerifier) AppId() string {
+	return v.appId
+}
+
+// FreshCreateTime 判断回调消息的推送时间（CreateTime）是否在允许偏差内，未配置偏差时不校验
+func (v *CallbackVerifier) FreshCreateTime(createTime int64) bool {
+	if v.maxSkew <= 0 {
+		return true
+	}
+	skew := time.Since(time.Unix(createTime, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	return skew <= v.maxSkew
+}
+
+// VerifyEcho 校验服务器配置时的GET请求，通过时返回需要原样输出的echostr
+func (v *CallbackVerifier) VerifyEcho(ctx context.Context, r *http.Request) (string, error) {
+	query := r.URL.Query()
+	echo := query.Get("echostr")
+	if v.token == "" || echo == "" {
+		return "", ErrCallbackUnauthorized
+	}
+	timestamp, nonce := query.Get("timestamp"), query.Get("nonce")
+	if err := v.checkSignature(query.Get("signature"), v.token, timestamp, nonce); err != nil {
+		return "", err
+	}
+	if err := v.checkReplay(ctx, timestamp, nonce); err != nil {
+		return "", err
+	}
+	if err := v.consume(ctx, timestamp, nonce); err != nil {
+		return "", err
+	}
+	return echo, nil
+}
+
+// Verify 校验POST回调的来源，返回明文消息体（安全模式下为解密后的内容）。签名请求只检查nonce是否已使用，
+// 处理成功后需调用 ConsumeNonce 记录
+func (v *CallbackVerifier) Verify(ctx context.Context, r *http.Request, body []byte) ([]byte, error) {
+	// 带签名参数的请求按消息推送签名校验，签名不通过时不再回退到云托管请求头
+	if v.signed(r) {
+		return v.verifySigned(ctx, r, body)
+	}
+
+	if v.trustCloudRun && r.Header.Get("X-WX-SOURCE") != "" {
+		appId := r.Header.Get("X-WX-APPID")
+		if appId == "" {
+			appId = r.Header.Get("X-WX-FROM-APPID")
+		}
+		if v.appId != "" && appId == v.appId {
+			return body, nil
+		}
+	}
+
+	// 云托管请求头不可信或appid不匹配时，只能通过签名校验
+	return nil, fmt.Errorf("%w: 缺少有效的云托管请求头或签名", ErrCallbackUnauthorized)
+}
+
+// ConsumeNonce 回调处理成功后记录签名请求的nonce，之后相同的请求视为重放；非签名请求不记录
+func (v *CallbackVerifier) ConsumeNonce(ctx context.Context, r *http.Request) error {
+	if !v.signed(r) {
+		return nil
+	}
+	query := r.URL.Query()
+	return v.consume(ctx, query.Get("timestamp"), query.Get("nonce"))
+}
+
+// signed 请求是否带有消息推送签名参数
+func (v *CallbackVerifier) signed(r *http.Request) bool {
+	query := r.URL.Query()
+	return (v.token != "" && query.Get("signature") != "") || query.Get("msg_signature") != ""
+}
+
+// verifySigned 校验消息推送签名，安全模式下解密消息体
+func (v *CallbackVerifier) verifySigned(ctx context.Context, r *http.Request, body []byte) ([]byte, error) {
+	if v.token == "" {
+		return nil, fmt.Errorf("%w: 未配置WECHAT_CALLBACK_TOKEN", ErrCallbackUnauthorized)
+	}
+	query := r.URL.Query()
+	timestamp, nonce := query.Get("timestamp"), query.Get("nonce")
+
+	if query.Get("encrypt_type") != "aes" {
+		if err := v.checkSignature(query.Get("signature"), v.token, timestamp, nonce); err != nil {
+			return nil, err
+		}
+		if err := v.checkReplay(ctx, timestamp, nonce); err != nil {
+			return nil, err
+		}
+		return body, nil
+	}
+
+	if v.aesKey == nil {
+		return nil, fmt.Errorf("%w: 未配置WECHAT_CALLBACK_AES_KEY", ErrCallbackUnauthorized)
+	}
+	var envelope struct {
+		Encrypt string `json:"Encrypt"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.Encrypt == "" {
+		return nil, fmt.Errorf("%w: 加密消息格式错误", ErrCallbackUnauthorized)
+	}
+	if err := v.checkSignature(query.Get("msg_signature"), v.token, timestamp, nonce, envelope.Encrypt); err != nil {
+		return nil, err
+	}
+	if err := v.checkReplay(ctx, timestamp, nonce); err != nil {
+		return nil, err
+	}
+	return v.decrypt(envelope.Encrypt)
+}
+
+// checkSignature 校验sha1签名：参数按字典序排序后拼接
+func (v *CallbackVerifier) checkSignature(signature string, parts ...string) error {
+	if signature == "" {
+		return fmt.Errorf("%w: 缺少签名", ErrCallbackUnauthorized)
+	}
+	sorted := append([]string(nil), parts...)
+	sort.Strings(sorted)
+	sum := sha1.Sum([]byte(strings.Join(sorted, "")))
+	expected := hex.EncodeToString(sum[:])
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(signature))) != 1 {
+		return fmt.Errorf("%w: 签名不匹配", ErrCallbackUnauthorized)
+	}
+	return nil
+}
+
+// checkReplay 拒绝时间戳超出允许偏差或nonce已使用过的请求，只检查不记录nonce
+func (v *CallbackVerifier) checkReplay(ctx context.Context, timestamp, nonce string) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: 时间戳无效", ErrCallbackUnauthorized)
+	}
+	if !v.FreshCreateTime(ts) {
+		return fmt.Errorf("%w: 时间戳超出允许范围", ErrCallbackUnauthorized)
+	}
+	if nonce == "" {
+		return fmt.Errorf("%w: 缺少nonce", ErrCallbackUnauthorized)
+	}
+
+	key, start, _ := v.nonceBucket(ts, timestamp, nonce)
+	count, err := v.nonceDao.Get(ctx, key, start.Unix())
+	if err != nil {
+		return fmt.Errorf("查询回调nonce失败: %v", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: 重复的请求", ErrCallbackUnauthorized)
+	}
+	return nil
+}
+
+// consume 记录nonce已使用，并发的相同请求中只有一个能记录成功
+func (v *CallbackVerifier) consume(ctx context.Context, timestamp, nonce string) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: 时间戳无效", ErrCallbackUnauthorized)
+	}
+	key, start, expiresAt := v.nonceBucket(ts, timestamp, nonce)
+	count, err := v.nonceDao.Increment(ctx, key, start.Unix(), expiresAt)
+	if err != nil {
+		return fmt.Errorf("记录回调nonce失败: %v", err)
+	}
+	if count > 1 {
+		return fmt.Errorf("%w: 重复的请求", ErrCallbackUnauthorized)
+	}
+	return nil
+}
+
+// nonceBucket nonce在时间戳允许范围内只能使用一次，计数存储在限流计数表中以便多实例共享
+func (v *CallbackVerifier) nonceBucket(ts int64, timestamp, nonce string) (string, time.Time, time.Time) {
+	window := 2 * v.maxSkew
+	if window <= 0 {
+		window = 10 * time.Minute
+	}
+	start, _ := windowBounds(time.Unix(ts, 0), window)
+	return "callback_nonce:" + timestamp + ":" + nonce, start, start.Add(2 * window)
+}
+
+// decrypt 解密安全模式消息：AES-256-CBC，IV为密钥前16字节，明文为 16字节随机串 + 4字节消息长度 + 消息 + AppID
+func (v *CallbackVerifier) decrypt(encrypted string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 密文格式错误", ErrCallbackUnauthorized)
+	}
+	block, err := aes.NewCipher(v.aesKey)
+	if err != nil {
+		return nil, fmt.Errorf("初始化解密失败: %v", err)
+	}
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: 密文长度错误", ErrCallbackUnauthorized)
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, v.aesKey[:aes.BlockSize]).CryptBlocks(plain, data)
+
+	// 去除PKCS#7填充（块大小32）
+	pad := int(plain[len(plain)-1])
+	if pad < 1 || pad > 32 || pad > len(plain) {
+		return nil, fmt.Errorf("%w: 填充错误", ErrCallbackUnauthorized)
+	}
+	for _, b := range plain[len(plain)-pad:] {
+		if int(b) != pad {
+			return nil, fmt.Errorf("%w: 填充错误", ErrCallbackUnauthorized)
+		}
+	}
+	plain = plain[:len(plain)-pad]
+	if len(plain) < 20 {
+		return nil, fmt.Errorf("%w: 明文长度错误", ErrCallbackUnauthorized)
+	}
+
+	msgLen := int(binary.BigEndian.Uint32(plain[16:20]))
+	if msgLen < 0 || 20+msgLen > len(plain) {
+		return nil, fmt.Errorf("%w: 消息长度错误", ErrCallbackUnauthorized)
+	}
+	msg := plain[20 : 20+msgLen]
+	appId := plain[20+msgLen:]
+	if v.appId != "" && !bytes.Equal(appId, []byte(v.appId)) {
+		return nil, fmt.Errorf("%w: 加密消息appid不匹配", ErrCallbackUnauthorized)
+	}
+	return msg, nil
+}
@@ -0,0 +1,322 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// 微信消息推送文档中的示例配置
+const (
+	testCallbackToken     = "pamtest"
+	testCallbackAESKey    = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+	testCallbackAppId     = "wxb11529c136998cb6"
+	testCallbackTimestamp = "1409304348"
+	testCallbackNonce     = "xxxxxx"
+	// sha1(sort(token, timestamp, nonce))
+	testCallbackSignature = "76480565cbe296026c53aaacd1ad523a1ddba24f"
+	// 以示例配置加密的消息 {"Event":"wxa_media_check","trace_id":"trace-1"}，随机串为 0123456789abcdef
+	testCallbackEncrypt      = "Q3stYC6hdFzMh9T8HCvyDCc/bZMP9Ewplq8gJeRgQEy4BuQ/xGD/+qIErbxctH/ge17LjCp3w+S5fffzC9QfBmuYJgPQbO0usV4a86dpL01kGG0ml4VHjC4/SYbi4iap"
+	testCallbackMsgSignature = "9288692605e5a82eee3d6b6be25a2ea5aab6c755"
+	testCallbackMessage      = `{"Event":"wxa_media_check","trace_id":"trace-1"}`
+)
+
+// fakeRateLimitDao 内存中的限流计数，按 键@窗口开始时间 计数
+type fakeRateLimitDao struct {
+	counters map[string]int
+	err      error
+}
+
+func (d *fakeRateLimitDao) Increment(_ context.Context, bucketKey string, windowStart int64, _ time.Time) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	key := bucketKey + "@" + strconv.FormatInt(windowStart, 10)
+	d.counters[key]++
+	return d.counters[key], nil
+}
+
+func (d *fakeRateLimitDao) Get(_ context.Context, bucketKey string, windowStart int64) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.counters[bucketKey+"@"+strconv.FormatInt(windowStart, 10)], nil
+}
+
+func (d *fakeRateLimitDao) DeleteExpired(context.Context, time.Time) error {
+	return nil
+}
+
+// newTestCallbackVerifier 使用示例配置创建校验器，maxSkew为0时不校验时间戳
+func newTestCallbackVerifier(t *testing.T, maxSkew time.Duration) *CallbackVerifier {
+	t.Helper()
+	t.Setenv("WECHAT_APPID", testCallbackAppId)
+	t.Setenv("WECHAT_CALLBACK_TOKEN", testCallbackToken)
+	t.Setenv("WECHAT_CALLBACK_AES_KEY", testCallbackAESKey)
+	v, err := NewCallbackVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.maxSkew = maxSkew
+	v.nonceDao = &fakeRateLimitDao{counters: make(map[string]int)}
+	return v
+}
+
+// encryptCallbackPlain 以示例密钥加密已填充的明文
+func encryptCallbackPlain(t *testing.T, plain []byte) string {
+	t.Helper()
+	key, _ := base64.StdEncoding.DecodeString(testCallbackAESKey + "=")
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(block, key[:aes.BlockSize]).CryptBlocks(out, plain)
+	return base64.StdEncoding.EncodeToString(out)
+}
+
+// callbackPlain 构造 16字节随机串 + 4字节消息长度 + 消息 + AppID，msgLen为负数时使用消息实际长度
+func callbackPlain(msg, appId string, msgLen int) []byte {
+	if msgLen < 0 {
+		msgLen = len(msg)
+	}
+	plain := []byte("0123456789abcdef")
+	plain = binary.BigEndian.AppendUint32(plain, uint32(msgLen))
+	plain = append(plain, msg...)
+	return append(plain, appId...)
+}
+
+// pkcs7Pad 按32字节块填充
+func pkcs7Pad(plain []byte) []byte {
+	pad := 32 - len(plain)%32
+	return append(plain, bytes.Repeat([]byte{byte(pad)}, pad)...)
+}
+
+func TestNewCallbackVerifierInvalidAESKey(t *testing.T) {
+	for _, key := range []string{"short", testCallbackAESKey + "A", "!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!"} {
+		t.Setenv("WECHAT_CALLBACK_AES_KEY", key)
+		if _, err := NewCallbackVerifier(); err == nil {
+			t.Errorf("NewCallbackVerifier(%q) 应返回错误", key)
+		}
+	}
+}
+
+func TestCallbackVerifierCheckSignature(t *testing.T) {
+	v := newTestCallbackVerifier(t, 0)
+	tests := []struct {
+		name      string
+		signature string
+		parts     []string
+		wantErr   bool
+	}{
+		{"明文模式示例签名", testCallbackSignature, []string{testCallbackToken, testCallbackTimestamp, testCallbackNonce}, false},
+		{"参数顺序不影响签名", testCallbackSignature, []string{testCallbackNonce, testCallbackToken, testCallbackTimestamp}, false},
+		{"签名大小写不敏感", "76480565CBE296026C53AAACD1AD523A1DDBA24F", []string{testCallbackToken, testCallbackTimestamp, testCallbackNonce}, false},
+		{"安全模式示例签名", testCallbackMsgSignature, []string{testCallbackToken, testCallbackTimestamp, testCallbackNonce, testCallbackEncrypt}, false},
+		{"缺少签名", "", []string{testCallbackToken, testCallbackTimestamp, testCallbackNonce}, true},
+		{"nonce被篡改", testCallbackSignature, []string{testCallbackToken, testCallbackTimestamp, "yyyyyy"}, true},
+		{"token错误", testCallbackSignature, []string{"other", testCallbackTimestamp, testCallbackNonce}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.checkSignature(tt.signature, tt.parts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkSignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrCallbackUnauthorized) {
+				t.Errorf("error = %v, want ErrCallbackUnauthorized", err)
+			}
+		})
+	}
+}
+
+func TestCallbackVerifierDecrypt(t *testing.T) {
+	msg := testCallbackMessage
+	badPadding := pkcs7Pad(callbackPlain(msg, testCallbackAppId, -1))
+	badPadding[len(badPadding)-2] ^= 0x01
+
+	tests := []struct {
+		name      string
+		appId     string
+		encrypted string
+		want      string
+		wantErr   bool
+	}{
+		{"示例密文", testCallbackAppId, testCallbackEncrypt, msg, false},
+		{"未配置appid时不校验", "", testCallbackEncrypt, msg, false},
+		{"appid不匹配", "wx0000000000000000", testCallbackEncrypt, "", true},
+		{"appid被截断", testCallbackAppId, encryptCallbackPlain(t, pkcs7Pad(callbackPlain(msg, testCallbackAppId[:10], -1))), "", true},
+		{"消息长度超出明文", testCallbackAppId, encryptCallbackPlain(t, pkcs7Pad(callbackPlain(msg, testCallbackAppId, 1000))), "", true},
+		{"消息长度偏短时appid不匹配", testCallbackAppId, encryptCallbackPlain(t, pkcs7Pad(callbackPlain(msg, testCallbackAppId, len(msg)-1))), "", true},
+		{"填充字节不一致", testCallbackAppId, encryptCallbackPlain(t, badPadding), "", true},
+		{"填充长度为0", testCallbackAppId, encryptCallbackPlain(t, append(callbackPlain(msg, testCallbackAppId, -1)[:32], bytes.Repeat([]byte{0}, 32)...)), "", true},
+		{"密文不是块大小的整数倍", testCallbackAppId, base64.StdEncoding.EncodeToString([]byte("short")), "", true},
+		{"密文不是base64", testCallbackAppId, "%%%", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestCallbackVerifier(t, 0)
+			v.appId = tt.appId
+			got, err := v.decrypt(tt.encrypted)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decrypt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("decrypt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCallbackVerifierCheckReplay(t *testing.T) {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+	future := strconv.FormatInt(time.Now().Add(10*time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		timestamp string
+		nonce     string
+		consumed  bool
+		dbErr     error
+		wantErr   error
+	}{
+		{"新的请求", now, "n1", false, nil, nil},
+		{"时间戳过旧", stale, "n1", false, nil, ErrCallbackUnauthorized},
+		{"时间戳超前", future, "n1", false, nil, ErrCallbackUnauthorized},
+		{"时间戳无效", "abc", "n1", false, nil, ErrCallbackUnauthorized},
+		{"缺少nonce", now, "", false, nil, ErrCallbackUnauthorized},
+		{"nonce已使用", now, "n1", true, nil, ErrCallbackUnauthorized},
+		{"计数存储异常", now, "n1", false, errors.New("db down"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestCallbackVerifier(t, 5*time.Minute)
+			nonceDao := v.nonceDao.(*fakeRateLimitDao)
+			if tt.consumed {
+				if err := v.consume(context.Background(), tt.timestamp, tt.nonce); err != nil {
+					t.Fatal(err)
+				}
+			}
+			nonceDao.err = tt.dbErr
+
+			err := v.checkReplay(context.Background(), tt.timestamp, tt.nonce)
+			switch {
+			case tt.dbErr != nil:
+				if err == nil || errors.Is(err, ErrCallbackUnauthorized) {
+					t.Errorf("checkReplay() error = %v, want 非校验失败的错误", err)
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("checkReplay() error = %v, want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Errorf("checkReplay() error = %v", err)
+			}
+			if tt.dbErr == nil && !tt.consumed && len(nonceDao.counters) != 0 {
+				t.Error("checkReplay 不应记录nonce")
+			}
+		})
+	}
+}
+
+func TestCallbackVerifierVerifyConsumesNonceAfterProcessing(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		want   string
+	}{
+		{
+			name:   "明文模式",
+			target: "/api/wechat/callback?signature=" + testCallbackSignature + "&timestamp=" + testCallbackTimestamp + "&nonce=" + testCallbackNonce,
+			body:   testCallbackMessage,
+			want:   testCallbackMessage,
+		},
+		{
+			name: "安全模式",
+			target: "/api/wechat/callback?encrypt_type=aes&msg_signature=" + testCallbackMsgSignature +
+				"&timestamp=" + testCallbackTimestamp + "&nonce=" + testCallbackNonce,
+			body: `{"Encrypt":"` + testCallbackEncrypt + `"}`,
+			want: testCallbackMessage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestCallbackVerifier(t, 0)
+			verify := func() ([]byte, error) {
+				r := httptest.NewRequest("POST", tt.target, bytes.NewBufferString(tt.body))
+				return v.Verify(ctx, r, []byte(tt.body))
+			}
+
+			got, err := verify()
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Verify() = %q, want %q", got, tt.want)
+			}
+
+			// 处理失败未记录nonce时，微信重试的同一推送仍可通过校验
+			if _, err := verify(); err != nil {
+				t.Fatalf("未记录nonce时重试 Verify() error = %v", err)
+			}
+
+			r := httptest.NewRequest("POST", tt.target, nil)
+			if err := v.ConsumeNonce(ctx, r); err != nil {
+				t.Fatalf("ConsumeNonce() error = %v", err)
+			}
+			if _, err := verify(); !errors.Is(err, ErrCallbackUnauthorized) {
+				t.Errorf("处理成功后重放 Verify() error = %v, want ErrCallbackUnauthorized", err)
+			}
+			if err := v.ConsumeNonce(ctx, r); !errors.Is(err, ErrCallbackUnauthorized) {
+				t.Errorf("重复记录 ConsumeNonce() error = %v, want ErrCallbackUnauthorized", err)
+			}
+		})
+	}
+}
+
+func TestCallbackVerifierVerifySource(t *testing.T) {
+	signed := "/api/wechat/callback?signature=" + testCallbackSignature + "&timestamp=" + testCallbackTimestamp + "&nonce=" + testCallbackNonce
+	tests := []struct {
+		name          string
+		trustCloudRun bool
+		target        string
+		headers       map[string]string
+		wantErr       bool
+	}{
+		{"默认不信任云托管请求头", false, "/api/wechat/callback", map[string]string{"X-WX-SOURCE": "1", "X-WX-APPID": testCallbackAppId}, true},
+		{"信任云托管请求头且appid一致", true, "/api/wechat/callback", map[string]string{"X-WX-SOURCE": "1", "X-WX-APPID": testCallbackAppId}, false},
+		{"云托管请求头缺少appid", true, "/api/wechat/callback", map[string]string{"X-WX-SOURCE": "1"}, true},
+		{"云托管请求头appid不一致", true, "/api/wechat/callback", map[string]string{"X-WX-SOURCE": "1", "X-WX-APPID": "wx0000000000000000"}, true},
+		{"签名错误时不回退到云托管请求头", true, "/api/wechat/callback?signature=bad&timestamp=1&nonce=x", map[string]string{"X-WX-SOURCE": "1", "X-WX-APPID": testCallbackAppId}, true},
+		{"签名正确", false, signed, nil, false},
+		{"没有请求头和签名", true, "/api/wechat/callback", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestCallbackVerifier(t, 0)
+			v.trustCloudRun = tt.trustCloudRun
+			r := httptest.NewRequest("POST", tt.target, nil)
+			for k, val := range tt.headers {
+				r.Header.Set(k, val)
+			}
+			_, err := v.Verify(context.Background(), r, []byte("{}"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrCallbackUnauthorized) {
+				t.Errorf("error = %v, want ErrCallbackUnauthorized", err)
+			}
+		})
+	}
+}
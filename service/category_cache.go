@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+	"wxcloudrun-golang/db/dao"
+	"wxcloudrun-golang/db/model"
+)
+
+// defaultCategoryCacheTTL 分类缓存默认有效期
+const defaultCategoryCacheTTL = time.Minute
+
+// categoryCache 分类内存缓存。后台修改分类后主动失效；多实例部署时其他实例依赖有效期（CATEGORY_CACHE_TTL）刷新
+type categoryCache struct {
+	mu       sync.RWMutex
+	ttl      time.Duration
+	loadedAt time.Time
+	active   []*model.CategoryModel          // 启用的分类，按排序值、帖子数排序
+	publish  []*model.CategoryModel          // 可用于发布的分类
+	byCode   map[string]*model.CategoryModel // 全部分类（含已停用）
+}
+
+// sharedCategoryCache 进程内共享的分类缓存，所有服务实例共用以便统一失效
+var sharedCategoryCache = newCategoryCache()
+
+// newCategoryCache 创建分类缓存
+func newCategoryCache() *categoryCache {
+	ttl := defaultCategoryCacheTTL
+	if d, err := time.ParseDuration(os.Getenv("CATEGORY_CACHE_TTL")); err == nil && d >= 0 {
+		ttl = d
+	}
+	return &categoryCache{ttl: ttl}
+}
+
+// Invalidate 使缓存失效，下次读取时重新加载
+func (c *categoryCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadedAt = time.Time{}
+}
+
+// Active 获取启用的分类
+func (c *categoryCache) Active(ctx context.Context, categoryDao dao.CategoryDao) ([]*model.CategoryModel, error) {
+	if err := c.ensureLoaded(ctx, categoryDao); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.active, nil
+}
+
+// Publish 获取可用于发布的分类
+func (c *categoryCache) Publish(ctx context.Context, categoryDao dao.CategoryDao) ([]*model.CategoryModel, error) {
+	if err := c.ensureLoaded(ctx, categoryDao); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.publish, nil
+}
+
+// GetActiveByCode 根据代码获取启用的分类
+func (c *categoryCache) GetActiveByCode(ctx context.Context, categoryDao dao.CategoryDao, code string) (*model.CategoryModel, error) {
+	if err := c.ensureLoaded(ctx, categoryDao); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	category, ok := c.byCode[code]
+	if !ok {
+		return nil, fmt.Errorf("分类 %s 不存在", code)
+	}
+	if !category.IsActive {
+		return nil, fmt.Errorf("分类 %s 已停用", code)
+	}
+	return category, nil
+}
+
+// ensureLoaded 缓存为空或已过期时从数据库加载
+func (c *categoryCache) ensureLoaded(ctx context.Context, categoryDao dao.CategoryDao) error {
+	c.mu.RLock()
+	fresh := !c.loadedAt.IsZero() && time.Since(c.loadedAt) < c.ttl
+	c.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	all, err := categoryDao.GetAllForAdmin(ctx)
+	if err != nil {
+		return fmt.Errorf("加载分类失败: %v", err)
+	}
+
+	byCode := make(map[string]*model.CategoryModel, len(all))
+	active := make([]*model.CategoryModel, 0, len(all))
+	publish := make([]*model.CategoryModel, 0, len(all))
+	for _, category := range all {
+		byCode[category.Code] = category
+		if !category.IsActive {
+			continue
+		}
+		active = append(active, category)
+		if category.Code != "all" {
+			publish = append(publish, category)
+		}
+	}
+	// 与CategoryDao.GetAll保持一致：排序值升序，帖子数降序
+	sort.SliceStable(active, func(i, j int) bool {
+		if active[i].Sort != active[j].Sort {
+			return active[i].Sort < active[j].Sort
+		}
+		return active[i].PostCount > active[j].PostCount
+	})
+
+	c.mu.Lock()
+	c.active = active
+	c.publish = publish
+	c.byCode = byCode
+	c.loadedAt = time.Now()
+	c.mu.Unlock()
+	return nil
+}
@@ -23,7 +23,7 @@ func (h *CategoryHandler) GetCategoriesHandler(w http.ResponseWriter, r *http.Re
 	w.Header().Set("Content-Type", "application/json")
 
 	// 调用服务
-	result, err := h.categoryService.GetCategories()
+	result, err := h.categoryService.GetCategories(r.Context())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -45,7 +45,7 @@ func (h *CategoryHandler) GetPublishCategoriesHandler(w http.ResponseWriter, r *
 	w.Header().Set("Content-Type", "application/json")
 
 	// 调用服务
-	result, err := h.categoryService.GetPublishCategories()
+	result, err := h.categoryService.GetPublishCategories(r.Context())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -74,7 +74,7 @@ func (h *CategoryHandler) GetHotTopicsHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	// 调用服务
-	result, err := h.categoryService.GetHotTopics(userId)
+	result, err := h.categoryService.GetHotTopics(r.Context(), userId)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"wxcloudrun-golang/db/dao"
 )
 
@@ -38,8 +39,8 @@ type TopicInfo struct {
 }
 
 // GetCategories 获取所有分类
-func (s *CategoryService) GetCategories() ([]*CategoryInfo, error) {
-	categories, err := s.categoryDao.GetAll()
+func (s *CategoryService) GetCategories(ctx context.Context) ([]*CategoryInfo, error) {
+	categories, err := sharedCategoryCache.Active(ctx, s.categoryDao)
 	if err != nil {
 		return nil, err
 	}
@@ -61,8 +62,8 @@ func (s *CategoryService) GetCategories() ([]*CategoryInfo, error) {
 }
 
 // GetPublishCategories 获取可用于发布的分类
-func (s *CategoryService) GetPublishCategories() ([]*CategoryInfo, error) {
-	categories, err := s.categoryDao.GetForPublish()
+func (s *CategoryService) GetPublishCategories(ctx context.Context) ([]*CategoryInfo, error) {
+	categories, err := sharedCategoryCache.Publish(ctx, s.categoryDao)
 	if err != nil {
 		return nil, err
 	}
@@ -84,8 +85,8 @@ func (s *CategoryService) GetPublishCategories() ([]*CategoryInfo, error) {
 }
 
 // GetHotTopics 获取热门话题
-func (s *CategoryService) GetHotTopics(userId int64) ([]*TopicInfo, error) {
-	categories, err := s.categoryDao.GetAll()
+func (s *CategoryService) GetHotTopics(ctx context.Context, userId int64) ([]*TopicInfo, error) {
+	categories, err := sharedCategoryCache.Active(ctx, s.categoryDao)
 	if err != nil {
 		return nil, err
 	}
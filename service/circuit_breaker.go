@@ -0,0 +1,218 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+	"wxcloudrun-golang/metrics"
+)
+
+// ErrCircuitOpen 微信接口连续失败已熔断，冷却期内直接失败不再请求
+var ErrCircuitOpen = errors.New("微信接口熔断中，暂不可用")
+
+// 熔断器状态
+const (
+	circuitClosed   = 0 // 正常请求
+	circuitOpen     = 1 // 熔断，直接失败
+	circuitHalfOpen = 2 // 冷却期结束，放行一个探测请求
+)
+
+// CircuitBreaker 微信接口熔断器：连续 WECHAT_BREAKER_THRESHOLD（默认5）次临时性失败后熔断，
+// 熔断 WECHAT_BREAKER_COOLDOWN（默认30s）后放行一个探测请求，成功则恢复，失败则继续熔断
+type CircuitBreaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    int
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// 各微信接口的熔断器，进程内所有服务实例共用
+var (
+	msgSecCheckBreaker  = newCircuitBreaker(metrics.APIMsgSecCheck)
+	mediaCheckBreaker   = newCircuitBreaker(metrics.APIMediaCheckAsync)
+	cloudStorageBreaker = newCircuitBreaker(metrics.APIBatchDownloadFile)
+)
+
+// newCircuitBreaker 创建熔断器，阈值为0时不熔断
+func newCircuitBreaker(name string) *CircuitBreaker {
+	return &CircuitBreaker{
+		name:      name,
+		threshold: envInt("WECHAT_BREAKER_THRESHOLD", 5),
+		cooldown:  envDuration("WECHAT_BREAKER_COOLDOWN", 30*time.Second),
+	}
+}
+
+// Allow 判断是否放行请求
+func (b *CircuitBreaker) Allow() error {
+	if b.threshold <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.setState(circuitHalfOpen)
+		b.probing = true
+		return nil
+	case circuitHalfOpen:
+		// 探测请求未返回前其他请求继续失败
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Record 记录一次请求结果，success为false表示临时性失败（网络错误、5xx、系统繁忙）
+func (b *CircuitBreaker) Record(success bool) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if success {
+		b.failures = 0
+		if b.state != circuitClosed {
+			slog.Info("微信接口熔断恢复", "api", b.name)
+			b.setState(circuitClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == circuitHalfOpen || (b.state == circuitClosed && b.failures >= b.threshold) {
+		slog.Warn("微信接口熔断", "api", b.name, "failures", b.failures, "cooldown", b.cooldown)
+		b.openedAt = time.Now()
+		b.setState(circuitOpen)
+	}
+}
+
+// Open 熔断器当前是否处于熔断状态
+func (b *CircuitBreaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state == circuitOpen && time.Since(b.openedAt) < b.cooldown
+}
+
+// setState 切换状态并更新监控指标，调用方需持有锁
+func (b *CircuitBreaker) setState(state int) {
+	b.state = state
+	metrics.ObserveCircuitState(b.name, state)
+}
+
+// wechatTransport 为微信接口请求增加熔断和重试：网络错误、5xx和 errcode=-1（系统繁忙）视为临时性失败，
+// 按 WECHAT_RETRY_BACKOFF（默认200ms）起指数退避，共请求 WECHAT_RETRY_ATTEMPTS（默认3）次；
+// 超时或重试中熔断时不再重试，重试总耗时受 http.Client 的超时限制
+type wechatTransport struct {
+	base     http.RoundTripper
+	breaker  *CircuitBreaker
+	attempts int
+	backoff  time.Duration
+}
+
+// newWechatTransport 创建带熔断和重试的Transport
+func newWechatTransport(base http.RoundTripper, breaker *CircuitBreaker) *wechatTransport {
+	attempts := envInt("WECHAT_RETRY_ATTEMPTS", 3)
+	if attempts < 1 {
+		attempts = 1
+	}
+	return &wechatTransport{
+		base:     base,
+		breaker:  breaker,
+		attempts: attempts,
+		backoff:  envDuration("WECHAT_RETRY_BACKOFF", 200*time.Millisecond),
+	}
+}
+
+// RoundTrip 发送请求，临时性失败时重试
+func (t *wechatTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	backoff := t.backoff
+	for attempt := 1; ; attempt++ {
+		if err := t.breaker.Allow(); err != nil {
+			return nil, err
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		transient := false
+		switch {
+		case err != nil:
+			transient = true
+		case resp.StatusCode >= http.StatusInternalServerError:
+			transient = true
+		default:
+			resp, transient = peekSystemBusy(resp)
+		}
+		t.breaker.Record(!transient)
+
+		timedOut := ctx.Err() != nil
+		var netErr interface{ Timeout() bool }
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			timedOut = true
+		}
+		if !transient || timedOut || attempt >= t.attempts || (req.Body != nil && req.GetBody == nil) || t.breaker.Open() {
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		metrics.ObserveWechatRetry(t.breaker.name)
+		slog.WarnContext(ctx, "微信接口临时性失败，稍后重试", "api", t.breaker.name, "attempt", attempt, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+
+		// 重试需要重新读取请求体
+		next := req.Clone(ctx)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			next.Body = body
+		}
+		req = next
+	}
+}
+
+// peekSystemBusy 读取响应体判断 errcode 是否为 -1（系统繁忙），并还原响应体供调用方读取
+func peekSystemBusy(resp *http.Response) (*http.Response, bool) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return resp, true
+	}
+	var result struct {
+		Errcode int `json:"errcode"`
+	}
+	if json.Unmarshal(body, &result) != nil {
+		return resp, false
+	}
+	return resp, result.Errcode == -1
+}
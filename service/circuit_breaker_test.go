@@ -0,0 +1,227 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	type step struct {
+		op        string // allow/success/failure/cooldown
+		wantErr   error
+		wantState int
+	}
+	tests := []struct {
+		name      string
+		threshold int
+		steps     []step
+	}{
+		{
+			name:      "连续失败达到阈值后熔断",
+			threshold: 2,
+			steps: []step{
+				{op: "failure", wantState: circuitClosed},
+				{op: "failure", wantState: circuitOpen},
+				{op: "allow", wantErr: ErrCircuitOpen, wantState: circuitOpen},
+			},
+		},
+		{
+			name:      "成功会清零失败次数",
+			threshold: 2,
+			steps: []step{
+				{op: "failure", wantState: circuitClosed},
+				{op: "success", wantState: circuitClosed},
+				{op: "failure", wantState: circuitClosed},
+				{op: "allow", wantState: circuitClosed},
+			},
+		},
+		{
+			name:      "冷却后只放行一个探测请求，探测成功恢复",
+			threshold: 1,
+			steps: []step{
+				{op: "failure", wantState: circuitOpen},
+				{op: "cooldown", wantState: circuitOpen},
+				{op: "allow", wantState: circuitHalfOpen},
+				{op: "allow", wantErr: ErrCircuitOpen, wantState: circuitHalfOpen},
+				{op: "success", wantState: circuitClosed},
+				{op: "allow", wantState: circuitClosed},
+			},
+		},
+		{
+			name:      "探测失败继续熔断",
+			threshold: 3,
+			steps: []step{
+				{op: "failure", wantState: circuitClosed},
+				{op: "failure", wantState: circuitClosed},
+				{op: "failure", wantState: circuitOpen},
+				{op: "cooldown", wantState: circuitOpen},
+				{op: "allow", wantState: circuitHalfOpen},
+				{op: "failure", wantState: circuitOpen},
+				{op: "allow", wantErr: ErrCircuitOpen, wantState: circuitOpen},
+			},
+		},
+		{
+			name:      "阈值为0时不熔断",
+			threshold: 0,
+			steps: []step{
+				{op: "failure", wantState: circuitClosed},
+				{op: "failure", wantState: circuitClosed},
+				{op: "allow", wantState: circuitClosed},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &CircuitBreaker{name: "test", threshold: tt.threshold, cooldown: time.Minute}
+			for i, s := range tt.steps {
+				var err error
+				switch s.op {
+				case "allow":
+					err = b.Allow()
+				case "success":
+					b.Record(true)
+				case "failure":
+					b.Record(false)
+				case "cooldown":
+					b.openedAt = b.openedAt.Add(-b.cooldown)
+				}
+				if !errors.Is(err, s.wantErr) {
+					t.Errorf("第%d步 %s error = %v, want %v", i+1, s.op, err, s.wantErr)
+				}
+				if b.state != s.wantState {
+					t.Errorf("第%d步 %s state = %d, want %d", i+1, s.op, b.state, s.wantState)
+				}
+				// 冷却期结束后即使尚未转为半开，Open() 也返回false
+				if wantOpen := s.wantState == circuitOpen && s.op != "cooldown"; b.Open() != wantOpen {
+					t.Errorf("第%d步 %s Open() = %v", i+1, s.op, b.Open())
+				}
+			}
+		})
+	}
+}
+
+// stubResult 测试桩的一次响应，err不为空时返回网络错误
+type stubResult struct {
+	status int
+	body   string
+	err    error
+}
+
+// stubRoundTripper 按顺序返回预设响应，并记录每次收到的请求体
+type stubRoundTripper struct {
+	results []stubResult
+	bodies  []string
+}
+
+func (s *stubRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := ""
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		body = string(data)
+	}
+	s.bodies = append(s.bodies, body)
+
+	result := s.results[len(s.bodies)-1]
+	if result.err != nil {
+		return nil, result.err
+	}
+	return &http.Response{
+		StatusCode: result.status,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader(result.body)),
+		Request:    req,
+	}, nil
+}
+
+// timeoutError 超时类网络错误
+type timeoutError struct{}
+
+func (timeoutError) Error() string { return "i/o timeout" }
+func (timeoutError) Timeout() bool { return true }
+
+func TestWechatTransportRetry(t *testing.T) {
+	networkErr := errors.New("connection reset by peer")
+	ok := stubResult{status: http.StatusOK, body: `{"errcode":0,"errmsg":"ok"}`}
+	busy := stubResult{status: http.StatusOK, body: `{"errcode":-1,"errmsg":"system error"}`}
+
+	tests := []struct {
+		name      string
+		results   []stubResult
+		noGetBody bool
+		threshold int
+		wantCalls int
+		wantErr   error
+		wantBody  string
+	}{
+		{name: "成功不重试", results: []stubResult{ok}, wantCalls: 1, wantBody: ok.body},
+		{name: "网络错误后重试成功", results: []stubResult{{err: networkErr}, ok}, wantCalls: 2, wantBody: ok.body},
+		{name: "5xx后重试成功", results: []stubResult{{status: http.StatusBadGateway}, ok}, wantCalls: 2, wantBody: ok.body},
+		{name: "系统繁忙后重试成功", results: []stubResult{busy, busy, ok}, wantCalls: 3, wantBody: ok.body},
+		{name: "重试次数用完返回最后一次的响应", results: []stubResult{busy, busy, busy}, wantCalls: 3, wantBody: busy.body},
+		{name: "重试次数用完返回最后一次的网络错误", results: []stubResult{{err: networkErr}, {err: networkErr}, {err: networkErr}}, wantCalls: 3, wantErr: networkErr},
+		{name: "4xx不重试", results: []stubResult{{status: http.StatusBadRequest, body: "bad"}}, wantCalls: 1, wantBody: "bad"},
+		{name: "业务错误码不重试", results: []stubResult{{status: http.StatusOK, body: `{"errcode":40001}`}}, wantCalls: 1, wantBody: `{"errcode":40001}`},
+		{name: "超时不重试", results: []stubResult{{err: timeoutError{}}}, wantCalls: 1, wantErr: timeoutError{}},
+		{name: "请求体无法重放时不重试", results: []stubResult{{err: networkErr}}, noGetBody: true, wantCalls: 1, wantErr: networkErr},
+		{name: "重试中熔断后不再重试", results: []stubResult{{err: networkErr}, {err: networkErr}}, threshold: 2, wantCalls: 2, wantErr: networkErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubRoundTripper{results: tt.results}
+			threshold := tt.threshold
+			if threshold == 0 {
+				threshold = 10
+			}
+			transport := &wechatTransport{
+				base:     stub,
+				breaker:  &CircuitBreaker{name: "test", threshold: threshold, cooldown: time.Minute},
+				attempts: 3,
+			}
+
+			const payload = `{"content":"hello"}`
+			req, err := http.NewRequest(http.MethodPost, "https://api.weixin.qq.com/wxa/msg_sec_check", strings.NewReader(payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.noGetBody {
+				req.GetBody = nil
+			}
+
+			resp, err := transport.RoundTrip(req)
+			if len(stub.bodies) != tt.wantCalls {
+				t.Errorf("请求次数 = %d, want %d", len(stub.bodies), tt.wantCalls)
+			}
+			for i, body := range stub.bodies {
+				if body != payload {
+					t.Errorf("第%d次请求体 = %q, want %q", i+1, body, payload)
+				}
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("RoundTrip() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RoundTrip() error = %v", err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("响应体 = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
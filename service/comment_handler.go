@@ -2,6 +2,7 @@ package service
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -10,12 +11,14 @@ import (
 // CommentHandler 评论处理器
 type CommentHandler struct {
 	commentService *CommentService
+	urlResolver    *TempURLResolver
 }
 
 // NewCommentHandler 创建评论处理器实例
 func NewCommentHandler() *CommentHandler {
 	return &CommentHandler{
 		commentService: NewCommentService(),
+		urlResolver:    NewTempURLResolver(),
 	}
 }
 
@@ -63,7 +66,11 @@ func (h *CommentHandler) CreateCommentHandler(w http.ResponseWriter, r *http.Req
 	openid := r.Header.Get("x-wx-openid")
 
 	// 调用服务
-	result, err := h.commentService.CreateComment(postId, &req, userId, openid)
+	result, err := h.commentService.CreateComment(r.Context(), postId, &req, userId, openid)
+	if errors.Is(err, ErrCommentBlocked) || IsAccountRestricted(err) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -116,21 +123,22 @@ func (h *CommentHandler) GetCommentListHandler(w http.ResponseWriter, r *http.Re
 		}
 	}
 
-	// 获取用户ID（这里简化处理，实际应该从token中获取）
-	userIdStr := r.Header.Get("X-User-Id")
+	// 从用户上下文中获取用户ID
+	userCtx := GetUserFromContext(r)
 	var userId int64
-	if userIdStr != "" {
-		if id, err := strconv.ParseInt(userIdStr, 10, 64); err == nil {
-			userId = id
-		}
+	if userCtx != nil && userCtx.User != nil {
+		userId = userCtx.User.Id
 	}
 
 	// 调用服务
-	result, err := h.commentService.GetCommentList(postId, page, pageSize, userId)
+	result, err := h.commentService.GetCommentList(r.Context(), postId, page, pageSize, userId)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if wantTempURLs(r) {
+		h.urlResolver.ResolveComments(r.Context(), result.List)
+	}
 
 	// 返回响应
 	response := map[string]interface{}{
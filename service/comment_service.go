@@ -1,11 +1,17 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"math"
 	"time"
 	"wxcloudrun-golang/db/dao"
 	"wxcloudrun-golang/db/model"
+	"wxcloudrun-golang/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // CommentService 评论服务
@@ -13,7 +19,12 @@ type CommentService struct {
 	commentDao dao.CommentDao
 	userDao    dao.UserDao
 	postDao    dao.PostDao
-	securityService *ContentSecurityService
+	userBlockDao dao.UserBlockDao
+	textModerator   *TextModerator
+	spamService     *SpamService
+	moderationService *ModerationService
+	accountService    *AccountService
+	contentChecks     *ContentCheckService
 }
 
 // NewCommentService 创建评论服务实例
@@ -22,20 +33,31 @@ func NewCommentService() *CommentService {
 		commentDao: dao.NewCommentDao(),
 		userDao:    dao.NewUserDao(),
 		postDao:    dao.NewPostDao(),
-		securityService: NewContentSecurityService(),
+		userBlockDao: dao.NewUserBlockDao(),
+		textModerator:   NewTextModerator(),
+		spamService:     NewSpamService(),
+		moderationService: NewModerationService(),
+		accountService:    NewAccountService(),
+		contentChecks:     NewContentCheckService(),
 	}
 }
 
+// ErrCommentBlocked 帖子或父评论作者已拉黑当前用户
+var ErrCommentBlocked = errors.New("对方已将你拉黑，无法评论")
+
 // CreateCommentRequest 创建评论请求
 type CreateCommentRequest struct {
 	Content  string `json:"content"`
 	ParentId int64  `json:"parentId"`
+	Audio    string `json:"audio"`         // 语音评论地址（cloud:// 文件ID或URL），可选
+	AudioDuration int `json:"audioDuration"` // 语音时长（秒）
 }
 
 // CreateCommentResponse 创建评论响应
 type CreateCommentResponse struct {
-	CommentId int64     `json:"commentId"`
-	CreatedAt time.Time `json:"createdAt"`
+	CommentId   int64     `json:"commentId"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UnderReview bool      `json:"underReview,omitempty"` // 评论进入人工审核，审核通过前仅作者可见
 }
 
 // CommentListResponse 评论列表响应
@@ -48,6 +70,9 @@ type CommentListResponse struct {
 type CommentDetail struct {
 	Id        int64     `json:"id"`
 	Content   string    `json:"content"`
+	Audio     string    `json:"audio,omitempty"`
+	AudioURL  string    `json:"audioUrl,omitempty"` // 语音的临时访问地址，请求带 resolveUrls=true 时返回
+	AudioDuration int   `json:"audioDuration,omitempty"`
 	Author    UserInfo  `json:"author"`
 	PostId    int64     `json:"postId"`
 	ParentId  *int64    `json:"parentId"`
@@ -58,61 +83,167 @@ type CommentDetail struct {
 }
 
 // CreateComment 创建评论
-func (s *CommentService) CreateComment(postId int64, req *CreateCommentRequest, authorId int64, openid string) (*CreateCommentResponse, error) {
+func (s *CommentService) CreateComment(ctx context.Context, postId int64, req *CreateCommentRequest, authorId int64, openid string) (resp *CreateCommentResponse, err error) {
+	ctx, span := tracing.Start(ctx, "CommentService.CreateComment")
+	span.SetAttributes(attribute.Int64("user.id", authorId), attribute.Int64("post.id", postId))
+	defer func() { tracing.End(span, err) }()
+
+	if err := validateAudio(req.Audio, req.AudioDuration); err != nil {
+		return nil, err
+	}
+
 	// 验证帖子是否存在
-	_, err := s.postDao.GetById(postId)
+	post, err := s.postDao.GetById(ctx, postId)
 	if err != nil {
 		return nil, fmt.Errorf("帖子不存在: %v", err)
 	}
 
-	// 内容安全校验
-	if openid != "" && req.Content != "" {
-		isSafe, err := s.securityService.IsContentSafe(openid, req.Content, SceneComment)
+	// 被帖子作者拉黑的用户不能评论其帖子
+	if err := s.checkNotBlocked(ctx, post.AuthorId, authorId); err != nil {
+		return nil, err
+	}
+
+	// 反垃圾检测（先于微信内容安全检测，避免垃圾内容消耗检测额度）
+	author, err := s.userDao.GetById(ctx, authorId)
+	if err != nil {
+		return nil, fmt.Errorf("用户不存在: %v", err)
+	}
+	if err := checkAccountWritable(author); err != nil {
+		return nil, err
+	}
+	moderationStatus := model.ModerationStatusNormal
+	moderationReason := ""
+	var reviewItems []*model.ModerationQueueModel
+	spamResult, spamErr := s.spamService.CheckComment(ctx, author, req.Content)
+	if spamErr != nil {
+		// 反垃圾检测异常时不阻断评论
+		slog.ErrorContext(ctx, "反垃圾检测失败", "user_id", authorId, "error", spamErr)
+	} else {
+		moderationStatus, err = spamModerationStatus(spamResult)
 		if err != nil {
-			return nil, fmt.Errorf("内容安全检测失败: %v", err)
+			return nil, err
+		}
+		moderationReason = spamResult.Reason
+		if moderationStatus == model.ModerationStatusPending {
+			reviewItems = append(reviewItems, newSpamReviewItem(req.Content, spamResult))
 		}
-		if !isSafe {
+	}
+
+	// 记录本次评论的内容检测，评论被拒绝时目标ID为0
+	checkLog := NewContentCheckLog(model.ContentCheckTargetComment, authorId)
+	var createdCommentId int64
+	defer func() { s.contentChecks.Save(ctx, checkLog, createdCommentId, postId) }()
+
+	// 内容安全校验（本地关键词过滤 + 微信内容安全检测），按审核策略处理：
+	// 待审核的评论先发布为待审核状态，命中静默隐藏策略的评论仅作者可见
+	shadowed := false
+	if req.Content != "" {
+		outcome, err := s.textModerator.Check(ctx, checkLog, openid, "content", SceneComment, req.Content)
+		if err != nil {
+			return nil, err
+		}
+		switch outcome.Action {
+		case PolicyActionAllow:
+		case PolicyActionReview:
+			reviewItems = append(reviewItems, outcome.ReviewItem(req.Content))
+		case PolicyActionShadow:
+			shadowed = true
+		default:
+			s.accountService.RecordViolation(ctx, authorId, "comment")
 			return nil, fmt.Errorf("评论内容包含违规信息，请修改后重试")
 		}
 	}
+	if shadowed {
+		moderationStatus = model.ModerationStatusShadowHidden
+		moderationReason = "内容命中审核策略"
+	} else if len(reviewItems) > 0 && moderationStatus == model.ModerationStatusNormal {
+		moderationStatus = model.ModerationStatusPending
+		moderationReason = "内容待人工审核"
+	}
+	// 语音评论在语音检测通过前仅作者可见
+	if req.Audio != "" && moderationStatus == model.ModerationStatusNormal {
+		moderationStatus = model.ModerationStatusPending
+		moderationReason = "语音检测中"
+	}
 
 	// 创建评论
 	comment := &model.CommentModel{
 		Content:  req.Content,
+		Audio:    req.Audio,
+		AudioDuration: req.AudioDuration,
 		AuthorId: authorId,
 		PostId:   postId,
 		ParentId: nil,
+		ModerationStatus: moderationStatus,
+		ModerationReason: moderationReason,
 	}
 
 	// 如果有父评论ID，验证父评论是否存在
 	if req.ParentId != 0 {
-		_, err := s.commentDao.GetById(req.ParentId)
+		parent, err := s.commentDao.GetById(ctx, req.ParentId)
 		if err != nil {
 			return nil, fmt.Errorf("父评论不存在: %v", err)
 		}
+		if err := s.checkNotBlocked(ctx, parent.AuthorId, authorId); err != nil {
+			return nil, err
+		}
 		comment.ParentId = &req.ParentId
 	}
 
-	err = s.commentDao.Create(comment)
+	// 语音评论：在同一事务中写入评论、语音检测记录和检测任务，由后台worker提交语音检测
+	if req.Audio != "" {
+		audioCheck := &model.MediaCheckModel{
+			MediaType: model.MediaTypeAudio,
+			MediaURL:  req.Audio,
+			Status:    model.MediaCheckStatusPending,
+		}
+		err = s.commentDao.CreateWithMediaCheck(ctx, comment, audioCheck, func(check *model.MediaCheckModel) (*model.JobModel, error) {
+			return newMediaCheckJob(check, openid)
+		})
+		if err == nil {
+			slog.InfoContext(ctx, "评论语音检测任务已创建", "comment_id", comment.Id, "check_id", audioCheck.Id)
+		}
+	} else {
+		err = s.commentDao.Create(ctx, comment)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("创建评论失败: %v", err)
 	}
+	createdCommentId = comment.Id
+	s.moderationService.EnqueueAll(ctx, reviewItems, model.ModerationTargetComment, comment.Id, postId, authorId)
 
-	// 更新帖子评论数
-	err = s.postDao.IncrementComments(postId)
-	if err != nil {
-		// 记录错误但不影响主流程
-		fmt.Printf("更新帖子评论数失败: %v\n", err)
+	// 更新帖子评论数，未通过审核的评论不计入
+	if comment.ModerationStatus == model.ModerationStatusNormal {
+		if err := s.postDao.IncrementComments(ctx, postId); err != nil {
+			// 记录错误但不影响主流程
+			slog.ErrorContext(ctx, "更新帖子评论数失败", "post_id", postId, "error", err)
+		}
 	}
 
 	return &CreateCommentResponse{
-		CommentId: comment.Id,
-		CreatedAt: comment.CreatedAt,
+		CommentId:   comment.Id,
+		CreatedAt:   comment.CreatedAt,
+		UnderReview: comment.ModerationStatus == model.ModerationStatusPending,
 	}, nil
 }
 
+// checkNotBlocked 校验ownerId未拉黑userId
+func (s *CommentService) checkNotBlocked(ctx context.Context, ownerId, userId int64) error {
+	if ownerId == userId {
+		return nil
+	}
+	blocked, err := s.userBlockDao.IsBlocked(ctx, ownerId, userId)
+	if err != nil {
+		return fmt.Errorf("查询拉黑关系失败: %v", err)
+	}
+	if blocked {
+		return ErrCommentBlocked
+	}
+	return nil
+}
+
 // GetCommentList 获取评论列表
-func (s *CommentService) GetCommentList(postId int64, page, pageSize int, userId int64) (*CommentListResponse, error) {
+func (s *CommentService) GetCommentList(ctx context.Context, postId int64, page, pageSize int, userId int64) (*CommentListResponse, error) {
 	// 参数验证
 	if page < 1 {
 		page = 1
@@ -122,7 +253,7 @@ func (s *CommentService) GetCommentList(postId int64, page, pageSize int, userId
 	}
 
 	// 获取主评论列表
-	comments, total, err := s.commentDao.GetByPostId(postId, page, pageSize)
+	comments, total, err := s.commentDao.GetByPostId(ctx, postId, userId, page, pageSize)
 	if err != nil {
 		return nil, fmt.Errorf("获取评论列表失败: %v", err)
 	}
@@ -131,7 +262,7 @@ func (s *CommentService) GetCommentList(postId int64, page, pageSize int, userId
 	commentDetails := make([]*CommentDetail, 0, len(comments))
 	for _, comment := range comments {
 		// 获取作者信息
-		author, err := s.userDao.GetById(comment.AuthorId)
+		author, err := s.userDao.GetById(ctx, comment.AuthorId)
 		if err != nil {
 			// 如果获取作者信息失败，使用默认信息
 			author = &model.UserModel{
@@ -147,6 +278,8 @@ func (s *CommentService) GetCommentList(postId int64, page, pageSize int, userId
 		commentDetail := &CommentDetail{
 			Id:       comment.Id,
 			Content:  comment.Content,
+			Audio:    comment.Audio,
+			AudioDuration: comment.AudioDuration,
 			Author: UserInfo{
 				Id:         author.Id,
 				Nickname:   author.Nickname,
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"wxcloudrun-golang/db/dao"
+	"wxcloudrun-golang/db/model"
+)
+
+// contentCheckSnippetLen 检测记录中保存的文本最大长度（字符数）
+const contentCheckSnippetLen = 200
+
+// ContentCheckService 内容检测记录服务
+type ContentCheckService struct {
+	contentCheckDao dao.ContentCheckDao
+}
+
+// NewContentCheckService 创建内容检测记录服务实例
+func NewContentCheckService() *ContentCheckService {
+	return &ContentCheckService{
+		contentCheckDao: dao.NewContentCheckDao(),
+	}
+}
+
+// ContentCheckLog 单次请求内产生的检测记录。检测发生在内容落库之前，
+// 因此先在内存中收集，内容创建（或被拒绝）后通过Save统一写入
+type ContentCheckLog struct {
+	targetType string
+	userId     int64
+	checks     []*model.ContentCheckModel
+}
+
+// NewContentCheckLog 创建检测记录收集器
+func NewContentCheckLog(targetType string, userId int64) *ContentCheckLog {
+	return &ContentCheckLog{targetType: targetType, userId: userId}
+}
+
+// AddText 记录一次文本检测，result为空表示检测请求失败
+func (l *ContentCheckLog) AddText(field string, scene int, content string, result *MsgSecCheckResponse, err error, elapsed time.Duration) {
+	check := &model.ContentCheckModel{
+		Kind:      model.ContentCheckKindText,
+		Field:     field,
+		Scene:     scene,
+		Content:   truncateRunes(content, contentCheckSnippetLen),
+		LatencyMs: elapsed.Milliseconds(),
+	}
+	if result != nil {
+		check.Suggest = result.Result.Suggest
+		check.Label = result.Result.Label
+		check.TraceId = result.TraceId
+		check.Errcode = result.Errcode
+		check.Errmsg = result.Errmsg
+		var keywords []string
+		for _, detail := range result.Detail {
+			if detail.Keyword != "" {
+				keywords = append(keywords, detail.Keyword)
+			}
+			if detail.Prob > check.Prob {
+				check.Prob = detail.Prob
+			}
+		}
+		check.Keywords = truncateRunes(strings.Join(keywords, ","), 500)
+	}
+	if err != nil {
+		check.Errcode = -1
+		check.Errmsg = truncateRunes(err.Error(), 200)
+	}
+	l.checks = append(l.checks, check)
+}
+
+// AddKeyword 记录一次命中本地关键词词库的过滤
+func (l *ContentCheckLog) AddKeyword(field string, scene int, content string, match *KeywordMatch, elapsed time.Duration) {
+	suggest := SuggestReview
+	if match.Action == model.KeywordActionReject {
+		suggest = SuggestRisky
+	}
+	l.checks = append(l.checks, &model.ContentCheckModel{
+		Kind:      model.ContentCheckKindKeyword,
+		Field:     field,
+		Scene:     scene,
+		Content:   truncateRunes(content, contentCheckSnippetLen),
+		Suggest:   suggest,
+		Keywords:  truncateRunes(strings.Join(match.Words, ","), 500),
+		LatencyMs: elapsed.Milliseconds(),
+	})
+}
+
+// AddMedia 记录一次图片/音频异步检测的提交，检测结果在回调中通过trace_id补全
+func (l *ContentCheckLog) AddMedia(kind, field string, scene int, mediaURL string, result *MediaCheckResponse, err error, elapsed time.Duration) {
+	check := &model.ContentCheckModel{
+		Kind:      kind,
+		Field:     field,
+		Scene:     scene,
+		Content:   truncateRunes(mediaURL, 500),
+		LatencyMs: elapsed.Milliseconds(),
+	}
+	if result != nil {
+		check.TraceId = result.TraceId
+		check.Errcode = result.Errcode
+		check.Errmsg = result.Errmsg
+	}
+	if err != nil {
+		check.Errcode = -1
+		check.Errmsg = truncateRunes(err.Error(), 200)
+	}
+	l.checks = append(l.checks, check)
+}
+
+// AddVideo 记录一次视频检测，视频检测器同步返回结论
+func (l *ContentCheckLog) AddVideo(field string, scene int, fileId string, result *VideoCheckResult, err error, elapsed time.Duration) {
+	check := &model.ContentCheckModel{
+		Kind:      model.ContentCheckKindVideo,
+		Field:     field,
+		Scene:     scene,
+		Content:   truncateRunes(fileId, 500),
+		LatencyMs: elapsed.Milliseconds(),
+	}
+	if result != nil {
+		check.TraceId = result.TraceId
+		check.Suggest = result.Suggest
+		check.Label = result.Label
+		check.Prob = result.Prob
+	}
+	if err != nil {
+		check.Errcode = -1
+		check.Errmsg = truncateRunes(err.Error(), 200)
+	}
+	l.checks = append(l.checks, check)
+}
+
+// Save 写入收集的检测记录，targetId为0表示内容被拒绝未创建。写入失败不影响主流程
+func (s *ContentCheckService) Save(ctx context.Context, log *ContentCheckLog, targetId, postId int64) {
+	if log == nil || len(log.checks) == 0 {
+		return
+	}
+	for _, check := range log.checks {
+		check.TargetType = log.targetType
+		check.TargetId = targetId
+		check.PostId = postId
+		check.UserId = log.userId
+	}
+	if err := s.contentCheckDao.CreateBatch(ctx, log.checks); err != nil {
+		slog.ErrorContext(ctx, "写入内容检测记录失败", "target_type", log.targetType, "target_id", targetId, "error", err)
+	}
+	log.checks = nil
+}
+
+// RecordMediaResult 写入异步媒体检测的回调结果，写入失败不影响主流程
+func (s *ContentCheckService) RecordMediaResult(ctx context.Context, traceId, suggest string, label int, prob float64, errcode int, errmsg string) {
+	if err := s.contentCheckDao.UpdateResultByTraceId(ctx, traceId, suggest, label, prob, errcode, errmsg); err != nil {
+		slog.WarnContext(ctx, "更新内容检测记录失败", "trace_id", traceId, "error", err)
+	}
+}
+
+// ContentCheckListResponse 内容检测记录列表响应
+type ContentCheckListResponse struct {
+	List       []*model.ContentCheckModel `json:"list"`
+	Pagination Pagination                 `json:"pagination"`
+}
+
+// GetChecks 分页查询内容检测记录
+func (s *ContentCheckService) GetChecks(ctx context.Context, filter dao.ContentCheckFilter, page, pageSize int) (*ContentCheckListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	checks, total, err := s.contentCheckDao.GetList(ctx, filter, page, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("获取内容检测记录失败: %v", err)
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+	return &ContentCheckListResponse{
+		List: checks,
+		Pagination: Pagination{
+			Current:  page,
+			PageSize: pageSize,
+			Total:    total,
+			HasMore:  page < totalPages,
+		},
+	}, nil
+}
+
+// truncateRunes 按字符数截断字符串
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
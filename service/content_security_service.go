@@ -2,11 +2,18 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"time"
+	"wxcloudrun-golang/metrics"
+	"wxcloudrun-golang/tracing"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // 内容安全检测场景值常量
@@ -37,7 +44,8 @@ const (
 
 // ContentSecurityService 内容安全校验服务
 type ContentSecurityService struct {
-	client *http.Client
+	client       *http.Client // 文本检测，使用 msg_sec_check 熔断器
+	mediaClient  *http.Client // 图片/音频异步检测，使用 media_check_async 熔断器
 	cloudStorage *WechatCloudStorageService
 }
 
@@ -45,7 +53,12 @@ type ContentSecurityService struct {
 func NewContentSecurityService() *ContentSecurityService {
 	return &ContentSecurityService{
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: newWechatTransport(otelhttp.NewTransport(http.DefaultTransport), msgSecCheckBreaker),
+		},
+		mediaClient: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: newWechatTransport(otelhttp.NewTransport(http.DefaultTransport), mediaCheckBreaker),
 		},
 		cloudStorage: NewWechatCloudStorageService(),
 	}
@@ -99,7 +112,20 @@ type MediaCheckResponse struct {
 }
 
 // CheckContentSecurity 检查内容安全性
-func (s *ContentSecurityService) CheckContentSecurity(openid, content string, scene int) (*MsgSecCheckResponse, error) {
+func (s *ContentSecurityService) CheckContentSecurity(ctx context.Context, openid, content string, scene int) (result *MsgSecCheckResponse, err error) {
+	ctx, span := tracing.Start(ctx, "ContentSecurityService.CheckContentSecurity")
+	span.SetAttributes(attribute.Int("wechat.scene", scene), attribute.Int("content.length", len(content)))
+	start := time.Now()
+	defer func() {
+		errcode := 0
+		if result != nil {
+			errcode = result.Errcode
+			span.SetAttributes(attribute.Int("wechat.errcode", result.Errcode), attribute.String("wechat.suggest", result.Result.Suggest))
+		}
+		metrics.ObserveWechatCall(metrics.APIMsgSecCheck, errcode, err, time.Since(start))
+		tracing.End(span, err)
+	}()
+
 	// 构建请求数据
 	requestData := MsgSecCheckRequest{
 		Openid:  openid,
@@ -115,7 +141,7 @@ func (s *ContentSecurityService) CheckContentSecurity(openid, content string, sc
 	}
 
 	//
-	req, err := http.NewRequest("POST", "http://api.weixin.qq.com/wxa/msg_sec_check", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", "http://api.weixin.qq.com/wxa/msg_sec_check", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("创建HTTP请求失败: %v", err)
 	}
@@ -135,19 +161,33 @@ func (s *ContentSecurityService) CheckContentSecurity(openid, content string, sc
 	if err != nil {
 		return nil, fmt.Errorf("读取响应内容失败: %v", err)
 	}
-	fmt.Printf("当前检测内容为:%s 当前检测结果为%s", requestData, body)
 
-	// 解析响应
+	// 解析响应，响应体可能包含用户内容和标识，只记录解析后的字段
 	var response MsgSecCheckResponse
 	if err := json.Unmarshal(body, &response); err != nil {
+		slog.DebugContext(ctx, "文本内容检测响应无法解析", "scene", scene, "status", resp.StatusCode, "size", len(body))
 		return nil, fmt.Errorf("解析响应失败: %v", err)
 	}
+	slog.DebugContext(ctx, "文本内容检测响应", "scene", scene, "openid", openid, "status", resp.StatusCode,
+		"errcode", response.Errcode, "errmsg", response.Errmsg, "trace_id", response.TraceId,
+		"suggest", response.Result.Suggest, "label", response.Result.Label)
 
 	return &response, nil
 }
 
 // CheckImageSecurity 检查图片内容安全性（云调用版本）
-func (s *ContentSecurityService) CheckImageSecurity(mediaURL, openid string, scene int) (*MediaCheckResponse, error) {
+func (s *ContentSecurityService) CheckImageSecurity(ctx context.Context, mediaURL, openid string, scene int) (result *MediaCheckResponse, err error) {
+	ctx, span := tracing.Start(ctx, "ContentSecurityService.CheckImageSecurity")
+	span.SetAttributes(attribute.Int("wechat.scene", scene), attribute.Int("wechat.media_type", 2))
+	start := time.Now()
+	defer func() {
+		if result != nil {
+			span.SetAttributes(attribute.Int("wechat.errcode", result.Errcode), attribute.String("wechat.trace_id", result.TraceId))
+		}
+		observeMediaCheck(result, err, time.Since(start))
+		tracing.End(span, err)
+	}()
+
 	// 构建请求数据
 	requestData := MediaCheckRequest{
 		MediaURL:  mediaURL,
@@ -167,7 +207,7 @@ func (s *ContentSecurityService) CheckImageSecurity(mediaURL, openid string, sce
 	url := "http://api.weixin.qq.com/wxa/media_check_async"
 
 	// 创建HTTP请求
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("创建HTTP请求失败: %v", err)
 	}
@@ -176,7 +216,7 @@ func (s *ContentSecurityService) CheckImageSecurity(mediaURL, openid string, sce
 	req.Header.Set("Content-Type", "application/json")
 
 	// 发送请求
-	resp, err := s.client.Do(req)
+	resp, err := s.mediaClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("发送请求失败: %v", err)
 	}
@@ -188,33 +228,32 @@ func (s *ContentSecurityService) CheckImageSecurity(mediaURL, openid string, sce
 		return nil, fmt.Errorf("读取响应内容失败: %v", err)
 	}
 
-	// 打印详细的请求和响应日志
-	fmt.Printf("=== 图片内容检测请求详情 ===\n")
-	fmt.Printf("请求URL: %s\n", url)
-	fmt.Printf("请求方法: POST\n")
-	fmt.Printf("请求头: Content-Type: application/json\n")
-	fmt.Printf("请求参数:\n")
-	fmt.Printf("  - media_url: %s\n", requestData.MediaURL)
-	fmt.Printf("  - media_type: %d (图片)\n", requestData.MediaType)
-	fmt.Printf("  - version: %d\n", requestData.Version)
-	fmt.Printf("  - scene: %d (论坛场景)\n", requestData.Scene)
-	fmt.Printf("  - openid: %s\n", requestData.Openid)
-	fmt.Printf("请求体JSON: %s\n", string(jsonData))
-	fmt.Printf("响应状态码: %d\n", resp.StatusCode)
-	fmt.Printf("响应体: %s\n", string(body))
-	fmt.Printf("=== 图片内容检测请求详情结束 ===\n")
-
-	// 解析响应
+	// 解析响应，只记录解析后的字段
 	var response MediaCheckResponse
 	if err := json.Unmarshal(body, &response); err != nil {
+		slog.DebugContext(ctx, "图片内容检测响应无法解析", "scene", requestData.Scene, "status", resp.StatusCode, "size", len(body))
 		return nil, fmt.Errorf("解析响应失败: %v", err)
 	}
+	slog.DebugContext(ctx, "图片内容检测响应", "media_url", requestData.MediaURL, "scene", requestData.Scene,
+		"openid", requestData.Openid, "status", resp.StatusCode,
+		"errcode", response.Errcode, "errmsg", response.Errmsg, "trace_id", response.TraceId)
 
 	return &response, nil
 }
 
 // CheckAudioSecurity 检查音频内容安全性（云调用版本）
-func (s *ContentSecurityService) CheckAudioSecurity(mediaURL, openid string, scene int) (*MediaCheckResponse, error) {
+func (s *ContentSecurityService) CheckAudioSecurity(ctx context.Context, mediaURL, openid string, scene int) (result *MediaCheckResponse, err error) {
+	ctx, span := tracing.Start(ctx, "ContentSecurityService.CheckAudioSecurity")
+	span.SetAttributes(attribute.Int("wechat.scene", scene), attribute.Int("wechat.media_type", 1))
+	start := time.Now()
+	defer func() {
+		if result != nil {
+			span.SetAttributes(attribute.Int("wechat.errcode", result.Errcode), attribute.String("wechat.trace_id", result.TraceId))
+		}
+		observeMediaCheck(result, err, time.Since(start))
+		tracing.End(span, err)
+	}()
+
 	// 构建请求数据
 	requestData := MediaCheckRequest{
 		MediaURL:  mediaURL,
@@ -234,7 +273,7 @@ func (s *ContentSecurityService) CheckAudioSecurity(mediaURL, openid string, sce
 	url := "http://api.weixin.qq.com/wxa/media_check_async"
 
 	// 创建HTTP请求
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("创建HTTP请求失败: %v", err)
 	}
@@ -243,7 +282,7 @@ func (s *ContentSecurityService) CheckAudioSecurity(mediaURL, openid string, sce
 	req.Header.Set("Content-Type", "application/json")
 
 	// 发送请求
-	resp, err := s.client.Do(req)
+	resp, err := s.mediaClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("发送请求失败: %v", err)
 	}
@@ -255,51 +294,56 @@ func (s *ContentSecurityService) CheckAudioSecurity(mediaURL, openid string, sce
 		return nil, fmt.Errorf("读取响应内容失败: %v", err)
 	}
 
-	// 打印详细的请求和响应日志
-	fmt.Printf("=== 音频内容检测请求详情 ===\n")
-	fmt.Printf("请求URL: %s\n", url)
-	fmt.Printf("请求方法: POST\n")
-	fmt.Printf("请求头: Content-Type: application/json\n")
-	fmt.Printf("请求参数:\n")
-	fmt.Printf("  - media_url: %s\n", requestData.MediaURL)
-	fmt.Printf("  - media_type: %d (音频)\n", requestData.MediaType)
-	fmt.Printf("  - version: %d\n", requestData.Version)
-	fmt.Printf("  - scene: %d (论坛场景)\n", requestData.Scene)
-	fmt.Printf("  - openid: %s\n", requestData.Openid)
-	fmt.Printf("请求体JSON: %s\n", string(jsonData))
-	fmt.Printf("响应状态码: %d\n", resp.StatusCode)
-	fmt.Printf("响应体: %s\n", string(body))
-	fmt.Printf("=== 音频内容检测请求详情结束 ===\n")
-
-	// 解析响应
+	// 解析响应，只记录解析后的字段
 	var response MediaCheckResponse
 	if err := json.Unmarshal(body, &response); err != nil {
+		slog.DebugContext(ctx, "音频内容检测响应无法解析", "scene", requestData.Scene, "status", resp.StatusCode, "size", len(body))
 		return nil, fmt.Errorf("解析响应失败: %v", err)
 	}
+	slog.DebugContext(ctx, "音频内容检测响应", "media_url", requestData.MediaURL, "scene", requestData.Scene,
+		"openid", requestData.Openid, "status", resp.StatusCode,
+		"errcode", response.Errcode, "errmsg", response.Errmsg, "trace_id", response.TraceId)
 
 	return &response, nil
 }
 
+// observeMediaCheck 记录media_check_async调用指标
+func observeMediaCheck(result *MediaCheckResponse, err error, elapsed time.Duration) {
+	errcode := 0
+	if result != nil {
+		errcode = result.Errcode
+	}
+	metrics.ObserveWechatCall(metrics.APIMediaCheckAsync, errcode, err, elapsed)
+}
+
 // IsContentSafe 判断内容是否安全
-func (s *ContentSecurityService) IsContentSafe(openid, content string, scene int) (bool, error) {
-	response, err := s.CheckContentSecurity(openid, content, scene)
+func (s *ContentSecurityService) IsContentSafe(ctx context.Context, openid, content string, scene int) (bool, error) {
+	response, err := s.CheckText(ctx, openid, content, scene)
 	if err != nil {
 		return false, err
 	}
 
+	// 只判断suggest字段，只有pass时通过
+	return response.Result.Suggest == SuggestPass, nil
+}
+
+// CheckText 检测文本内容，返回带有处理建议（pass/review/risky）的检测结果，错误码非0时返回错误
+func (s *ContentSecurityService) CheckText(ctx context.Context, openid, content string, scene int) (*MsgSecCheckResponse, error) {
+	response, err := s.CheckContentSecurity(ctx, openid, content, scene)
+	if err != nil {
+		return nil, err
+	}
+
 	// 检查错误码
 	if response.Errcode != 0 {
-		return false, fmt.Errorf("内容安全检测失败: %s", response.Errmsg)
+		return nil, fmt.Errorf("内容安全检测失败: %s", response.Errmsg)
 	}
 
-	// 只判断suggest字段，只有pass时通过
-	isSafe := response.Result.Suggest == "pass"
-
 	// 打印检测结果日志
-	fmt.Printf("[内容安全检测] OpenID: %s, 场景: %d, 建议: %s, 标签: %d, 是否通过: %t\n",
-		openid, scene, response.Result.Suggest, response.Result.Label, isSafe)
+	slog.InfoContext(ctx, "内容安全检测", "openid", openid, "scene", scene, "suggest", response.Result.Suggest,
+		"label", response.Result.Label, "trace_id", response.TraceId)
 
-	return isSafe, nil
+	return response, nil
 }
 
 // IsMediaCheckSuccess 判断媒体内容检测是否成功发起
@@ -316,13 +360,13 @@ func (s *ContentSecurityService) GetMediaCheckError(response *MediaCheckResponse
 }
 
 // GetContentSecurityResult 获取内容安全检测详细结果
-func (s *ContentSecurityService) GetContentSecurityResult(openid, content string, scene int) (*MsgSecCheckResponse, error) {
-	return s.CheckContentSecurity(openid, content, scene)
+func (s *ContentSecurityService) GetContentSecurityResult(ctx context.Context, openid, content string, scene int) (*MsgSecCheckResponse, error) {
+	return s.CheckContentSecurity(ctx, openid, content, scene)
 }
 
 // GetContentSecurityDetail 获取内容安全检测的详细分析
-func (s *ContentSecurityService) GetContentSecurityDetail(openid, content string, scene int) (*ContentSecurityDetail, error) {
-	response, err := s.CheckContentSecurity(openid, content, scene)
+func (s *ContentSecurityService) GetContentSecurityDetail(ctx context.Context, openid, content string, scene int) (*ContentSecurityDetail, error) {
+	response, err := s.CheckContentSecurity(ctx, openid, content, scene)
 	if err != nil {
 		return nil, err
 	}
@@ -331,8 +375,8 @@ func (s *ContentSecurityService) GetContentSecurityDetail(openid, content string
 	isSafe := response.Result.Suggest == "pass"
 
 	// 打印检测结果日志
-	fmt.Printf("[内容安全检测详情] OpenID: %s, 场景: %d, 建议: %s, 标签: %d, 是否通过: %t, 追踪ID: %s\n",
-		openid, scene, response.Result.Suggest, response.Result.Label, isSafe, response.TraceId)
+	slog.InfoContext(ctx, "内容安全检测详情", "openid", openid, "scene", scene, "suggest", response.Result.Suggest,
+		"label", response.Result.Label, "trace_id", response.TraceId, "is_safe", isSafe)
 
 	detail := &ContentSecurityDetail{
 		IsSafe:    isSafe,
@@ -434,45 +478,45 @@ func (s *ContentSecurityService) GetSuggestDescription(suggest string) string {
 }
 
 // CheckCloudStorageImageSecurity 检查云存储图片内容安全性
-func (s *ContentSecurityService) CheckCloudStorageImageSecurity(cloudID, openid string, scene int) (*MediaCheckResponse, error) {
+func (s *ContentSecurityService) CheckCloudStorageImageSecurity(ctx context.Context, cloudID, openid string, scene int) (*MediaCheckResponse, error) {
 	// 验证云存储文件ID格式
 	if !s.cloudStorage.ValidateCloudID(cloudID) {
 		return nil, fmt.Errorf("无效的云存储文件ID格式: %s", cloudID)
 	}
 
 	// 获取真实下载URL
-	downloadURL, err := s.cloudStorage.GetFileDownloadURL(cloudID)
+	downloadURL, err := s.cloudStorage.GetFileDownloadURL(ctx, cloudID)
 	if err != nil {
 		return nil, fmt.Errorf("获取云存储文件下载URL失败: %v", err)
 	}
 
-	fmt.Printf("云存储文件ID: %s, 获取到下载URL: %s\n", cloudID, downloadURL)
+	slog.DebugContext(ctx, "获取到云存储文件下载URL", "cloud_id", cloudID)
 
 	// 使用真实下载URL进行内容检测
-	return s.CheckImageSecurity(downloadURL, openid, scene)
+	return s.CheckImageSecurity(ctx, downloadURL, openid, scene)
 }
 
 // CheckCloudStorageAudioSecurity 检查云存储音频内容安全性
-func (s *ContentSecurityService) CheckCloudStorageAudioSecurity(cloudID, openid string, scene int) (*MediaCheckResponse, error) {
+func (s *ContentSecurityService) CheckCloudStorageAudioSecurity(ctx context.Context, cloudID, openid string, scene int) (*MediaCheckResponse, error) {
 	// 验证云存储文件ID格式
 	if !s.cloudStorage.ValidateCloudID(cloudID) {
 		return nil, fmt.Errorf("无效的云存储文件ID格式: %s", cloudID)
 	}
 
 	// 获取真实下载URL
-	downloadURL, err := s.cloudStorage.GetFileDownloadURL(cloudID)
+	downloadURL, err := s.cloudStorage.GetFileDownloadURL(ctx, cloudID)
 	if err != nil {
 		return nil, fmt.Errorf("获取云存储文件下载URL失败: %v", err)
 	}
 
-	fmt.Printf("云存储文件ID: %s, 获取到下载URL: %s\n", cloudID, downloadURL)
+	slog.DebugContext(ctx, "获取到云存储文件下载URL", "cloud_id", cloudID)
 
 	// 使用真实下载URL进行内容检测
-	return s.CheckAudioSecurity(downloadURL, openid, scene)
+	return s.CheckAudioSecurity(ctx, downloadURL, openid, scene)
 }
 
 // CheckMultipleCloudStorageImagesSecurity 批量检查云存储图片内容安全性
-func (s *ContentSecurityService) CheckMultipleCloudStorageImagesSecurity(cloudIDs []string, openid string, scene int) (map[string]*MediaCheckResponse, error) {
+func (s *ContentSecurityService) CheckMultipleCloudStorageImagesSecurity(ctx context.Context, cloudIDs []string, openid string, scene int) (map[string]*MediaCheckResponse, error) {
 	if len(cloudIDs) == 0 {
 		return nil, fmt.Errorf("文件ID列表不能为空")
 	}
@@ -485,19 +529,19 @@ func (s *ContentSecurityService) CheckMultipleCloudStorageImagesSecurity(cloudID
 	}
 
 	// 批量获取真实下载URL
-	downloadURLs, err := s.cloudStorage.GetMultipleFileDownloadURLs(cloudIDs)
+	downloadURLs, err := s.cloudStorage.GetMultipleFileDownloadURLs(ctx, cloudIDs)
 	if err != nil {
 		return nil, fmt.Errorf("批量获取云存储文件下载URL失败: %v", err)
 	}
 
-	fmt.Printf("批量获取到 %d 个文件的下载URL\n", len(downloadURLs))
+	slog.DebugContext(ctx, "批量获取云存储文件下载URL", "count", len(downloadURLs))
 
 	// 批量进行内容检测
 	results := make(map[string]*MediaCheckResponse)
 	for cloudID, downloadURL := range downloadURLs {
-		response, err := s.CheckImageSecurity(downloadURL, openid, scene)
+		response, err := s.CheckImageSecurity(ctx, downloadURL, openid, scene)
 		if err != nil {
-			fmt.Printf("警告: 文件 %s 内容检测失败: %v\n", cloudID, err)
+			slog.WarnContext(ctx, "文件内容检测失败", "cloud_id", cloudID, "error", err)
 			continue
 		}
 		results[cloudID] = response
@@ -507,7 +551,7 @@ func (s *ContentSecurityService) CheckMultipleCloudStorageImagesSecurity(cloudID
 }
 
 // CheckMultipleCloudStorageAudiosSecurity 批量检查云存储音频内容安全性
-func (s *ContentSecurityService) CheckMultipleCloudStorageAudiosSecurity(cloudIDs []string, openid string, scene int) (map[string]*MediaCheckResponse, error) {
+func (s *ContentSecurityService) CheckMultipleCloudStorageAudiosSecurity(ctx context.Context, cloudIDs []string, openid string, scene int) (map[string]*MediaCheckResponse, error) {
 	if len(cloudIDs) == 0 {
 		return nil, fmt.Errorf("文件ID列表不能为空")
 	}
@@ -520,19 +564,19 @@ func (s *ContentSecurityService) CheckMultipleCloudStorageAudiosSecurity(cloudID
 	}
 
 	// 批量获取真实下载URL
-	downloadURLs, err := s.cloudStorage.GetMultipleFileDownloadURLs(cloudIDs)
+	downloadURLs, err := s.cloudStorage.GetMultipleFileDownloadURLs(ctx, cloudIDs)
 	if err != nil {
 		return nil, fmt.Errorf("批量获取云存储文件下载URL失败: %v", err)
 	}
 
-	fmt.Printf("批量获取到 %d 个文件的下载URL\n", len(downloadURLs))
+	slog.DebugContext(ctx, "批量获取云存储文件下载URL", "count", len(downloadURLs))
 
 	// 批量进行内容检测
 	results := make(map[string]*MediaCheckResponse)
 	for cloudID, downloadURL := range downloadURLs {
-		response, err := s.CheckAudioSecurity(downloadURL, openid, scene)
+		response, err := s.CheckAudioSecurity(ctx, downloadURL, openid, scene)
 		if err != nil {
-			fmt.Printf("警告: 文件 %s 内容检测失败: %v\n", cloudID, err)
+			slog.WarnContext(ctx, "文件内容检测失败", "cloud_id", cloudID, "error", err)
 			continue
 		}
 		results[cloudID] = response
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+var errImageMalformed = errors.New("图片结构无效")
+
+// stripImageMetadata 去除图片中的EXIF、XMP和文本元数据（可能包含拍摄位置、设备信息），不重新编码图片。
+// JPEG会保留方向信息，避免去除EXIF后照片显示方向错误；GIF不含EXIF，原样返回
+func stripImageMetadata(contentType string, data []byte) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEGMetadata(data)
+	case "image/png":
+		return stripPNGMetadata(data)
+	case "image/webp":
+		return stripWebPMetadata(data)
+	default:
+		return data, nil
+	}
+}
+
+// stripJPEGMetadata 去除JPEG的APP1（EXIF/XMP）、APP13（IPTC）和注释段，EXIF中的方向不为1时写回仅含方向的EXIF段
+func stripJPEGMetadata(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, errImageMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+	pos := 2
+	for {
+		if pos+4 > len(data) || data[pos] != 0xFF {
+			return nil, errImageMalformed
+		}
+		marker := data[pos+1]
+		if marker == 0xFF {
+			// 段之间的填充字节
+			pos++
+			continue
+		}
+		if marker == 0xD9 || (marker >= 0xD0 && marker <= 0xD7) || marker == 0x01 {
+			out.Write(data[pos : pos+2])
+			pos += 2
+			continue
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, errImageMalformed
+		}
+		segment := data[pos:end]
+
+		switch marker {
+		case 0xE1:
+			// 在原EXIF段的位置写回方向信息
+			if o := exifOrientation(segment[4:]); o > 1 {
+				out.Write(orientationExifSegment(o))
+			}
+		case 0xED, 0xFE:
+		case 0xDA:
+			// 扫描数据开始，之后的内容原样保留
+			out.Write(data[pos:])
+			return out.Bytes(), nil
+		default:
+			out.Write(segment)
+		}
+		pos = end
+	}
+}
+
+// exifOrientation 读取APP1段中EXIF的方向标签，不存在或无法解析时返回0
+func exifOrientation(payload []byte) uint16 {
+	if len(payload) < 14 || !bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+		return 0
+	}
+	tiff := payload[6:]
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd : ifd+2]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 && order.Uint16(tiff[entry+2:entry+4]) == 3 {
+			if o := order.Uint16(tiff[entry+8 : entry+10]); o >= 1 && o <= 8 {
+				return o
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// orientationExifSegment 构造仅含方向标签的APP1段
+func orientationExifSegment(orientation uint16) []byte {
+	segment := []byte{
+		0xFF, 0xE1, 0x00, 0x22,
+		'E', 'x', 'i', 'f', 0x00, 0x00,
+		'M', 'M', 0x00, 0x2A, 0x00, 0x00, 0x00, 0x08, // TIFF头，IFD0紧随其后
+		0x00, 0x01, // 1个标签
+		0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, // Orientation, SHORT, 1
+		0x00, 0x00, 0x00, 0x00, // 无下一个IFD
+	}
+	binary.BigEndian.PutUint16(segment[28:30], orientation)
+	return segment
+}
+
+// pngMetadataChunks PNG中需要去除的元数据块
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+// stripPNGMetadata 去除PNG的EXIF、文本和时间块
+func stripPNGMetadata(data []byte) ([]byte, error) {
+	signature := []byte("\x89PNG\r\n\x1a\n")
+	if !bytes.HasPrefix(data, signature) {
+		return nil, errImageMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(signature)
+	pos := len(signature)
+	for pos < len(data) {
+		if pos+12 > len(data) {
+			return nil, errImageMalformed
+		}
+		length := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		end := pos + 12 + length
+		if end > len(data) {
+			return nil, errImageMalformed
+		}
+		chunkType := string(data[pos+4 : pos+8])
+		if crc32.ChecksumIEEE(data[pos+4:end-4]) != binary.BigEndian.Uint32(data[end-4:end]) {
+			return nil, errImageMalformed
+		}
+		if !pngMetadataChunks[chunkType] {
+			out.Write(data[pos:end])
+		}
+		pos = end
+		if chunkType == "IEND" {
+			return out.Bytes(), nil
+		}
+	}
+	return nil, errImageMalformed
+}
+
+// stripWebPMetadata 去除WebP的EXIF和XMP块，并清除VP8X中对应的标志位
+func stripWebPMetadata(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errImageMalformed
+	}
+	riffEnd := 8 + int(binary.LittleEndian.Uint32(data[4:8]))
+	if riffEnd > len(data) || riffEnd < 12 {
+		return nil, errImageMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:12])
+	pos := 12
+	for pos < riffEnd {
+		if pos+8 > riffEnd {
+			return nil, errImageMalformed
+		}
+		fourcc := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		end := pos + 8 + size + size%2
+		if end > riffEnd {
+			return nil, errImageMalformed
+		}
+		switch fourcc {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[pos:end]...)
+			if size > 0 {
+				chunk[8] &^= 0x08 | 0x04
+			}
+			out.Write(chunk)
+		default:
+			out.Write(data[pos:end])
+		}
+		pos = end
+	}
+
+	result := out.Bytes()
+	binary.LittleEndian.PutUint32(result[4:8], uint32(len(result)-8))
+	return result, nil
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testImage 生成测试用的小图片
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 32), uint8(y * 32), 128, 255})
+		}
+	}
+	return img
+}
+
+// testExifPayload 构造小端序EXIF：方向标签和一个含定位信息的文本标签
+func testExifPayload(orientation uint16) []byte {
+	var b bytes.Buffer
+	b.WriteString("Exif\x00\x00II*\x00")
+	binary.Write(&b, binary.LittleEndian, uint32(8))
+	binary.Write(&b, binary.LittleEndian, uint16(2))
+	// Orientation, SHORT, 1
+	binary.Write(&b, binary.LittleEndian, []uint16{0x0112, 3})
+	binary.Write(&b, binary.LittleEndian, uint32(1))
+	binary.Write(&b, binary.LittleEndian, []uint16{orientation, 0})
+	// ImageDescription, ASCII, 指向IFD之后的数据
+	binary.Write(&b, binary.LittleEndian, []uint16{0x010E, 2})
+	binary.Write(&b, binary.LittleEndian, uint32(12))
+	binary.Write(&b, binary.LittleEndian, uint32(8+2+2*12+4))
+	binary.Write(&b, binary.LittleEndian, uint32(0))
+	b.WriteString("GPS-SECRET\x00\x00")
+	return b.Bytes()
+}
+
+// jpegSegment 构造JPEG段
+func jpegSegment(marker byte, payload []byte) []byte {
+	segment := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// pngChunk 构造PNG块
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(chunk[:4], uint32(len(data)))
+	copy(chunk[4:8], chunkType)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// webpChunk 构造WebP块，奇数长度补齐一个字节
+func webpChunk(fourcc string, data []byte) []byte {
+	chunk := make([]byte, 8, 9+len(data))
+	copy(chunk[:4], fourcc)
+	binary.LittleEndian.PutUint32(chunk[4:8], uint32(len(data)))
+	chunk = append(chunk, data...)
+	if len(data)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// webpFile 拼接WebP文件
+func webpFile(chunks ...[]byte) []byte {
+	body := []byte("WEBP")
+	for _, chunk := range chunks {
+		body = append(body, chunk...)
+	}
+	file := []byte("RIFF\x00\x00\x00\x00")
+	binary.LittleEndian.PutUint32(file[4:8], uint32(len(body)))
+	return append(file, body...)
+}
+
+// assertSamePixels 比较两张图片解码后的像素
+func assertSamePixels(t *testing.T, want, got image.Image) {
+	t.Helper()
+	if want.Bounds() != got.Bounds() {
+		t.Fatalf("图片尺寸 = %v, want %v", got.Bounds(), want.Bounds())
+	}
+	for y := want.Bounds().Min.Y; y < want.Bounds().Max.Y; y++ {
+		for x := want.Bounds().Min.X; x < want.Bounds().Max.X; x++ {
+			if want.At(x, y) != got.At(x, y) {
+				t.Fatalf("像素(%d,%d) = %v, want %v", x, y, got.At(x, y), want.At(x, y))
+			}
+		}
+	}
+}
+
+func TestStripJPEGMetadata(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, testImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+	original, err := jpeg.Decode(bytes.NewReader(encoded.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := encoded.Bytes()
+
+	withMetadata := func(orientation uint16) []byte {
+		var b bytes.Buffer
+		b.Write(raw[:2])
+		b.Write(jpegSegment(0xE1, testExifPayload(orientation)))
+		b.Write(jpegSegment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta>XMP-SECRET</x:xmpmeta>")))
+		b.Write(jpegSegment(0xED, []byte("Photoshop 3.0\x00IPTC-SECRET")))
+		b.Write(jpegSegment(0xFE, []byte("COMMENT-SECRET")))
+		b.Write(raw[2:])
+		return b.Bytes()
+	}
+
+	tests := []struct {
+		name            string
+		input           []byte
+		wantOrientation uint16
+	}{
+		{"无元数据", raw, 0},
+		{"方向为1时不保留EXIF", withMetadata(1), 0},
+		{"保留旋转方向", withMetadata(6), 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := stripJPEGMetadata(tt.input)
+			if err != nil {
+				t.Fatalf("stripJPEGMetadata() error = %v", err)
+			}
+			for _, secret := range []string{"GPS-SECRET", "XMP-SECRET", "IPTC-SECRET", "COMMENT-SECRET"} {
+				if bytes.Contains(got, []byte(secret)) {
+					t.Errorf("元数据 %s 未去除", secret)
+				}
+			}
+
+			orientation := uint16(0)
+			if i := bytes.Index(got, []byte("Exif\x00\x00")); i >= 0 {
+				orientation = exifOrientation(got[i:])
+			}
+			if orientation != tt.wantOrientation {
+				t.Errorf("方向 = %d, want %d", orientation, tt.wantOrientation)
+			}
+
+			decoded, err := jpeg.Decode(bytes.NewReader(got))
+			if err != nil {
+				t.Fatalf("去除元数据后无法解码: %v", err)
+			}
+			assertSamePixels(t, original, decoded)
+		})
+	}
+}
+
+func TestStripJPEGMetadataMalformed(t *testing.T) {
+	tests := map[string][]byte{
+		"不是JPEG": []byte("GIF89a"),
+		"段长度越界":  {0xFF, 0xD8, 0xFF, 0xE1, 0x10, 0x00, 'E'},
+		"缺少扫描数据": {0xFF, 0xD8, 0xFF, 0xFE, 0x00, 0x03, 'x'},
+	}
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := stripJPEGMetadata(input); err == nil {
+				t.Error("stripJPEGMetadata() 应返回错误")
+			}
+		})
+	}
+}
+
+func TestStripPNGMetadata(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	raw := encoded.Bytes()
+
+	// 在IHDR之后插入元数据块
+	ihdrEnd := 8 + 12 + int(binary.BigEndian.Uint32(raw[8:12]))
+	var b bytes.Buffer
+	b.Write(raw[:ihdrEnd])
+	b.Write(pngChunk("eXIf", testExifPayload(6)[6:]))
+	b.Write(pngChunk("tEXt", []byte("Comment\x00TEXT-SECRET")))
+	b.Write(pngChunk("iTXt", []byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00XMP-SECRET")))
+	b.Write(pngChunk("tIME", []byte{0x07, 0xE8, 1, 1, 0, 0, 0}))
+	b.Write(raw[ihdrEnd:])
+
+	got, err := stripPNGMetadata(b.Bytes())
+	if err != nil {
+		t.Fatalf("stripPNGMetadata() error = %v", err)
+	}
+	if !bytes.Equal(got, raw) {
+		t.Error("去除元数据后应与原始图片一致")
+	}
+	decoded, err := png.Decode(bytes.NewReader(got))
+	if err != nil {
+		t.Fatalf("去除元数据后无法解码: %v", err)
+	}
+	assertSamePixels(t, testImage(), decoded)
+
+	corrupted := append([]byte(nil), b.Bytes()...)
+	corrupted[ihdrEnd+10] ^= 0xFF
+	if _, err := stripPNGMetadata(corrupted); err == nil {
+		t.Error("CRC错误时应返回错误")
+	}
+}
+
+func TestStripWebPMetadata(t *testing.T) {
+	imageData := []byte("VP8L-PIXELS") // 奇数长度，校验补齐字节
+	vp8x := []byte{0x08 | 0x04 | 0x10, 0, 0, 0, 7, 0, 0, 7, 0, 0}
+
+	tests := []struct {
+		name  string
+		input []byte
+		want  []byte
+	}{
+		{
+			name:  "简单格式无元数据",
+			input: webpFile(webpChunk("VP8L", imageData)),
+			want:  webpFile(webpChunk("VP8L", imageData)),
+		},
+		{
+			name: "扩展格式去除EXIF和XMP",
+			input: webpFile(
+				webpChunk("VP8X", vp8x),
+				webpChunk("ICCP", []byte("icc")),
+				webpChunk("VP8L", imageData),
+				webpChunk("EXIF", testExifPayload(6)[6:]),
+				webpChunk("XMP ", []byte("XMP-SECRET")),
+			),
+			want: webpFile(
+				webpChunk("VP8X", append([]byte{0x10}, vp8x[1:]...)),
+				webpChunk("ICCP", []byte("icc")),
+				webpChunk("VP8L", imageData),
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := stripWebPMetadata(tt.input)
+			if err != nil {
+				t.Fatalf("stripWebPMetadata() error = %v", err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("stripWebPMetadata() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	truncated := webpFile(webpChunk("VP8L", imageData))
+	binary.LittleEndian.PutUint32(truncated[16:20], 100)
+	if _, err := stripWebPMetadata(truncated); err == nil {
+		t.Error("块长度越界时应返回错误")
+	}
+}
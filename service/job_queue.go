@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+	"wxcloudrun-golang/db/dao"
+	"wxcloudrun-golang/db/model"
+	"wxcloudrun-golang/logger"
+	"wxcloudrun-golang/metrics"
+)
+
+// 异步任务执行结果，用于监控指标
+const (
+	jobOutcomeDone      = "done"
+	jobOutcomeRetry     = "retry"
+	jobOutcomeAbandoned = "abandoned"
+	jobOutcomeLockLost  = "lock_lost" // 执行超过抢占超时，任务已被其他worker重新抢占
+)
+
+// 异步任务默认配置
+const (
+	defaultJobMaxAttempts = 5
+	jobBaseBackoff        = 10 * time.Second
+	jobMaxBackoff         = 30 * time.Minute
+	jobPruneInterval      = time.Hour
+	jobPruneBatch         = 500
+)
+
+// JobHandler 异步任务处理器
+type JobHandler struct {
+	// Handle 执行任务，返回错误时按退避策略重试
+	Handle func(ctx context.Context, payload []byte) error
+	// GiveUp 超出重试次数放弃任务时调用，可为空
+	GiveUp func(ctx context.Context, payload []byte, err error)
+}
+
+// JobQueue 基于数据库的异步任务队列：任务与业务数据在同一事务中写入jobs表，
+// 由各实例的worker轮询抢占执行，失败后按指数退避重试，超出次数后放弃
+type JobQueue struct {
+	jobDao      dao.JobDao
+	handlers    map[string]JobHandler
+	workers     int
+	interval    time.Duration // 没有任务时的轮询间隔
+	lockTimeout time.Duration // 执行中任务的抢占超时，超时后视为worker异常退出并重新执行
+	retention   time.Duration // 已完成任务的保留时长，0为不清理
+	instanceId  string
+}
+
+// NewJobQueue 创建异步任务队列。可通过环境变量配置：
+// JOB_WORKERS（worker数，默认2，0为不在本实例执行任务）、JOB_POLL_INTERVAL（轮询间隔，默认2s）、JOB_LOCK_TIMEOUT（默认5m）、
+// JOB_RETENTION（已完成任务的保留时长，默认168h，0为不清理）
+func NewJobQueue() *JobQueue {
+	hostname, _ := os.Hostname()
+	return &JobQueue{
+		jobDao:      dao.NewJobDao(),
+		handlers:    make(map[string]JobHandler),
+		workers:     envInt("JOB_WORKERS", 2),
+		interval:    envDuration("JOB_POLL_INTERVAL", 2*time.Second),
+		lockTimeout: envDuration("JOB_LOCK_TIMEOUT", 5*time.Minute),
+		retention:   envDuration("JOB_RETENTION", 7*24*time.Hour),
+		instanceId:  fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+}
+
+// Register 注册任务处理器，需在Start之前调用
+func (q *JobQueue) Register(jobType string, handler JobHandler) {
+	q.handlers[jobType] = handler
+}
+
+// newJob 构造待执行的任务，maxAttempts为0时使用默认值
+func newJob(jobType string, payload interface{}, maxAttempts int) (*model.JobModel, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("序列化任务参数失败: %v", err)
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = envInt("JOB_MAX_ATTEMPTS", defaultJobMaxAttempts)
+	}
+	return &model.JobModel{
+		Type:        jobType,
+		Payload:     string(data),
+		Status:      model.JobStatusPending,
+		MaxAttempts: maxAttempts,
+		RunAt:       time.Now(),
+	}, nil
+}
+
+// Enqueue 写入一个待执行的任务
+func (q *JobQueue) Enqueue(ctx context.Context, jobType string, payload interface{}) error {
+	job, err := newJob(jobType, payload, 0)
+	if err != nil {
+		return err
+	}
+	if err := q.jobDao.Create(ctx, job); err != nil {
+		return fmt.Errorf("创建任务失败: %v", err)
+	}
+	return nil
+}
+
+// Start 启动worker和已完成任务的清理，ctx取消后worker在当前任务完成后退出
+func (q *JobQueue) Start(ctx context.Context) {
+	if q.workers <= 0 {
+		slog.Info("异步任务worker未启用")
+		return
+	}
+	slog.Info("异步任务worker已启动", "workers", q.workers, "instance", q.instanceId)
+
+	for i := 0; i < q.workers; i++ {
+		go q.work(ctx, q.instanceId+"-"+strconv.Itoa(i))
+	}
+	if q.retention > 0 {
+		go q.prune(ctx)
+	}
+}
+
+// prune 定期删除超过保留时长的已完成任务，每批最多删除jobPruneBatch条，避免长时间锁表
+func (q *JobQueue) prune(ctx context.Context) {
+	ticker := time.NewTicker(jobPruneInterval)
+	defer ticker.Stop()
+	for {
+		before := time.Now().Add(-q.retention)
+		for {
+			deleted, err := q.jobDao.DeleteDone(ctx, before, jobPruneBatch)
+			if err != nil {
+				slog.ErrorContext(ctx, "清理已完成任务失败", "error", err)
+				break
+			}
+			if deleted < jobPruneBatch {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// work worker主循环：有任务时连续执行，没有任务时等待轮询间隔
+func (q *JobQueue) work(ctx context.Context, workerId string) {
+	for {
+		found, err := q.runNext(ctx, workerId)
+		if err != nil {
+			slog.ErrorContext(ctx, "抢占异步任务失败", "worker", workerId, "error", err)
+		}
+		if found && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(q.interval):
+		}
+	}
+}
+
+// runNext 抢占并执行一个任务，返回是否执行了任务
+func (q *JobQueue) runNext(ctx context.Context, workerId string) (bool, error) {
+	job, err := q.jobDao.ClaimNext(ctx, workerId, time.Now(), q.lockTimeout)
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	jobCtx := logger.WithRequestId(ctx, fmt.Sprintf("job-%d-%d", job.Id, job.Attempts))
+	start := time.Now()
+	outcome := q.execute(jobCtx, workerId, job)
+	metrics.ObserveJob(job.Type, outcome, time.Since(start))
+	return true, nil
+}
+
+// execute 执行任务并根据结果更新任务状态。执行超过抢占超时后任务可能已被其他worker重新抢占，
+// 此时不再更新任务状态，也不调用GiveUp，以重新抢占的worker的执行结果为准
+func (q *JobQueue) execute(ctx context.Context, workerId string, job *model.JobModel) string {
+	handler, ok := q.handlers[job.Type]
+	var err error
+	if !ok {
+		err = fmt.Errorf("未注册的任务类型: %s", job.Type)
+	} else {
+		err = q.safeHandle(ctx, handler, job)
+	}
+
+	if err == nil {
+		held, err := q.jobDao.Complete(ctx, job.Id, workerId)
+		if err != nil {
+			slog.ErrorContext(ctx, "更新任务状态失败", "job_id", job.Id, "error", err)
+		} else if !held {
+			return q.lockLost(ctx, job)
+		}
+		return jobOutcomeDone
+	}
+
+	lastError := truncateRunes(err.Error(), 500)
+	if ok && job.Attempts < job.MaxAttempts {
+		runAt := time.Now().Add(jobBackoff(job.Attempts))
+		slog.WarnContext(ctx, "异步任务执行失败，稍后重试", "job_id", job.Id, "type", job.Type,
+			"attempts", job.Attempts, "run_at", runAt, "error", err)
+		held, err := q.jobDao.Retry(ctx, job.Id, workerId, lastError, runAt)
+		if err != nil {
+			slog.ErrorContext(ctx, "更新任务状态失败", "job_id", job.Id, "error", err)
+		} else if !held {
+			return q.lockLost(ctx, job)
+		}
+		return jobOutcomeRetry
+	}
+
+	slog.ErrorContext(ctx, "异步任务超出重试次数，已放弃", "job_id", job.Id, "type", job.Type,
+		"attempts", job.Attempts, "error", err)
+	held, abandonErr := q.jobDao.Abandon(ctx, job.Id, workerId, lastError)
+	if abandonErr != nil {
+		slog.ErrorContext(ctx, "更新任务状态失败", "job_id", job.Id, "error", abandonErr)
+	} else if !held {
+		return q.lockLost(ctx, job)
+	}
+	if ok && handler.GiveUp != nil {
+		handler.GiveUp(ctx, []byte(job.Payload), err)
+	}
+	return jobOutcomeAbandoned
+}
+
+// lockLost 记录抢占已失效的任务
+func (q *JobQueue) lockLost(ctx context.Context, job *model.JobModel) string {
+	slog.WarnContext(ctx, "异步任务执行超过抢占超时，已被重新抢占，忽略本次结果", "job_id", job.Id, "type", job.Type,
+		"attempts", job.Attempts, "lock_timeout", q.lockTimeout)
+	return jobOutcomeLockLost
+}
+
+// safeHandle 执行任务处理器，处理器panic时按失败处理
+func (q *JobQueue) safeHandle(ctx context.Context, handler JobHandler, job *model.JobModel) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("任务处理异常: %v", r)
+		}
+	}()
+	return handler.Handle(ctx, []byte(job.Payload))
+}
+
+// jobBackoff 第attempts次执行失败后的重试等待时间：10s、20s、40s……最长30分钟
+func jobBackoff(attempts int) time.Duration {
+	backoff := jobBaseBackoff
+	for i := 1; i < attempts && backoff < jobMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > jobMaxBackoff {
+		backoff = jobMaxBackoff
+	}
+	return backoff
+}
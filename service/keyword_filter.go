@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"wxcloudrun-golang/db/dao"
+	"wxcloudrun-golang/db/model"
+)
+
+// defaultKeywordCacheTTL 关键词匹配器默认刷新间隔
+const defaultKeywordCacheTTL = time.Minute
+
+// KeywordMatch 关键词过滤结果
+type KeywordMatch struct {
+	Action string   // 最严格的处理：reject/review，未命中时为空
+	Words  []string // 命中的关键词
+}
+
+// Matched 是否命中关键词
+func (m *KeywordMatch) Matched() bool {
+	return m != nil && m.Action != ""
+}
+
+// keywordMatcher 由词库构建的匹配器，每个模式对应一个关键词
+type keywordMatcher struct {
+	automaton *ahoCorasick
+	keywords  []*model.BlockedKeywordModel // 与模式序号一一对应
+}
+
+// keywordCache 关键词匹配器缓存。后台修改词库后本实例立即重建，其他实例在 KEYWORD_CACHE_TTL 内重建
+type keywordCache struct {
+	mu       sync.RWMutex
+	ttl      time.Duration
+	loadedAt time.Time
+	matcher  *keywordMatcher
+}
+
+// sharedKeywordCache 进程内共享的关键词匹配器
+var sharedKeywordCache = &keywordCache{
+	ttl:     envDuration("KEYWORD_CACHE_TTL", defaultKeywordCacheTTL),
+	matcher: &keywordMatcher{automaton: newAhoCorasick(nil)},
+}
+
+// KeywordFilterService 本地关键词过滤：在调用微信内容安全接口前匹配词库，
+// 匹配前统一全角/半角、大小写并去除空白和符号，关键词的拼音、缩写等变体由词库的variants配置
+type KeywordFilterService struct {
+	keywordDao   dao.BlockedKeywordDao
+	auditService *AuditService
+	cache        *keywordCache
+}
+
+// NewKeywordFilterService 创建关键词过滤服务实例
+func NewKeywordFilterService() *KeywordFilterService {
+	return &KeywordFilterService{
+		keywordDao:   dao.NewBlockedKeywordDao(),
+		auditService: NewAuditService(),
+		cache:        sharedKeywordCache,
+	}
+}
+
+// Check 匹配文本中的关键词，返回最严格的处理
+func (s *KeywordFilterService) Check(ctx context.Context, text string) *KeywordMatch {
+	match := &KeywordMatch{}
+	normalized := normalizeKeywordText(text)
+	if normalized == "" {
+		return match
+	}
+
+	matcher := s.cache.get(ctx, s.keywordDao)
+	seen := make(map[int64]bool)
+	for _, i := range matcher.automaton.Match(normalized) {
+		keyword := matcher.keywords[i]
+		if seen[keyword.Id] {
+			continue
+		}
+		seen[keyword.Id] = true
+		match.Words = append(match.Words, keyword.Word)
+		if match.Action != model.KeywordActionReject {
+			match.Action = keyword.Action
+		}
+	}
+	return match
+}
+
+// KeywordRequest 添加/更新关键词请求
+type KeywordRequest struct {
+	Word     string   `json:"word"`
+	Variants []string `json:"variants"`
+	Action   string   `json:"action"`
+	Note     string   `json:"note"`
+}
+
+// KeywordListResponse 关键词列表响应
+type KeywordListResponse struct {
+	List       []*model.BlockedKeywordModel `json:"list"`
+	Pagination Pagination                   `json:"pagination"`
+}
+
+// GetKeywords 分页查询词库
+func (s *KeywordFilterService) GetKeywords(ctx context.Context, word string, page, pageSize int) (*KeywordListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	keywords, total, err := s.keywordDao.GetList(ctx, strings.TrimSpace(word), page, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("获取关键词列表失败: %v", err)
+	}
+	return &KeywordListResponse{
+		List: keywords,
+		Pagination: Pagination{
+			Current:  page,
+			PageSize: pageSize,
+			Total:    total,
+			HasMore:  int64(page*pageSize) < total,
+		},
+	}, nil
+}
+
+// CreateKeyword 添加关键词
+func (s *KeywordFilterService) CreateKeyword(ctx context.Context, operator *UserContext, req *KeywordRequest) (*model.BlockedKeywordModel, error) {
+	keyword := &model.BlockedKeywordModel{OperatorId: operator.User.Id}
+	if err := applyKeywordRequest(keyword, req); err != nil {
+		return nil, err
+	}
+	if err := s.keywordDao.Create(ctx, keyword); err != nil {
+		return nil, fmt.Errorf("添加关键词失败: %v", err)
+	}
+
+	s.auditService.Record(ctx, operator, model.AuditActionCreateKeyword, "keyword", keyword.Id, req)
+	s.cache.invalidate()
+	return keyword, nil
+}
+
+// UpdateKeyword 更新关键词
+func (s *KeywordFilterService) UpdateKeyword(ctx context.Context, operator *UserContext, id int64, req *KeywordRequest) (*model.BlockedKeywordModel, error) {
+	keyword, err := s.keywordDao.GetById(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("关键词不存在: %v", err)
+	}
+	if err := applyKeywordRequest(keyword, req); err != nil {
+		return nil, err
+	}
+	keyword.OperatorId = operator.User.Id
+	if err := s.keywordDao.Update(ctx, keyword); err != nil {
+		return nil, fmt.Errorf("更新关键词失败: %v", err)
+	}
+
+	s.auditService.Record(ctx, operator, model.AuditActionUpdateKeyword, "keyword", keyword.Id, req)
+	s.cache.invalidate()
+	return keyword, nil
+}
+
+// DeleteKeyword 删除关键词
+func (s *KeywordFilterService) DeleteKeyword(ctx context.Context, operator *UserContext, id int64) error {
+	keyword, err := s.keywordDao.GetById(ctx, id)
+	if err != nil {
+		return fmt.Errorf("关键词不存在: %v", err)
+	}
+	if err := s.keywordDao.Delete(ctx, id); err != nil {
+		return fmt.Errorf("删除关键词失败: %v", err)
+	}
+
+	s.auditService.Record(ctx, operator, model.AuditActionDeleteKeyword, "keyword", id, map[string]string{"word": keyword.Word})
+	s.cache.invalidate()
+	return nil
+}
+
+// applyKeywordRequest 校验请求并写入关键词
+func applyKeywordRequest(keyword *model.BlockedKeywordModel, req *KeywordRequest) error {
+	word := strings.TrimSpace(req.Word)
+	if normalizeKeywordText(word) == "" {
+		return fmt.Errorf("关键词不能为空")
+	}
+	if len([]rune(word)) > 100 {
+		return fmt.Errorf("关键词不能超过100个字符")
+	}
+	switch req.Action {
+	case "":
+		req.Action = model.KeywordActionReject
+	case model.KeywordActionReject, model.KeywordActionReview:
+	default:
+		return fmt.Errorf("处理方式无效: %s", req.Action)
+	}
+
+	var variants []string
+	for _, variant := range req.Variants {
+		variant = strings.TrimSpace(strings.ReplaceAll(variant, ",", ""))
+		if normalizeKeywordText(variant) != "" {
+			variants = append(variants, variant)
+		}
+	}
+	joined := strings.Join(variants, ",")
+	if len([]rune(joined)) > 500 {
+		return fmt.Errorf("变体总长度不能超过500个字符")
+	}
+
+	keyword.Word = word
+	keyword.Variants = joined
+	keyword.Action = req.Action
+	keyword.Note = truncateRunes(req.Note, 200)
+	return nil
+}
+
+// get 获取缓存的匹配器，过期时重新构建；加载失败时继续使用上一次构建的匹配器
+func (c *keywordCache) get(ctx context.Context, keywordDao dao.BlockedKeywordDao) *keywordMatcher {
+	c.mu.RLock()
+	matcher, fresh := c.matcher, !c.loadedAt.IsZero() && time.Since(c.loadedAt) < c.ttl
+	c.mu.RUnlock()
+	if fresh {
+		return matcher
+	}
+
+	keywords, err := keywordDao.GetAll(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadedAt = time.Now()
+	if err != nil {
+		slog.ErrorContext(ctx, "加载关键词词库失败，继续使用当前词库", "error", err)
+		return c.matcher
+	}
+	c.matcher = buildKeywordMatcher(keywords)
+	return c.matcher
+}
+
+// invalidate 使缓存失效，下次匹配时重新构建
+func (c *keywordCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadedAt = time.Time{}
+}
+
+// buildKeywordMatcher 将关键词及其变体规范化后构建自动机
+func buildKeywordMatcher(keywords []*model.BlockedKeywordModel) *keywordMatcher {
+	matcher := &keywordMatcher{}
+	var patterns []string
+	for _, keyword := range keywords {
+		forms := append([]string{keyword.Word}, strings.Split(keyword.Variants, ",")...)
+		for _, form := range forms {
+			if pattern := normalizeKeywordText(form); pattern != "" {
+				patterns = append(patterns, pattern)
+				matcher.keywords = append(matcher.keywords, keyword)
+			}
+		}
+	}
+	matcher.automaton = newAhoCorasick(patterns)
+	return matcher
+}
+
+// normalizeKeywordText 全角字符转半角、统一小写并去除空白和符号，避免插入符号或全角字符绕过匹配
+func normalizeKeywordText(text string) string {
+	var builder strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '　':
+			continue
+		case r >= '！' && r <= '～':
+			r -= 0xFEE0
+		}
+		r = unicode.ToLower(r)
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
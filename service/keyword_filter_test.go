@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"wxcloudrun-golang/db/model"
+)
+
+func TestNormalizeKeywordText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "全角字母和数字转半角并小写", text: "ＷＥＣＨＡＴ１２３", want: "wechat123"},
+		{name: "全角空格被去除", text: "加　微　信", want: "加微信"},
+		{name: "插入的符号和空白被去除", text: "加*微.信 v-x", want: "加微信vx"},
+		{name: "全角符号被去除", text: "赌！博？", want: "赌博"},
+		{name: "只有符号时为空", text: "!!! ...", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeKeywordText(tt.text); got != tt.want {
+				t.Errorf("normalizeKeywordText(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeywordFilterServiceCheck(t *testing.T) {
+	keywords := []*model.BlockedKeywordModel{
+		{Id: 1, Word: "赌博", Variants: "dubo,DB", Action: model.KeywordActionReject},
+		{Id: 2, Word: "加微信", Variants: "加vx", Action: model.KeywordActionReview},
+	}
+	service := &KeywordFilterService{
+		cache: &keywordCache{ttl: time.Hour, loadedAt: time.Now(), matcher: buildKeywordMatcher(keywords)},
+	}
+
+	tests := []struct {
+		name       string
+		text       string
+		wantAction string
+		wantWords  []string
+	}{
+		{name: "未命中", text: "今天天气不错", wantAction: "", wantWords: nil},
+		{name: "命中review关键词", text: "有事加微信", wantAction: model.KeywordActionReview, wantWords: []string{"加微信"}},
+		{name: "插入符号仍命中", text: "加 微-信", wantAction: model.KeywordActionReview, wantWords: []string{"加微信"}},
+		{name: "全角变体命中", text: "一起ＤＵＢＯ吗", wantAction: model.KeywordActionReject, wantWords: []string{"赌博"}},
+		{
+			name:       "同时命中时取最严格的处理",
+			text:       "加VX带你赌博",
+			wantAction: model.KeywordActionReject,
+			wantWords:  []string{"加微信", "赌博"},
+		},
+		{name: "同一关键词的多个变体只记一次", text: "赌博dubo", wantAction: model.KeywordActionReject, wantWords: []string{"赌博"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := service.Check(context.Background(), tt.text)
+			if match.Action != tt.wantAction || !reflect.DeepEqual(match.Words, tt.wantWords) {
+				t.Errorf("Check(%q) = %s/%v, want %s/%v", tt.text, match.Action, match.Words, tt.wantAction, tt.wantWords)
+			}
+			if match.Matched() != (tt.wantAction != "") {
+				t.Errorf("Matched() = %v", match.Matched())
+			}
+		})
+	}
+}
@@ -2,7 +2,7 @@ package service
 
 import (
 	"encoding/json"
-	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
@@ -67,10 +67,14 @@ func (h *LikeHandler) ToggleLikeHandler(w http.ResponseWriter, r *http.Request)
 	userId := userCtx.User.Id
 
 	// 调用服务
-	result, err := h.likeService.ToggleLike(postId, userId, &req)
+	result, err := h.likeService.ToggleLike(r.Context(), postId, userId, &req)
+	if IsAccountRestricted(err) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		// 记录错误信息
-		fmt.Printf("点赞操作失败: postId=%d, userId=%d, action=%s, error=%v\n", postId, userId, req.Action, err)
+		slog.ErrorContext(r.Context(), "点赞操作失败", "post_id", postId, "user_id", userId, "action", req.Action, "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"wxcloudrun-golang/db/dao"
 	"wxcloudrun-golang/db/model"
@@ -10,6 +11,7 @@ import (
 type LikeService struct {
 	userLikeDao dao.UserLikeDao
 	postDao     dao.PostDao
+	userDao     dao.UserDao
 }
 
 // NewLikeService 创建点赞服务实例
@@ -17,6 +19,7 @@ func NewLikeService() *LikeService {
 	return &LikeService{
 		userLikeDao: dao.NewUserLikeDao(),
 		postDao:     dao.NewPostDao(),
+		userDao:     dao.NewUserDao(),
 	}
 }
 
@@ -32,15 +35,24 @@ type LikeResponse struct {
 }
 
 // ToggleLike 切换点赞状态
-func (s *LikeService) ToggleLike(postId int64, userId int64, req *LikeRequest) (*LikeResponse, error) {
+func (s *LikeService) ToggleLike(ctx context.Context, postId int64, userId int64, req *LikeRequest) (*LikeResponse, error) {
+	// 被禁言的账号不能点赞
+	user, err := s.userDao.GetById(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("用户不存在: %v", err)
+	}
+	if err := checkAccountWritable(user); err != nil {
+		return nil, err
+	}
+
 	// 验证帖子是否存在
-	_, err := s.postDao.GetById(postId)
+	_, err = s.postDao.GetById(ctx, postId)
 	if err != nil {
 		return nil, fmt.Errorf("帖子不存在: %v", err)
 	}
 
 	// 检查当前点赞状态
-	isLiked, err := s.userLikeDao.IsLiked(userId, postId)
+	isLiked, err := s.userLikeDao.IsLiked(ctx, userId, postId)
 	if err != nil {
 		return nil, fmt.Errorf("检查点赞状态失败: %v", err)
 	}
@@ -53,13 +65,13 @@ func (s *LikeService) ToggleLike(postId int64, userId int64, req *LikeRequest) (
 				UserId: userId,
 				PostId: postId,
 			}
-			err = s.userLikeDao.Create(userLike)
+			err = s.userLikeDao.Create(ctx, userLike)
 			if err != nil {
 				return nil, fmt.Errorf("创建点赞记录失败: %v", err)
 			}
 
 			// 增加帖子点赞数
-			err = s.postDao.IncrementLikes(postId)
+			err = s.postDao.IncrementLikes(ctx, postId)
 			if err != nil {
 				return nil, fmt.Errorf("更新帖子点赞数失败: %v", err)
 			}
@@ -70,13 +82,13 @@ func (s *LikeService) ToggleLike(postId int64, userId int64, req *LikeRequest) (
 	case "unlike":
 		if isLiked {
 			// 删除点赞记录
-			err = s.userLikeDao.Delete(userId, postId)
+			err = s.userLikeDao.Delete(ctx, userId, postId)
 			if err != nil {
 				return nil, fmt.Errorf("删除点赞记录失败: %v", err)
 			}
 
 			// 减少帖子点赞数
-			err = s.postDao.DecrementLikes(postId)
+			err = s.postDao.DecrementLikes(ctx, postId)
 			if err != nil {
 				return nil, fmt.Errorf("更新帖子点赞数失败: %v", err)
 			}
@@ -89,7 +101,7 @@ func (s *LikeService) ToggleLike(postId int64, userId int64, req *LikeRequest) (
 	}
 
 	// 获取最新的点赞数
-	updatedPost, err := s.postDao.GetById(postId)
+	updatedPost, err := s.postDao.GetById(ctx, postId)
 	if err != nil {
 		return nil, fmt.Errorf("获取帖子信息失败: %v", err)
 	}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// VideoAttachment 帖子视频附件
+type VideoAttachment struct {
+	FileId   string `json:"fileId"`   // 云存储文件ID（cloud://）
+	Duration int    `json:"duration"` // 视频时长（秒）
+	Cover    string `json:"cover"`    // 封面图片（cloud:// 文件ID或URL），与帖子图片一样做图片检测
+	Width    int    `json:"width"`    // 视频宽度（像素）
+	Height   int    `json:"height"`   // 视频高度（像素）
+}
+
+// VideoDetail 帖子视频详情
+type VideoDetail struct {
+	FileId   string `json:"fileId"`
+	URL      string `json:"url,omitempty"` // 临时播放地址，帖子详情或请求带 resolveUrls=true 时返回
+	Duration int    `json:"duration"`
+	Cover    string `json:"cover"`
+	CoverURL string `json:"coverUrl,omitempty"` // 封面的临时访问地址，请求带 resolveUrls=true 时返回
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+}
+
+// validateAudio 校验语音附件：地址不超过500个字符，时长在1秒到 AUDIO_MAX_DURATION（默认60秒）之间。
+// 没有语音时时长必须为0
+func validateAudio(audio string, duration int) error {
+	if audio == "" {
+		if duration != 0 {
+			return errors.New("缺少语音文件")
+		}
+		return nil
+	}
+	if utf8.RuneCountInString(audio) > 500 {
+		return errors.New("语音地址过长")
+	}
+	maxDuration := envInt("AUDIO_MAX_DURATION", 60)
+	if duration < 1 || duration > maxDuration {
+		return fmt.Errorf("语音时长需在1到%d秒之间", maxDuration)
+	}
+	return nil
+}
+
+// validateVideo 校验视频附件：视频须为云存储文件ID，必须带封面，时长在1秒到 VIDEO_MAX_DURATION（默认300秒）之间
+func validateVideo(video *VideoAttachment, cloudStorage *WechatCloudStorageService) error {
+	if video == nil {
+		return nil
+	}
+	if !cloudStorage.ValidateCloudID(video.FileId) || utf8.RuneCountInString(video.FileId) > 500 {
+		return errors.New("视频文件ID无效")
+	}
+	if video.Cover == "" {
+		return errors.New("视频缺少封面")
+	}
+	if utf8.RuneCountInString(video.Cover) > 500 {
+		return errors.New("视频封面地址过长")
+	}
+	maxDuration := envInt("VIDEO_MAX_DURATION", 300)
+	if video.Duration < 1 || video.Duration > maxDuration {
+		return fmt.Errorf("视频时长需在1到%d秒之间", maxDuration)
+	}
+	if video.Width < 0 || video.Height < 0 {
+		return errors.New("视频尺寸无效")
+	}
+	return nil
+}
@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+	"wxcloudrun-golang/db/dao"
+	"wxcloudrun-golang/db/model"
+)
+
+// JobTypeMediaCheckSubmit 提交图片或语音到media_check_async的任务类型
+const JobTypeMediaCheckSubmit = "image_check.submit"
+
+// mediaCheckJobPayload 媒体检测提交任务参数
+type mediaCheckJobPayload struct {
+	MediaCheckId int64  `json:"mediaCheckId"`
+	Openid       string `json:"openid"` // 发帖时请求头中的openid，为空时使用作者的openid
+}
+
+// MediaCheckJobs 媒体检测异步任务：发帖时只写入待检测记录和任务，由worker提交检测，
+// 单个图片或语音提交失败时单独重试，不影响帖子和其他媒体
+type MediaCheckJobs struct {
+	mediaCheckDao     dao.MediaCheckDao
+	postDao           dao.PostDao
+	commentDao        dao.CommentDao
+	userDao           dao.UserDao
+	securityService   *ContentSecurityService
+	moderationService *ModerationService
+	profileModeration *ProfileModerationService
+	videoModeration   *VideoModerationService
+	contentChecks     *ContentCheckService
+}
+
+// NewMediaCheckJobs 创建媒体检测异步任务处理器
+func NewMediaCheckJobs() *MediaCheckJobs {
+	return &MediaCheckJobs{
+		mediaCheckDao:     dao.NewMediaCheckDao(),
+		postDao:           dao.NewPostDao(),
+		commentDao:        dao.NewCommentDao(),
+		userDao:           dao.NewUserDao(),
+		securityService:   NewContentSecurityService(),
+		moderationService: NewModerationService(),
+		profileModeration: NewProfileModerationService(),
+		videoModeration:   NewVideoModerationService(),
+		contentChecks:     NewContentCheckService(),
+	}
+}
+
+// mediaCheckTarget 媒体检测记录所属的对象：帖子媒体为帖子及其作者，语音评论为评论及其作者，头像为用户本人
+type mediaCheckTarget struct {
+	targetType string           // 检测记录的对象类型
+	targetId   int64            // 检测记录的对象ID
+	postId     int64            // 所属帖子ID，头像为0
+	owner      *model.UserModel // 帖子或评论作者，头像所属用户
+	field      string           // 检测记录的字段名
+	scene      int              // 内容安全检测场景
+}
+
+// loadMediaCheckTarget 获取媒体检测记录所属的对象
+func loadMediaCheckTarget(ctx context.Context, postDao dao.PostDao, commentDao dao.CommentDao, userDao dao.UserDao, check *model.MediaCheckModel) (*mediaCheckTarget, error) {
+	if check.UserId != 0 {
+		user, err := userDao.GetById(ctx, check.UserId)
+		if err != nil {
+			return nil, fmt.Errorf("获取用户失败: %v", err)
+		}
+		return &mediaCheckTarget{
+			targetType: model.ContentCheckTargetUser,
+			targetId:   user.Id,
+			owner:      user,
+			field:      model.ProfileFieldAvatar,
+			scene:      SceneProfile,
+		}, nil
+	}
+
+	if check.CommentId != 0 {
+		comment, err := commentDao.GetById(ctx, check.CommentId)
+		if err != nil {
+			return nil, fmt.Errorf("获取评论失败: %v", err)
+		}
+		author, err := userDao.GetById(ctx, comment.AuthorId)
+		if err != nil {
+			return nil, fmt.Errorf("获取作者失败: %v", err)
+		}
+		return &mediaCheckTarget{
+			targetType: model.ContentCheckTargetComment,
+			targetId:   comment.Id,
+			postId:     comment.PostId,
+			owner:      author,
+			field:      mediaCheckKind(check),
+			scene:      SceneComment,
+		}, nil
+	}
+
+	post, err := postDao.GetById(ctx, check.PostId)
+	if err != nil {
+		return nil, fmt.Errorf("获取帖子失败: %v", err)
+	}
+	author, err := userDao.GetById(ctx, post.AuthorId)
+	if err != nil {
+		return nil, fmt.Errorf("获取作者失败: %v", err)
+	}
+	return &mediaCheckTarget{
+		targetType: model.ContentCheckTargetPost,
+		targetId:   post.Id,
+		postId:     post.Id,
+		owner:      author,
+		field:      mediaCheckKind(check),
+		scene:      SceneForum,
+	}, nil
+}
+
+// mediaCheckKind 检测记录的媒体类型，用于检测日志和审核策略
+func mediaCheckKind(check *model.MediaCheckModel) string {
+	switch check.MediaType {
+	case model.MediaTypeAudio:
+		return model.ContentCheckKindAudio
+	case model.MediaTypeVideo:
+		return model.ContentCheckKindVideo
+	default:
+		return model.ContentCheckKindImage
+	}
+}
+
+// mediaModerationSource 媒体检测建议人工审核时审核队列的来源
+func mediaModerationSource(check *model.MediaCheckModel) string {
+	switch check.MediaType {
+	case model.MediaTypeAudio:
+		return model.ModerationSourceAudio
+	case model.MediaTypeVideo:
+		return model.ModerationSourceVideo
+	default:
+		return model.ModerationSourceImage
+	}
+}
+
+// mediaName 检测记录媒体类型的中文名称
+func mediaName(check *model.MediaCheckModel) string {
+	switch check.MediaType {
+	case model.MediaTypeAudio:
+		return "语音"
+	case model.MediaTypeVideo:
+		return "视频"
+	default:
+		return "图片"
+	}
+}
+
+// Register 将媒体检测任务注册到任务队列
+func (j *MediaCheckJobs) Register(queue *JobQueue) {
+	queue.Register(JobTypeMediaCheckSubmit, JobHandler{
+		Handle: j.handleSubmit,
+		GiveUp: j.giveUpSubmit,
+	})
+}
+
+// newMediaCheckJob 构造媒体检测提交任务
+func newMediaCheckJob(check *model.MediaCheckModel, openid string) (*model.JobModel, error) {
+	return newJob(JobTypeMediaCheckSubmit, mediaCheckJobPayload{MediaCheckId: check.Id, Openid: openid}, 0)
+}
+
+// handleSubmit 提交一个图片或语音的异步检测，视频提交给视频检测器
+func (j *MediaCheckJobs) handleSubmit(ctx context.Context, payload []byte) error {
+	var p mediaCheckJobPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("解析任务参数失败: %v", err)
+	}
+
+	check, err := j.mediaCheckDao.GetById(ctx, p.MediaCheckId)
+	if err != nil {
+		return fmt.Errorf("获取媒体检测记录失败: %v", err)
+	}
+	if check.Status != model.MediaCheckStatusPending {
+		// 已提交（例如超时处理任务已接管），任务幂等完成
+		return nil
+	}
+
+	target, err := loadMediaCheckTarget(ctx, j.postDao, j.commentDao, j.userDao, check)
+	if err != nil {
+		return err
+	}
+	openid := p.Openid
+	if openid == "" {
+		openid = target.owner.OpenId
+	}
+
+	checkLog := NewContentCheckLog(target.targetType, target.owner.Id)
+	defer j.contentChecks.Save(ctx, checkLog, target.targetId, target.postId)
+
+	// 视频由本地视频检测器同步检测，提交后直接处理结论
+	if check.MediaType == model.MediaTypeVideo {
+		return j.videoModeration.CheckVideo(ctx, check, checkLog, func(traceId string) (bool, error) {
+			return j.mediaCheckDao.MarkSubmitted(ctx, check.Id, traceId)
+		})
+	}
+
+	start := time.Now()
+	result, err := submitMediaCheck(ctx, j.securityService, check, openid, target.scene)
+	checkLog.AddMedia(mediaCheckKind(check), target.field, target.scene, check.MediaURL, result, err, time.Since(start))
+	if err != nil {
+		return err
+	}
+
+	submitted, err := j.mediaCheckDao.MarkSubmitted(ctx, check.Id, result.TraceId)
+	if err != nil {
+		return fmt.Errorf("记录媒体检测提交结果失败: %v", err)
+	}
+	if !submitted {
+		slog.WarnContext(ctx, "媒体检测记录已被其他任务提交", "check_id", check.Id, "trace_id", result.TraceId)
+		return nil
+	}
+	slog.InfoContext(ctx, "媒体检测请求已提交", "check_id", check.Id, "media_type", check.MediaType, "post_id", check.PostId,
+		"comment_id", check.CommentId, "user_id", check.UserId, "trace_id", result.TraceId)
+	return nil
+}
+
+// giveUpSubmit 多次提交失败后，将媒体转人工审核，避免帖子或评论一直处于检测中
+func (j *MediaCheckJobs) giveUpSubmit(ctx context.Context, payload []byte, cause error) {
+	var p mediaCheckJobPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return
+	}
+	check, err := j.mediaCheckDao.GetById(ctx, p.MediaCheckId)
+	if err != nil {
+		slog.ErrorContext(ctx, "获取媒体检测记录失败", "check_id", p.MediaCheckId, "error", err)
+		return
+	}
+	_, err = expireMediaCheck(ctx, j.mediaCheckDao, j.postDao, j.moderationService, j.profileModeration, check,
+		model.MediaCheckStatusReview, mediaName(check)+"检测提交失败: "+truncateRunes(cause.Error(), 150))
+	if err != nil {
+		slog.ErrorContext(ctx, "处理提交失败的媒体检测失败", "check_id", check.Id, "error", err)
+	}
+}
+
+// submitMediaCheck 按媒体类型提交图片或语音异步检测，云存储文件ID先换取下载地址；接口返回错误码时返回错误
+func submitMediaCheck(ctx context.Context, securityService *ContentSecurityService, check *model.MediaCheckModel, openid string, scene int) (*MediaCheckResponse, error) {
+	var result *MediaCheckResponse
+	var err error
+	cloudFile := securityService.cloudStorage.ValidateCloudID(check.MediaURL)
+	switch {
+	case check.MediaType == model.MediaTypeAudio && cloudFile:
+		result, err = securityService.CheckCloudStorageAudioSecurity(ctx, check.MediaURL, openid, scene)
+	case check.MediaType == model.MediaTypeAudio:
+		result, err = securityService.CheckAudioSecurity(ctx, check.MediaURL, openid, scene)
+	case cloudFile:
+		result, err = securityService.CheckCloudStorageImageSecurity(ctx, check.MediaURL, openid, scene)
+	default:
+		result, err = securityService.CheckImageSecurity(ctx, check.MediaURL, openid, scene)
+	}
+	if err != nil {
+		return result, fmt.Errorf("%s安全检测失败: %v", mediaName(check), err)
+	}
+	if !securityService.IsMediaCheckSuccess(result) {
+		return result, fmt.Errorf("%s安全检测请求失败: %s", mediaName(check), securityService.GetMediaCheckError(result))
+	}
+	return result, nil
+}
+
+// expireMediaCheck 将仍未得到结果的媒体检测判定为失败或转人工审核，并同步帖子的媒体检测状态（头像同步用户资料，语音评论同步评论状态）。
+// 返回false表示记录已收到检测结果，无需处理
+func expireMediaCheck(ctx context.Context, mediaCheckDao dao.MediaCheckDao, postDao dao.PostDao, moderationService *ModerationService,
+	profileModeration *ProfileModerationService, check *model.MediaCheckModel, status int, errmsg string) (bool, error) {
+	expired, err := mediaCheckDao.ExpirePending(ctx, check.Id, status, errmsg)
+	if err != nil {
+		return false, fmt.Errorf("更新检测状态失败: %v", err)
+	}
+	if !expired {
+		return false, nil
+	}
+
+	if check.UserId != 0 || check.CommentId != 0 {
+		action, reason := PolicyActionReject, errmsg
+		if status == model.MediaCheckStatusReview {
+			action, reason = PolicyActionReview, errmsg+"，转人工审核"
+		}
+		if check.CommentId != 0 {
+			return true, moderationService.SettleCommentMedia(ctx, check, action, reason, "", 0)
+		}
+		return true, profileModeration.SettleAvatarCheck(ctx, check, action, reason, "", 0)
+	}
+
+	if status == model.MediaCheckStatusReview {
+		post, err := postDao.GetById(ctx, check.PostId)
+		if err != nil {
+			return true, fmt.Errorf("获取帖子失败: %v", err)
+		}
+		err = moderationService.Enqueue(ctx, &model.ModerationQueueModel{
+			TargetType:   model.ModerationTargetPost,
+			TargetId:     post.Id,
+			PostId:       post.Id,
+			AuthorId:     post.AuthorId,
+			Source:       mediaModerationSource(check),
+			Content:      check.MediaURL,
+			Reason:       errmsg + "，转人工审核",
+			TraceId:      check.TraceId,
+			MediaCheckId: check.Id,
+		})
+		if err != nil {
+			return true, err
+		}
+	}
+
+	if err := syncPostMediaCheckStatus(ctx, postDao, check.PostId); err != nil {
+		return true, fmt.Errorf("同步帖子媒体检测状态失败: %v", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+	"wxcloudrun-golang/db/dao"
+	"wxcloudrun-golang/db/model"
+	"wxcloudrun-golang/metrics"
+)
+
+// 超时图片检测的处理结果，用于监控指标
+const (
+	sweepOutcomeResubmitted = "resubmitted"
+	sweepOutcomeFailed      = "failed"
+	sweepOutcomeReview      = "review"
+	sweepOutcomeError       = "error"
+)
+
+// mediaCheckSweepBatch 每轮最多处理的超时记录数
+const mediaCheckSweepBatch = 50
+
+// MediaCheckSweeper 超时媒体检测处理任务：微信未推送media_check_async回调时，帖子会一直停留在检测中状态而无法展示。
+// 任务定期扫描超过期限仍未收到结果的记录，在重试次数内重新提交检测，超出次数后按配置判定为失败或转人工审核
+type MediaCheckSweeper struct {
+	mediaCheckDao     dao.MediaCheckDao
+	postDao           dao.PostDao
+	commentDao        dao.CommentDao
+	userDao           dao.UserDao
+	securityService   *ContentSecurityService
+	moderationService *ModerationService
+	profileModeration *ProfileModerationService
+	videoModeration   *VideoModerationService
+	contentChecks     *ContentCheckService
+
+	interval    time.Duration // 扫描间隔
+	timeout     time.Duration // 提交后等待回调的期限
+	maxAttempts int           // 最多提交次数（含首次）
+	failAction  int           // 超出重试次数后的检测状态：检测失败或待人工审核
+}
+
+// NewMediaCheckSweeper 创建超时图片检测处理任务。可通过环境变量配置：
+// IMAGE_CHECK_SWEEP_INTERVAL（扫描间隔，默认1m，0为关闭）、IMAGE_CHECK_TIMEOUT（等待回调期限，默认10m）、
+// IMAGE_CHECK_MAX_ATTEMPTS（最多提交次数，默认3）、IMAGE_CHECK_TIMEOUT_ACTION（review转人工审核或fail判定失败，默认review）
+func NewMediaCheckSweeper() *MediaCheckSweeper {
+	failAction := model.MediaCheckStatusReview
+	if os.Getenv("IMAGE_CHECK_TIMEOUT_ACTION") == "fail" {
+		failAction = model.MediaCheckStatusFailed
+	}
+	maxAttempts := envInt("IMAGE_CHECK_MAX_ATTEMPTS", 3)
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return &MediaCheckSweeper{
+		mediaCheckDao:     dao.NewMediaCheckDao(),
+		postDao:           dao.NewPostDao(),
+		commentDao:        dao.NewCommentDao(),
+		userDao:           dao.NewUserDao(),
+		securityService:   NewContentSecurityService(),
+		moderationService: NewModerationService(),
+		profileModeration: NewProfileModerationService(),
+		videoModeration:   NewVideoModerationService(),
+		contentChecks:     NewContentCheckService(),
+		interval:          envDuration("IMAGE_CHECK_SWEEP_INTERVAL", time.Minute),
+		timeout:           envDuration("IMAGE_CHECK_TIMEOUT", 10*time.Minute),
+		maxAttempts:       maxAttempts,
+		failAction:        failAction,
+	}
+}
+
+// Start 在后台定期执行扫描，ctx取消后停止
+func (s *MediaCheckSweeper) Start(ctx context.Context) {
+	if s.interval <= 0 {
+		slog.Info("图片检测超时处理任务已关闭")
+		return
+	}
+	slog.Info("图片检测超时处理任务已启动", "interval", s.interval, "timeout", s.timeout, "max_attempts", s.maxAttempts)
+
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Sweep(ctx); err != nil {
+					slog.ErrorContext(ctx, "图片检测超时处理失败", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Sweep 执行一轮扫描
+func (s *MediaCheckSweeper) Sweep(ctx context.Context) error {
+	checks, err := s.mediaCheckDao.GetOverdueChecks(ctx, time.Now().Add(-s.timeout), mediaCheckSweepBatch)
+	if err != nil {
+		return fmt.Errorf("获取超时图片检测记录失败: %v", err)
+	}
+	if len(checks) == 0 {
+		return nil
+	}
+	slog.InfoContext(ctx, "发现超时未收到结果的图片检测", "count", len(checks))
+
+	for _, check := range checks {
+		outcome, err := s.handle(ctx, check)
+		if err != nil {
+			outcome = sweepOutcomeError
+			slog.ErrorContext(ctx, "处理超时图片检测失败", "check_id", check.Id, "post_id", check.PostId,
+				"trace_id", check.TraceId, "attempts", check.Attempts, "error", err)
+		}
+		if outcome != "" {
+			metrics.ObserveImageCheckSweep(outcome)
+		}
+	}
+	return nil
+}
+
+// handle 处理单条超时记录，返回处理结果，记录已被其他实例处理时返回空
+func (s *MediaCheckSweeper) handle(ctx context.Context, check *model.MediaCheckModel) (string, error) {
+	attempts := check.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	if attempts >= s.maxAttempts {
+		return s.expire(ctx, check)
+	}
+
+	target, err := loadMediaCheckTarget(ctx, s.postDao, s.commentDao, s.userDao, check)
+	if err != nil {
+		return "", err
+	}
+
+	// 先抢占再提交，避免多实例重复提交
+	claimed, err := s.mediaCheckDao.ClaimRetry(ctx, check.Id, check.Attempts)
+	if err != nil {
+		return "", fmt.Errorf("抢占重新提交失败: %v", err)
+	}
+	if !claimed {
+		return "", nil
+	}
+
+	checkLog := NewContentCheckLog(target.targetType, target.owner.Id)
+	defer s.contentChecks.Save(ctx, checkLog, target.targetId, target.postId)
+
+	if check.MediaType == model.MediaTypeVideo {
+		err := s.videoModeration.CheckVideo(ctx, check, checkLog, func(traceId string) (bool, error) {
+			return true, s.mediaCheckDao.UpdateTraceId(ctx, check.Id, traceId)
+		})
+		if err != nil {
+			return "", err
+		}
+		return sweepOutcomeResubmitted, nil
+	}
+
+	start := time.Now()
+	result, err := submitMediaCheck(ctx, s.securityService, check, target.owner.OpenId, target.scene)
+	checkLog.AddMedia(mediaCheckKind(check), target.field, target.scene, check.MediaURL, result, err, time.Since(start))
+	if err != nil {
+		// 提交失败同样消耗一次重试次数，下一轮继续处理
+		return "", err
+	}
+
+	if err := s.mediaCheckDao.UpdateTraceId(ctx, check.Id, result.TraceId); err != nil {
+		return "", fmt.Errorf("更新trace_id失败: %v", err)
+	}
+	slog.InfoContext(ctx, "媒体检测已重新提交", "check_id", check.Id, "media_type", check.MediaType, "post_id", check.PostId,
+		"old_trace_id", check.TraceId, "trace_id", result.TraceId, "attempts", attempts+1)
+	return sweepOutcomeResubmitted, nil
+}
+
+// expire 超出重试次数后判定为失败或转人工审核，并同步帖子的图片检测状态
+func (s *MediaCheckSweeper) expire(ctx context.Context, check *model.MediaCheckModel) (string, error) {
+	errmsg := fmt.Sprintf("%s检测提交%d次均未收到结果", mediaName(check), s.maxAttempts)
+	expired, err := expireMediaCheck(ctx, s.mediaCheckDao, s.postDao, s.moderationService, s.profileModeration, check, s.failAction, errmsg)
+	if err != nil {
+		return "", err
+	}
+	if !expired {
+		return "", nil
+	}
+
+	outcome := sweepOutcomeFailed
+	if s.failAction == model.MediaCheckStatusReview {
+		outcome = sweepOutcomeReview
+	}
+	slog.WarnContext(ctx, "媒体检测超时", "check_id", check.Id, "media_type", check.MediaType, "post_id", check.PostId,
+		"trace_id", check.TraceId, "attempts", check.Attempts, "outcome", outcome)
+	return outcome, nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// MediaHandler 媒体上传处理器
+type MediaHandler struct {
+	mediaService *MediaService
+}
+
+// NewMediaHandler 创建媒体上传处理器实例
+func NewMediaHandler() *MediaHandler {
+	return &MediaHandler{
+		mediaService: NewMediaService(),
+	}
+}
+
+// UploadHandler 上传图片处理器
+// POST /api/media  multipart/form-data，文件字段名为 file
+func (h *MediaHandler) UploadHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userCtx := GetUserFromContext(r)
+	if userCtx == nil || userCtx.User == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// 请求体上限为文件上限加上表单字段的余量，文件本身的大小在读取时单独限制
+	maxSize := h.mediaService.MaxSize()
+	r.Body = http.MaxBytesReader(w, r.Body, int64(maxSize)+1<<20)
+	data, err := readMultipartFile(r, "file", maxSize)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.Is(err, ErrMediaTooLarge) || errors.As(err, &maxBytesErr) {
+			http.Error(w, ErrMediaTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.mediaService.UploadImage(r.Context(), userCtx.User, data)
+	if err != nil {
+		switch {
+		case IsAccountRestricted(err):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, ErrMediaTooLarge):
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		case errors.Is(err, ErrMediaTypeUnsupported):
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		case errors.Is(err, ErrMediaInvalid):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"code":    200,
+		"message": "上传成功",
+		"data":    result,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// readMultipartFile 流式读取表单中指定字段的文件内容，超过maxSize时返回 ErrMediaTooLarge
+func readMultipartFile(r *http.Request, field string, maxSize int) ([]byte, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			// 读完所有字段仍未找到文件时 err 为 io.EOF
+			return nil, err
+		}
+		if part.FormName() != field {
+			part.Close()
+			continue
+		}
+		defer part.Close()
+		data, err := io.ReadAll(io.LimitReader(part, int64(maxSize)+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > maxSize {
+			return nil, ErrMediaTooLarge
+		}
+		return data, nil
+	}
+}
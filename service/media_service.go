@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+	"wxcloudrun-golang/db/model"
+)
+
+// 媒体上传错误，处理器据此返回4xx
+var (
+	ErrMediaTooLarge        = errors.New("文件过大")
+	ErrMediaTypeUnsupported = errors.New("不支持的文件类型，仅支持JPEG、PNG、GIF、WebP图片")
+	ErrMediaInvalid         = errors.New("图片文件已损坏或格式无效")
+)
+
+// defaultMediaUploadMaxSize 默认上传大小上限，可通过 MEDIA_UPLOAD_MAX_SIZE（字节）覆盖
+const defaultMediaUploadMaxSize = 10 << 20
+
+// mediaUploadTypes 允许上传的文件类型及对应扩展名，类型由文件内容识别，不信任客户端声明
+var mediaUploadTypes = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+// FileStorage 文件存储后端
+type FileStorage interface {
+	// Upload 上传文件到path，返回文件ID
+	Upload(ctx context.Context, path string, data []byte) (string, error)
+	// Delete 删除文件
+	Delete(ctx context.Context, fileIds []string) error
+}
+
+// newFileStorage 按 MEDIA_STORAGE 创建文件存储，fake 时使用内存存储（用于开发和测试），默认为微信云存储
+func newFileStorage() FileStorage {
+	switch os.Getenv("MEDIA_STORAGE") {
+	case "fake":
+		return NewFakeFileStorage()
+	default:
+		return &cloudFileStorage{cloudStorage: NewWechatCloudStorageService()}
+	}
+}
+
+// cloudFileStorage 微信云存储后端
+type cloudFileStorage struct {
+	cloudStorage *WechatCloudStorageService
+}
+
+// Upload 上传文件到云存储
+func (s *cloudFileStorage) Upload(ctx context.Context, path string, data []byte) (string, error) {
+	return s.cloudStorage.UploadFile(ctx, path, data)
+}
+
+// Delete 删除云存储文件
+func (s *cloudFileStorage) Delete(ctx context.Context, fileIds []string) error {
+	return s.cloudStorage.DeleteFiles(ctx, fileIds)
+}
+
+// FakeFileStorage 内存文件存储，返回 cloud://fake/ 开头的文件ID，用于开发和测试
+type FakeFileStorage struct {
+	mu    sync.RWMutex
+	files map[string][]byte
+}
+
+// NewFakeFileStorage 创建内存文件存储
+func NewFakeFileStorage() *FakeFileStorage {
+	return &FakeFileStorage{files: make(map[string][]byte)}
+}
+
+// Upload 保存文件内容
+func (s *FakeFileStorage) Upload(ctx context.Context, path string, data []byte) (string, error) {
+	fileId := "cloud://fake/" + path
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[fileId] = append([]byte(nil), data...)
+	return fileId, nil
+}
+
+// Delete 删除文件内容，不存在的文件忽略
+func (s *FakeFileStorage) Delete(ctx context.Context, fileIds []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, fileId := range fileIds {
+		delete(s.files, fileId)
+	}
+	return nil
+}
+
+// Get 获取已上传的文件内容
+func (s *FakeFileStorage) Get(fileId string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.files[fileId]
+	return data, ok
+}
+
+// MediaUploadResult 媒体上传结果
+type MediaUploadResult struct {
+	FileId      string `json:"fileId"`
+	ContentType string `json:"contentType"`
+	Size        int    `json:"size"`
+}
+
+// MediaService 服务端媒体上传：供无法使用小程序SDK直传的网页端和后台工具上传图片，
+// 上传前校验类型和大小并去除EXIF等元数据，返回的文件ID可直接用于发帖
+type MediaService struct {
+	storage FileStorage
+	maxSize int
+}
+
+// NewMediaService 创建媒体上传服务实例
+func NewMediaService() *MediaService {
+	return NewMediaServiceWithStorage(newFileStorage())
+}
+
+// NewMediaServiceWithStorage 使用指定的文件存储创建媒体上传服务实例
+func NewMediaServiceWithStorage(storage FileStorage) *MediaService {
+	return &MediaService{
+		storage: storage,
+		maxSize: envInt("MEDIA_UPLOAD_MAX_SIZE", defaultMediaUploadMaxSize),
+	}
+}
+
+// MaxSize 返回上传大小上限（字节）
+func (s *MediaService) MaxSize() int {
+	return s.maxSize
+}
+
+// UploadImage 校验并上传图片，返回云存储文件ID
+func (s *MediaService) UploadImage(ctx context.Context, user *model.UserModel, data []byte) (*MediaUploadResult, error) {
+	if err := checkAccountWritable(user); err != nil {
+		return nil, err
+	}
+	if len(data) > s.maxSize {
+		return nil, ErrMediaTooLarge
+	}
+	if len(data) == 0 {
+		return nil, ErrMediaInvalid
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := mediaUploadTypes[contentType]
+	if !ok {
+		return nil, ErrMediaTypeUnsupported
+	}
+
+	stripped, err := stripImageMetadata(contentType, data)
+	if err != nil {
+		slog.WarnContext(ctx, "图片元数据处理失败", "user_id", user.Id, "content_type", contentType, "error", err)
+		return nil, ErrMediaInvalid
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("生成文件名失败: %v", err)
+	}
+	path := fmt.Sprintf("uploads/%s/%d-%s.%s", time.Now().Format("2006/01/02"), user.Id, hex.EncodeToString(suffix), ext)
+
+	fileId, err := s.storage.Upload(ctx, path, stripped)
+	if err != nil {
+		return nil, fmt.Errorf("上传文件失败: %v", err)
+	}
+
+	slog.InfoContext(ctx, "媒体上传成功", "user_id", user.Id, "file_id", fileId, "content_type", contentType,
+		"size", len(stripped), "original_size", len(data))
+	return &MediaUploadResult{FileId: fileId, ContentType: contentType, Size: len(stripped)}, nil
+}
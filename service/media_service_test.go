@@ -0,0 +1,218 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"wxcloudrun-golang/db/model"
+)
+
+// testJPEGWithExif 生成带EXIF的JPEG
+func testJPEGWithExif(t *testing.T) []byte {
+	t.Helper()
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, testImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+	raw := encoded.Bytes()
+	return append(append(append([]byte(nil), raw[:2]...), jpegSegment(0xE1, testExifPayload(6))...), raw[2:]...)
+}
+
+func TestMediaServiceUploadImage(t *testing.T) {
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	jpegData := testJPEGWithExif(t)
+	muted := &model.UserModel{Id: 2, Status: model.UserStatusMuted}
+
+	tests := []struct {
+		name            string
+		maxSize         int
+		user            *model.UserModel
+		data            []byte
+		wantErr         error
+		wantContentType string
+	}{
+		{"JPEG", 1 << 20, &model.UserModel{Id: 1}, jpegData, nil, "image/jpeg"},
+		{"PNG", 1 << 20, &model.UserModel{Id: 1}, pngData.Bytes(), nil, "image/png"},
+		{"GIF", 1 << 20, &model.UserModel{Id: 1}, []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;"), nil, "image/gif"},
+		{"WebP", 1 << 20, &model.UserModel{Id: 1}, webpFile(webpChunk("VP8L", []byte("pixels"))), nil, "image/webp"},
+		{"超过大小上限", len(jpegData) - 1, &model.UserModel{Id: 1}, jpegData, ErrMediaTooLarge, ""},
+		{"空文件", 1 << 20, &model.UserModel{Id: 1}, nil, ErrMediaInvalid, ""},
+		{"文本文件", 1 << 20, &model.UserModel{Id: 1}, []byte("hello world"), ErrMediaTypeUnsupported, ""},
+		{"PDF", 1 << 20, &model.UserModel{Id: 1}, []byte("%PDF-1.4\n"), ErrMediaTypeUnsupported, ""},
+		{"SVG", 1 << 20, &model.UserModel{Id: 1}, []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), ErrMediaTypeUnsupported, ""},
+		{"JPEG结构损坏", 1 << 20, &model.UserModel{Id: 1}, []byte("\xFF\xD8\xFF\xE1\x7F\x00Exif"), ErrMediaInvalid, ""},
+		{"禁言账号", 1 << 20, muted, jpegData, ErrAccountMuted, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := NewFakeFileStorage()
+			service := NewMediaServiceWithStorage(storage)
+			service.maxSize = tt.maxSize
+
+			result, err := service.UploadImage(context.Background(), tt.user, tt.data)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("UploadImage() error = %v, want %v", err, tt.wantErr)
+				}
+				if len(storage.files) != 0 {
+					t.Error("校验失败时不应上传文件")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UploadImage() error = %v", err)
+			}
+
+			if result.ContentType != tt.wantContentType {
+				t.Errorf("ContentType = %q, want %q", result.ContentType, tt.wantContentType)
+			}
+			if !strings.HasPrefix(result.FileId, "cloud://fake/uploads/") {
+				t.Errorf("FileId = %q, want cloud://fake/uploads/ 前缀", result.FileId)
+			}
+			stored, ok := storage.Get(result.FileId)
+			if !ok {
+				t.Fatal("文件未写入存储")
+			}
+			if len(stored) != result.Size {
+				t.Errorf("Size = %d, 存储的文件大小 %d", result.Size, len(stored))
+			}
+			if bytes.Contains(stored, []byte("GPS-SECRET")) {
+				t.Error("上传的文件仍包含EXIF")
+			}
+		})
+	}
+}
+
+// newMediaUploadRequest 构造带用户上下文的上传请求
+func newMediaUploadRequest(t *testing.T, user *model.UserModel, data []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("note", "test"); err != nil {
+		t.Fatal(err)
+	}
+	part, err := writer.CreateFormFile("file", "photo.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/media", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req.WithContext(context.WithValue(req.Context(), "user", &UserContext{User: user, IP: "10.0.0.1"}))
+}
+
+func TestMediaUploadHandler(t *testing.T) {
+	jpegData := testJPEGWithExif(t)
+
+	tests := []struct {
+		name       string
+		maxSize    int
+		data       []byte
+		wantStatus int
+	}{
+		{"上传成功", 1 << 20, jpegData, http.StatusOK},
+		{"超过大小上限", 100, jpegData, http.StatusRequestEntityTooLarge},
+		{"不支持的类型", 1 << 20, []byte("hello world"), http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &MediaHandler{mediaService: NewMediaServiceWithStorage(NewFakeFileStorage())}
+			handler.mediaService.maxSize = tt.maxSize
+
+			rec := httptest.NewRecorder()
+			handler.UploadHandler(rec, newMediaUploadRequest(t, &model.UserModel{Id: 1}, tt.data))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Data MediaUploadResult `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Data.FileId == "" || response.Data.ContentType != "image/jpeg" {
+				t.Errorf("响应数据 = %+v", response.Data)
+			}
+		})
+	}
+}
+
+func TestMediaUploadRateLimit(t *testing.T) {
+	storage := NewFakeFileStorage()
+	handler := &MediaHandler{mediaService: NewMediaServiceWithStorage(storage)}
+	limiter := &RateLimiter{
+		store: &MemoryRateLimitStore{counters: make(map[string]*memoryCounter)},
+		budgets: map[string]RateLimitBudget{
+			RateLimitMediaUpload: {UserLimit: 2, IPLimit: 10, Window: time.Hour},
+		},
+	}
+	limited := limiter.Limit(RateLimitMediaUpload, handler.UploadHandler)
+	jpegData := testJPEGWithExif(t)
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		rec := httptest.NewRecorder()
+		limited(rec, newMediaUploadRequest(t, &model.UserModel{Id: 1}, jpegData))
+		if rec.Code != want {
+			t.Fatalf("第%d次上传 status = %d, want %d", i+1, rec.Code, want)
+		}
+	}
+	if len(storage.files) != 2 {
+		t.Errorf("上传的文件数 = %d, want 2", len(storage.files))
+	}
+
+	// 用户额度按用户计数，其他用户不受影响
+	rec := httptest.NewRecorder()
+	limited(rec, newMediaUploadRequest(t, &model.UserModel{Id: 2}, jpegData))
+	if rec.Code != http.StatusOK {
+		t.Errorf("其他用户上传 status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestFakeFileStorageDelete(t *testing.T) {
+	storage := NewFakeFileStorage()
+	service := NewMediaServiceWithStorage(storage)
+	jpegData := testJPEGWithExif(t)
+
+	var fileIds []string
+	for i := 0; i < 2; i++ {
+		result, err := service.UploadImage(context.Background(), &model.UserModel{Id: 1}, jpegData)
+		if err != nil {
+			t.Fatalf("UploadImage() error = %v", err)
+		}
+		fileIds = append(fileIds, result.FileId)
+	}
+
+	var fs FileStorage = storage
+	if err := fs.Delete(context.Background(), []string{fileIds[0], "cloud://fake/missing.jpg"}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, ok := storage.Get(fileIds[0]); ok {
+		t.Error("删除后文件仍然存在")
+	}
+	if _, ok := storage.Get(fileIds[1]); !ok {
+		t.Error("未删除的文件不应受影响")
+	}
+	if err := fs.Delete(context.Background(), nil); err != nil {
+		t.Errorf("Delete(nil) error = %v", err)
+	}
+}
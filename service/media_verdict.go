@@ -0,0 +1,198 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LabelThreshold 单个标签的置信度阈值（0-100），置信度达到Review转人工审核，达到Risky判定违规
+type LabelThreshold struct {
+	Review float64
+	Risky  float64
+}
+
+// defaultLabelThresholds 各标签的默认阈值，色情、违法犯罪从严，广告从宽；
+// 可通过环境变量 MEDIA_CHECK_THRESHOLDS 覆盖，格式为 "标签:审核阈值/违规阈值"，多个以逗号分隔，
+// 如 MEDIA_CHECK_THRESHOLDS=20001:40/70,20006:85/95
+var defaultLabelThresholds = map[int]LabelThreshold{
+	LabelPorn:      {Review: 40, Risky: 70},
+	LabelTerrorism: {Review: 50, Risky: 80},
+	LabelPolitics:  {Review: 50, Risky: 80},
+	LabelAbuse:     {Review: 60, Risky: 85},
+	LabelAd:        {Review: 80, Risky: 95},
+}
+
+// MediaVerdict 综合媒体检测回调所有检测策略后的结论
+type MediaVerdict struct {
+	Suggest string  // 综合结论：pass/review/risky
+	Label   int     // 决定结论的标签
+	Prob    float64 // 决定结论的置信度
+	Details []*MediaVerdictDetail
+}
+
+// MediaVerdictDetail 单个检测策略的结果及按阈值判定后的结论
+type MediaVerdictDetail struct {
+	Strategy string
+	Errcode  int
+	Suggest  string
+	Label    int
+	Prob     float64
+	Verdict  string
+}
+
+// MediaVerdictEngine 媒体检测结论判定：综合结论由每个Detail按标签阈值判定后的结论得出，
+// 阈值可以把pass升级为review/risky、把risky降级为review，但不会把微信判定为review/risky的结果降为pass。
+// 微信的整体Result在任一策略为risky时即为risky，只在有策略检测失败或没有策略结果时参与判定，否则降级不会生效
+type MediaVerdictEngine struct {
+	thresholds map[int]LabelThreshold
+}
+
+// NewMediaVerdictEngine 创建媒体检测结论判定器
+func NewMediaVerdictEngine() *MediaVerdictEngine {
+	thresholds := make(map[int]LabelThreshold, len(defaultLabelThresholds))
+	for label, threshold := range defaultLabelThresholds {
+		thresholds[label] = threshold
+	}
+	if value := os.Getenv("MEDIA_CHECK_THRESHOLDS"); value != "" {
+		parsed, err := parseLabelThresholds(value)
+		if err != nil {
+			slog.Warn("媒体检测阈值配置无效，使用默认值", "env", "MEDIA_CHECK_THRESHOLDS", "value", value, "error", err)
+		} else {
+			for label, threshold := range parsed {
+				thresholds[label] = threshold
+			}
+		}
+	}
+	return &MediaVerdictEngine{thresholds: thresholds}
+}
+
+// Judge 根据回调结果给出综合结论，回调本身失败时结论为risky
+func (e *MediaVerdictEngine) Judge(callback *WechatMediaCheckCallback) *MediaVerdict {
+	verdict := &MediaVerdict{Suggest: SuggestPass}
+	judged, failed := 0, 0
+	for _, d := range callback.Detail {
+		detail := &MediaVerdictDetail{
+			Strategy: d.Strategy,
+			Errcode:  d.Errcode,
+			Suggest:  d.Suggest,
+			Label:    d.Label,
+			Prob:     d.Prob,
+			Verdict:  SuggestPass,
+		}
+		// 单个策略检测失败时不参与判定，只记录
+		if d.Errcode == 0 {
+			detail.Verdict = e.judgeDetail(d.Suggest, d.Label, d.Prob)
+			judged++
+		} else {
+			failed++
+		}
+		verdict.Details = append(verdict.Details, detail)
+
+		if compareSuggest(detail.Verdict, verdict.Suggest) > 0 ||
+			(detail.Verdict == verdict.Suggest && detail.Verdict != SuggestPass && detail.Prob > verdict.Prob) {
+			verdict.Suggest = detail.Verdict
+			verdict.Label = detail.Label
+			verdict.Prob = detail.Prob
+		}
+	}
+
+	// 部分策略检测失败或没有策略结果时无法确认整体结果的来源，整体结果比各策略更严格时以整体结果为准
+	useResult := failed > 0 || judged == 0
+	if useResult && callback.Result.Suggest != "" && compareSuggest(normalizeSuggest(callback.Result.Suggest), verdict.Suggest) > 0 {
+		verdict.Suggest = normalizeSuggest(callback.Result.Suggest)
+		verdict.Label = callback.Result.Label
+		verdict.Prob = 0
+	}
+	if verdict.Suggest == SuggestPass && verdict.Label == 0 {
+		verdict.Label = callback.Result.Label
+	}
+	return verdict
+}
+
+// judgeDetail 判定单个检测策略的结论
+func (e *MediaVerdictEngine) judgeDetail(suggest string, label int, prob float64) string {
+	suggest = normalizeSuggest(suggest)
+	threshold, ok := e.thresholds[label]
+	if !ok || label == LabelNormal || prob <= 0 {
+		return suggest
+	}
+
+	byProb := SuggestPass
+	switch {
+	case threshold.Risky > 0 && prob >= threshold.Risky:
+		byProb = SuggestRisky
+	case threshold.Review > 0 && prob >= threshold.Review:
+		byProb = SuggestReview
+	}
+
+	// 微信判定的结果最多降级为review
+	floor := suggest
+	if floor == SuggestRisky {
+		floor = SuggestReview
+	}
+	if compareSuggest(byProb, floor) > 0 {
+		return byProb
+	}
+	return floor
+}
+
+// normalizeSuggest 未知的建议值按risky处理
+func normalizeSuggest(suggest string) string {
+	switch suggest {
+	case SuggestPass, SuggestReview, SuggestRisky:
+		return suggest
+	default:
+		return SuggestRisky
+	}
+}
+
+// compareSuggest 比较两个建议的严重程度：risky > review > pass
+func compareSuggest(a, b string) int {
+	rank := func(s string) int {
+		switch s {
+		case SuggestPass:
+			return 0
+		case SuggestReview:
+			return 1
+		default:
+			return 2
+		}
+	}
+	return rank(a) - rank(b)
+}
+
+// parseLabelThresholds 解析 "标签:审核阈值/违规阈值,..." 格式的阈值配置
+func parseLabelThresholds(value string) (map[int]LabelThreshold, error) {
+	thresholds := make(map[int]LabelThreshold)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		labelPart, probPart, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("格式应为 标签:审核阈值/违规阈值: %s", item)
+		}
+		label, err := strconv.Atoi(strings.TrimSpace(labelPart))
+		if err != nil {
+			return nil, fmt.Errorf("标签无效: %s", labelPart)
+		}
+		reviewPart, riskyPart, ok := strings.Cut(probPart, "/")
+		if !ok {
+			return nil, fmt.Errorf("格式应为 标签:审核阈值/违规阈值: %s", item)
+		}
+		review, err := strconv.ParseFloat(strings.TrimSpace(reviewPart), 64)
+		if err != nil || review < 0 || review > 100 {
+			return nil, fmt.Errorf("审核阈值无效: %s", reviewPart)
+		}
+		risky, err := strconv.ParseFloat(strings.TrimSpace(riskyPart), 64)
+		if err != nil || risky < 0 || risky > 100 {
+			return nil, fmt.Errorf("违规阈值无效: %s", riskyPart)
+		}
+		thresholds[label] = LabelThreshold{Review: review, Risky: risky}
+	}
+	return thresholds, nil
+}
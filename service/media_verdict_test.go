@@ -0,0 +1,132 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// testDetail 媒体检测回调中的单个检测策略结果
+type testDetail struct {
+	Strategy string  `json:"strategy"`
+	Errcode  int     `json:"errcode"`
+	Suggest  string  `json:"suggest"`
+	Label    int     `json:"label"`
+	Prob     float64 `json:"prob"`
+}
+
+// newTestCallback 构造媒体检测回调
+func newTestCallback(t *testing.T, resultSuggest string, resultLabel int, details ...testDetail) *WechatMediaCheckCallback {
+	t.Helper()
+	raw, err := json.Marshal(map[string]interface{}{
+		"detail": details,
+		"result": map[string]interface{}{"suggest": resultSuggest, "label": resultLabel},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var callback WechatMediaCheckCallback
+	if err := json.Unmarshal(raw, &callback); err != nil {
+		t.Fatal(err)
+	}
+	return &callback
+}
+
+func TestMediaVerdictEngineJudge(t *testing.T) {
+	engine := &MediaVerdictEngine{thresholds: defaultLabelThresholds}
+	normal := testDetail{Strategy: "content_model", Suggest: SuggestPass, Label: LabelNormal, Prob: 90}
+
+	tests := []struct {
+		name        string
+		callback    *WechatMediaCheckCallback
+		wantSuggest string
+		wantLabel   int
+	}{
+		{
+			name:        "全部通过",
+			callback:    newTestCallback(t, SuggestPass, LabelNormal, normal),
+			wantSuggest: SuggestPass,
+			wantLabel:   LabelNormal,
+		},
+		{
+			name: "置信度达到审核阈值时升级为review",
+			callback: newTestCallback(t, SuggestPass, LabelNormal,
+				normal, testDetail{Strategy: "porn", Suggest: SuggestPass, Label: LabelPorn, Prob: 50}),
+			wantSuggest: SuggestReview,
+			wantLabel:   LabelPorn,
+		},
+		{
+			name: "置信度达到违规阈值时升级为risky",
+			callback: newTestCallback(t, SuggestPass, LabelNormal,
+				testDetail{Strategy: "porn", Suggest: SuggestPass, Label: LabelPorn, Prob: 75}),
+			wantSuggest: SuggestRisky,
+			wantLabel:   LabelPorn,
+		},
+		{
+			name: "低置信度的risky降级为review，整体结果不覆盖",
+			callback: newTestCallback(t, SuggestRisky, LabelAd,
+				normal, testDetail{Strategy: "ad", Suggest: SuggestRisky, Label: LabelAd, Prob: 60}),
+			wantSuggest: SuggestReview,
+			wantLabel:   LabelAd,
+		},
+		{
+			name: "高置信度保持risky",
+			callback: newTestCallback(t, SuggestRisky, LabelAd,
+				testDetail{Strategy: "ad", Suggest: SuggestRisky, Label: LabelAd, Prob: 96}),
+			wantSuggest: SuggestRisky,
+			wantLabel:   LabelAd,
+		},
+		{
+			name: "review不会降为pass",
+			callback: newTestCallback(t, SuggestReview, LabelAd,
+				testDetail{Strategy: "ad", Suggest: SuggestReview, Label: LabelAd, Prob: 10}),
+			wantSuggest: SuggestReview,
+			wantLabel:   LabelAd,
+		},
+		{
+			name: "没有阈值的标签沿用微信结论",
+			callback: newTestCallback(t, SuggestRisky, 21000,
+				testDetail{Strategy: "other", Suggest: SuggestRisky, Label: 21000, Prob: 10}),
+			wantSuggest: SuggestRisky,
+			wantLabel:   21000,
+		},
+		{
+			name: "同一结论取置信度最高的标签",
+			callback: newTestCallback(t, SuggestRisky, LabelPorn,
+				testDetail{Strategy: "abuse", Suggest: SuggestPass, Label: LabelAbuse, Prob: 65},
+				testDetail{Strategy: "ad", Suggest: SuggestPass, Label: LabelAd, Prob: 85}),
+			wantSuggest: SuggestReview,
+			wantLabel:   LabelAd,
+		},
+		{
+			name: "有策略检测失败时整体结果更严格则以整体结果为准",
+			callback: newTestCallback(t, SuggestRisky, LabelPorn,
+				normal, testDetail{Strategy: "porn", Errcode: -1008}),
+			wantSuggest: SuggestRisky,
+			wantLabel:   LabelPorn,
+		},
+		{
+			name:        "没有策略结果时使用整体结果",
+			callback:    newTestCallback(t, SuggestReview, LabelAbuse),
+			wantSuggest: SuggestReview,
+			wantLabel:   LabelAbuse,
+		},
+		{
+			name:        "未知的整体结果按risky处理",
+			callback:    newTestCallback(t, "block", LabelPorn),
+			wantSuggest: SuggestRisky,
+			wantLabel:   LabelPorn,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict := engine.Judge(tt.callback)
+			if verdict.Suggest != tt.wantSuggest || verdict.Label != tt.wantLabel {
+				t.Errorf("Judge() = %s/%d, want %s/%d", verdict.Suggest, verdict.Label, tt.wantSuggest, tt.wantLabel)
+			}
+			if len(verdict.Details) != len(tt.callback.Detail) {
+				t.Errorf("Details 数量 = %d, want %d", len(verdict.Details), len(tt.callback.Detail))
+			}
+		})
+	}
+}
@@ -2,11 +2,22 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
 	"net/http"
+	"time"
 	"wxcloudrun-golang/db/dao"
 	"wxcloudrun-golang/db/model"
+	"wxcloudrun-golang/logger"
+	"wxcloudrun-golang/metrics"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
+// RequestIdHeader 请求ID请求头/响应头
+const RequestIdHeader = "X-Request-ID"
+
 // UserContext 用户上下文
 type UserContext struct {
 	User   *model.UserModel
@@ -21,30 +32,10 @@ type UserContext struct {
 // UserMiddleware 用户认证中间件
 func UserMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// 获取微信小程序请求头
-		openId := r.Header.Get("X-WX-OPENID")
-		appId := r.Header.Get("X-WX-APPID")
-		unionId := r.Header.Get("X-WX-UNIONID")
-		fromOpenId := r.Header.Get("X-WX-FROM-OPENID")
-		fromAppId := r.Header.Get("X-WX-FROM-APPID")
-		fromUnionId := r.Header.Get("X-WX-FROM-UNIONID")
-		env := r.Header.Get("X-WX-ENV")
-		source := r.Header.Get("X-WX-SOURCE")
-		ip := r.Header.Get("X-Original-Forwarded-For")
-
-		// 优先使用 X-WX-OPENID，如果不存在则使用 X-WX-FROM-OPENID
-		if openId == "" {
-			openId = fromOpenId
-		}
-		if appId == "" {
-			appId = fromAppId
-		}
-		if unionId == "" {
-			unionId = fromUnionId
-		}
+		userCtx := userContextFromHeaders(r)
 
 		// 如果没有 openId，返回错误
-		if openId == "" {
+		if userCtx.OpenId == "" {
 			http.Error(w, "Missing X-WX-OPENID header", http.StatusUnauthorized)
 			return
 		}
@@ -52,24 +43,21 @@ func UserMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		// 创建用户DAO实例
 		userDao := dao.NewUserDao()
 
-			// 查询用户是否存在
-	user, err := userDao.GetUserByOpenId(openId)
-	if err != nil {
-		// 用户不存在，返回错误
-		http.Error(w, "User not found, please register first", http.StatusUnauthorized)
-		return
-	}
+		// 查询用户是否存在
+		user, err := userDao.GetUserByOpenId(r.Context(), userCtx.OpenId)
+		if err != nil {
+			// 用户不存在，返回错误
+			http.Error(w, "User not found, please register first", http.StatusUnauthorized)
+			return
+		}
 
-		// 创建用户上下文
-		userCtx := &UserContext{
-			User:    user,
-			OpenId:  openId,
-			AppId:   appId,
-			UnionId: unionId,
-			Env:     env,
-			Source:  source,
-			IP:      ip,
+		// 停用、封禁的账号不能使用需要登录的接口，禁言只在写接口中校验
+		if err := accountRestriction(user, false); err != nil {
+			slog.WarnContext(r.Context(), "受限账号访问被拒绝", "user_id", user.Id, "status", user.Status, "path", r.URL.Path)
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
 		}
+		userCtx.User = user
 
 		// 将用户上下文存储到请求上下文中
 		ctx := context.WithValue(r.Context(), "user", userCtx)
@@ -80,6 +68,55 @@ func UserMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// OptionalUserMiddleware 可选用户认证中间件：请求带有已注册用户的身份时写入用户上下文，
+// 否则（无身份、未注册、账号停用或封禁）按匿名访问处理，用于游客也可访问、登录后结果按用户过滤的接口
+func OptionalUserMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userCtx := userContextFromHeaders(r)
+		if userCtx.OpenId == "" {
+			next(w, r)
+			return
+		}
+
+		user, err := dao.NewUserDao().GetUserByOpenId(r.Context(), userCtx.OpenId)
+		if err != nil || accountRestriction(user, false) != nil {
+			next(w, r)
+			return
+		}
+		userCtx.User = user
+
+		next(w, r.WithContext(context.WithValue(r.Context(), "user", userCtx)))
+	}
+}
+
+// userContextFromHeaders 从微信云托管请求头读取调用方身份，不查询用户
+func userContextFromHeaders(r *http.Request) *UserContext {
+	// 获取微信小程序请求头
+	openId := r.Header.Get("X-WX-OPENID")
+	appId := r.Header.Get("X-WX-APPID")
+	unionId := r.Header.Get("X-WX-UNIONID")
+
+	// 优先使用 X-WX-OPENID，如果不存在则使用 X-WX-FROM-OPENID
+	if openId == "" {
+		openId = r.Header.Get("X-WX-FROM-OPENID")
+	}
+	if appId == "" {
+		appId = r.Header.Get("X-WX-FROM-APPID")
+	}
+	if unionId == "" {
+		unionId = r.Header.Get("X-WX-FROM-UNIONID")
+	}
+
+	return &UserContext{
+		OpenId:  openId,
+		AppId:   appId,
+		UnionId: unionId,
+		Env:     r.Header.Get("X-WX-ENV"),
+		Source:  r.Header.Get("X-WX-SOURCE"),
+		IP:      r.Header.Get("X-Original-Forwarded-For"),
+	}
+}
+
 // GetUserFromContext 从上下文中获取用户信息
 func GetUserFromContext(r *http.Request) *UserContext {
 	if userCtx, ok := r.Context().Value("user").(*UserContext); ok {
@@ -90,6 +127,38 @@ func GetUserFromContext(r *http.Request) *UserContext {
 
 
 
+// roleRanks 角色权限等级，数值越大权限越高
+var roleRanks = map[string]int{
+	model.RoleUser:      0,
+	model.RoleModerator: 1,
+	model.RoleAdmin:     2,
+}
+
+// HasRole 判断用户角色是否不低于指定角色
+func HasRole(user *model.UserModel, role string) bool {
+	if user == nil {
+		return false
+	}
+	userRank, ok := roleRanks[user.Role]
+	if !ok {
+		return false
+	}
+	return userRank >= roleRanks[role]
+}
+
+// AdminMiddleware 后台权限中间件，在用户认证基础上要求角色不低于role
+func AdminMiddleware(role string, next http.HandlerFunc) http.HandlerFunc {
+	return UserMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		userCtx := GetUserFromContext(r)
+		if userCtx == nil || !HasRole(userCtx.User, role) {
+			slog.WarnContext(r.Context(), "后台接口权限不足", "path", r.URL.Path, "required_role", role)
+			http.Error(w, "Permission denied", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	})
+}
+
 // RequireAuth 要求认证的中间件
 func RequireAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -101,3 +170,73 @@ func RequireAuth(next http.HandlerFunc) http.HandlerFunc {
 		next(w, r)
 	}
 }
+
+// statusRecorder 记录响应状态码的ResponseWriter
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader 记录状态码
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// RequestIdMiddleware 请求ID中间件：沿用调用方传入的X-Request-ID，没有则生成，
+// 写入请求上下文和响应头，并在请求结束后输出访问日志
+func RequestIdMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestId := r.Header.Get(RequestIdHeader)
+		if requestId == "" {
+			requestId = newRequestId()
+		}
+		w.Header().Set(RequestIdHeader, requestId)
+
+		ctx := logger.WithRequestId(r.Context(), requestId)
+		r = r.WithContext(ctx)
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		slog.InfoContext(ctx, "http request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"elapsed_ms", time.Since(start).Milliseconds(),
+			"ip", r.Header.Get("X-Original-Forwarded-For"),
+		)
+	})
+}
+
+// MetricsMiddleware 指标中间件：按路由记录请求数和耗时
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		metrics.ObserveHTTPRequest(r.URL.Path, r.Method, rec.status, time.Since(start))
+	})
+}
+
+// TracingMiddleware 链路追踪中间件：解析上游trace上下文，为每个请求创建以路由命名的Span
+func TracingMiddleware(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "http.server",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + metrics.RouteLabel(r.URL.Path)
+		}),
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return r.URL.Path != "/metrics"
+		}),
+	)
+}
+
+// newRequestId 生成随机请求ID
+func newRequestId() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return hex.EncodeToString([]byte(time.Now().Format("20060102150405.000000000")))
+	}
+	return hex.EncodeToString(buf)
+}
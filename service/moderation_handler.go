@@ -0,0 +1,142 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"wxcloudrun-golang/db/model"
+)
+
+// ModerationHandler 人工审核处理器
+type ModerationHandler struct {
+	moderationService *ModerationService
+	auditService      *AuditService
+}
+
+// NewModerationHandler 创建人工审核处理器实例
+func NewModerationHandler() *ModerationHandler {
+	return &ModerationHandler{
+		moderationService: NewModerationService(),
+		auditService:      NewAuditService(),
+	}
+}
+
+// ReviewDecisionRequest 审核决定请求
+type ReviewDecisionRequest struct {
+	Note string `json:"note"`
+}
+
+// HandleModerationRequests 处理审核相关请求，需要经过AdminMiddleware校验审核员权限
+// GET  /api/admin/moderation/queue              获取审核队列
+// POST /api/admin/moderation/queue/{id}/approve 审核通过
+// POST /api/admin/moderation/queue/{id}/reject  审核驳回
+func (h *ModerationHandler) HandleModerationRequests(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userCtx := GetUserFromContext(r)
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	if path == "/api/admin/moderation/queue" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.GetQueueHandler(w, r)
+		return
+	}
+
+	// /api/admin/moderation/queue/{id}/{action}
+	pathParts := strings.Split(path, "/")
+	if len(pathParts) != 7 || pathParts[4] != "queue" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.ParseInt(pathParts[5], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid queue item ID", http.StatusBadRequest)
+		return
+	}
+
+	var req ReviewDecisionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var item *model.ModerationQueueModel
+	var action string
+	switch pathParts[6] {
+	case "approve":
+		action = model.AuditActionModerationApprove
+		item, err = h.moderationService.Approve(r.Context(), id, userCtx.User.Id, req.Note)
+	case "reject":
+		action = model.AuditActionModerationReject
+		item, err = h.moderationService.Reject(r.Context(), id, userCtx.User.Id, req.Note)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.auditService.Record(r.Context(), userCtx, action, "moderation_queue", item.Id, map[string]interface{}{
+		"target_type": item.TargetType,
+		"target_id":   item.TargetId,
+		"source":      item.Source,
+		"note":        req.Note,
+	})
+
+	response := map[string]interface{}{
+		"code":    200,
+		"message": "处理成功",
+		"data":    item,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetQueueHandler 获取审核队列处理器，默认只返回待审核项
+func (h *ModerationHandler) GetQueueHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	status := model.ModerationQueuePending
+	if statusStr := query.Get("status"); statusStr != "" {
+		if statusStr == "all" {
+			status = -1
+		} else if st, err := strconv.Atoi(statusStr); err == nil {
+			status = st
+		}
+	}
+
+	page := 1
+	if p, err := strconv.Atoi(query.Get("page")); err == nil && p > 0 {
+		page = p
+	}
+	pageSize := 20
+	if ps, err := strconv.Atoi(query.Get("pageSize")); err == nil && ps > 0 && ps <= 50 {
+		pageSize = ps
+	}
+
+	result, err := h.moderationService.GetQueue(r.Context(), status, query.Get("targetType"), page, pageSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"code":    200,
+		"message": "success",
+		"data":    result,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
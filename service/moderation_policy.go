@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+	"wxcloudrun-golang/db/dao"
+	"wxcloudrun-golang/db/model"
+)
+
+// 审核策略动作
+const (
+	PolicyActionAllow  = "allow"  // 直接通过
+	PolicyActionReview = "review" // 发布为待审核，进入人工审核队列
+	PolicyActionShadow = "shadow" // 静默隐藏，仅作者可见
+	PolicyActionReject = "reject" // 拒绝发布（图片判定为违规）
+)
+
+// PolicyRule 审核策略规则，零值字段匹配任意值，规则按顺序匹配，第一条命中的规则生效
+type PolicyRule struct {
+	Kind     string  `json:"kind,omitempty"`     // 检测类型：text/image/audio
+	Scene    int     `json:"scene,omitempty"`    // 检测场景：1-资料 2-评论 3-论坛 4-社交日志
+	Label    int     `json:"label,omitempty"`    // 检测标签
+	Suggest  string  `json:"suggest,omitempty"`  // 检测建议：pass/review/risky
+	MinLevel float64 `json:"minLevel,omitempty"` // 最低风险等级，文本取检测策略的level，图片/音频取prob
+	Action   string  `json:"action"`             // 命中后的动作：allow/review/shadow/reject
+}
+
+// PolicySignal 参与策略判定的一条检测结果（整体结果或单个检测策略）
+type PolicySignal struct {
+	Suggest string
+	Label   int
+	Level   float64
+}
+
+// ModerationPolicy 当前生效的审核策略
+type ModerationPolicy struct {
+	Version   int64         `json:"version"` // 策略版本ID，0表示未配置，使用默认策略
+	Rules     []*PolicyRule `json:"rules"`
+	Note      string        `json:"note"`
+	UpdatedAt *time.Time    `json:"updatedAt"`
+}
+
+// defaultPolicyCacheTTL 审核策略默认刷新间隔
+const defaultPolicyCacheTTL = 30 * time.Second
+
+// policyCache 审核策略内存缓存。后台修改策略后本实例立即生效，其他实例在 MODERATION_POLICY_TTL 内重新加载
+type policyCache struct {
+	mu       sync.RWMutex
+	ttl      time.Duration
+	loadedAt time.Time
+	policy   *ModerationPolicy
+}
+
+// sharedPolicyCache 进程内共享的审核策略缓存，文本和媒体检测共用
+var sharedPolicyCache = &policyCache{
+	ttl:    envDuration("MODERATION_POLICY_TTL", defaultPolicyCacheTTL),
+	policy: &ModerationPolicy{},
+}
+
+// ModerationPolicyService 审核策略服务：按 场景 + 标签 + 建议 + 风险等级 决定内容的处理动作，
+// 未命中任何规则时 pass 通过、review 转人工审核、其余拒绝
+type ModerationPolicyService struct {
+	policyDao    dao.ModerationPolicyDao
+	auditService *AuditService
+	cache        *policyCache
+}
+
+// NewModerationPolicyService 创建审核策略服务实例
+func NewModerationPolicyService() *ModerationPolicyService {
+	return &ModerationPolicyService{
+		policyDao:    dao.NewModerationPolicyDao(),
+		auditService: NewAuditService(),
+		cache:        sharedPolicyCache,
+	}
+}
+
+// EvaluateText 判定文本检测结果的处理动作，综合整体结果和每个检测策略，取最严格的动作
+func (s *ModerationPolicyService) EvaluateText(ctx context.Context, scene int, result *MsgSecCheckResponse) string {
+	signals := []PolicySignal{{Suggest: result.Result.Suggest, Label: result.Result.Label}}
+	for _, detail := range result.Detail {
+		if detail.Errcode != 0 || detail.Suggest == "" {
+			continue
+		}
+		signals = append(signals, PolicySignal{Suggest: detail.Suggest, Label: detail.Label, Level: float64(detail.Level)})
+	}
+	return s.Evaluate(ctx, model.ContentCheckKindText, scene, signals)
+}
+
+// EvaluateMedia 判定图片/音频检测结论的处理动作，各检测策略以按阈值判定后的结论参与匹配
+func (s *ModerationPolicyService) EvaluateMedia(ctx context.Context, kind string, scene int, verdict *MediaVerdict) string {
+	signals := []PolicySignal{{Suggest: verdict.Suggest, Label: verdict.Label, Level: verdict.Prob}}
+	for _, detail := range verdict.Details {
+		if detail.Errcode != 0 {
+			continue
+		}
+		signals = append(signals, PolicySignal{Suggest: detail.Verdict, Label: detail.Label, Level: detail.Prob})
+	}
+	return s.Evaluate(ctx, kind, scene, signals)
+}
+
+// Evaluate 对每条检测结果匹配策略规则，返回最严格的动作
+func (s *ModerationPolicyService) Evaluate(ctx context.Context, kind string, scene int, signals []PolicySignal) string {
+	rules := s.cache.get(ctx, s.policyDao).Rules
+	action := PolicyActionAllow
+	for _, signal := range signals {
+		if a := matchPolicy(rules, kind, scene, signal); comparePolicyAction(a, action) > 0 {
+			action = a
+		}
+	}
+	return action
+}
+
+// GetPolicy 获取当前生效的审核策略
+func (s *ModerationPolicyService) GetPolicy(ctx context.Context) *ModerationPolicy {
+	return s.cache.get(ctx, s.policyDao)
+}
+
+// UpdatePolicyRequest 更新审核策略请求
+type UpdatePolicyRequest struct {
+	Rules []*PolicyRule `json:"rules"`
+	Note  string        `json:"note"`
+}
+
+// UpdatePolicy 校验并保存新版本的审核策略，本实例立即生效
+func (s *ModerationPolicyService) UpdatePolicy(ctx context.Context, operator *UserContext, req *UpdatePolicyRequest) (*ModerationPolicy, error) {
+	if err := validatePolicyRules(req.Rules); err != nil {
+		return nil, err
+	}
+	rules, err := json.Marshal(req.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("序列化审核策略失败: %v", err)
+	}
+	record := &model.ModerationPolicyModel{
+		Rules:      string(rules),
+		Note:       truncateRunes(req.Note, 200),
+		OperatorId: operator.User.Id,
+	}
+	if err := s.policyDao.Create(ctx, record); err != nil {
+		return nil, fmt.Errorf("保存审核策略失败: %v", err)
+	}
+
+	s.auditService.Record(ctx, operator, model.AuditActionUpdatePolicy, "policy", record.Id, req)
+	s.cache.invalidate()
+	slog.InfoContext(ctx, "审核策略已更新", "version", record.Id, "rule_count", len(req.Rules), "operator_id", operator.User.Id)
+
+	return s.cache.get(ctx, s.policyDao), nil
+}
+
+// get 获取缓存的策略，过期时重新加载；加载失败时继续使用上一次加载的策略
+func (c *policyCache) get(ctx context.Context, policyDao dao.ModerationPolicyDao) *ModerationPolicy {
+	c.mu.RLock()
+	policy, fresh := c.policy, !c.loadedAt.IsZero() && time.Since(c.loadedAt) < c.ttl
+	c.mu.RUnlock()
+	if fresh {
+		return policy
+	}
+
+	loaded, err := loadModerationPolicy(ctx, policyDao)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// 加载失败时同样推迟下次加载，避免数据库异常时每次判定都重试
+	c.loadedAt = time.Now()
+	if err != nil {
+		slog.ErrorContext(ctx, "加载审核策略失败，继续使用当前策略", "version", c.policy.Version, "error", err)
+		return c.policy
+	}
+	if loaded.Version != c.policy.Version {
+		slog.InfoContext(ctx, "审核策略已加载", "version", loaded.Version, "rule_count", len(loaded.Rules))
+	}
+	c.policy = loaded
+	return loaded
+}
+
+// invalidate 使缓存失效，下次判定时重新加载
+func (c *policyCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadedAt = time.Time{}
+}
+
+// loadModerationPolicy 从数据库加载最新的审核策略，未配置时返回空策略
+func loadModerationPolicy(ctx context.Context, policyDao dao.ModerationPolicyDao) (*ModerationPolicy, error) {
+	record, err := policyDao.GetLatest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return &ModerationPolicy{}, nil
+	}
+	var rules []*PolicyRule
+	if err := json.Unmarshal([]byte(record.Rules), &rules); err != nil {
+		return nil, fmt.Errorf("解析审核策略失败: %v", err)
+	}
+	updatedAt := record.CreatedAt
+	return &ModerationPolicy{Version: record.Id, Rules: rules, Note: record.Note, UpdatedAt: &updatedAt}, nil
+}
+
+// matchPolicy 返回第一条命中规则的动作，未命中时按检测建议给出默认动作
+func matchPolicy(rules []*PolicyRule, kind string, scene int, signal PolicySignal) string {
+	for _, rule := range rules {
+		if rule.Kind != "" && rule.Kind != kind {
+			continue
+		}
+		if rule.Scene != 0 && rule.Scene != scene {
+			continue
+		}
+		if rule.Label != 0 && rule.Label != signal.Label {
+			continue
+		}
+		if rule.Suggest != "" && rule.Suggest != signal.Suggest {
+			continue
+		}
+		if rule.MinLevel > 0 && signal.Level < rule.MinLevel {
+			continue
+		}
+		return rule.Action
+	}
+
+	switch signal.Suggest {
+	case SuggestPass, "":
+		return PolicyActionAllow
+	case SuggestReview:
+		return PolicyActionReview
+	default:
+		return PolicyActionReject
+	}
+}
+
+// comparePolicyAction 比较两个动作的严格程度：reject > shadow > review > allow
+func comparePolicyAction(a, b string) int {
+	rank := map[string]int{PolicyActionAllow: 0, PolicyActionReview: 1, PolicyActionShadow: 2, PolicyActionReject: 3}
+	return rank[a] - rank[b]
+}
+
+// validatePolicyRules 校验策略规则的取值
+func validatePolicyRules(rules []*PolicyRule) error {
+	for i, rule := range rules {
+		if rule == nil {
+			return fmt.Errorf("第%d条规则为空", i+1)
+		}
+		switch rule.Action {
+		case PolicyActionAllow, PolicyActionReview, PolicyActionShadow, PolicyActionReject:
+		default:
+			return fmt.Errorf("第%d条规则的动作无效: %s", i+1, rule.Action)
+		}
+		switch rule.Kind {
+		case "", model.ContentCheckKindText, model.ContentCheckKindImage, model.ContentCheckKindAudio, model.ContentCheckKindVideo:
+		default:
+			return fmt.Errorf("第%d条规则的检测类型无效: %s", i+1, rule.Kind)
+		}
+		switch rule.Suggest {
+		case "", SuggestPass, SuggestReview, SuggestRisky:
+		default:
+			return fmt.Errorf("第%d条规则的检测建议无效: %s", i+1, rule.Suggest)
+		}
+		if rule.Scene < 0 || rule.Scene > SceneSocial {
+			return fmt.Errorf("第%d条规则的场景无效: %d", i+1, rule.Scene)
+		}
+		if rule.MinLevel < 0 || rule.MinLevel > 100 {
+			return fmt.Errorf("第%d条规则的风险等级无效: %v", i+1, rule.MinLevel)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wxcloudrun-golang/db/model"
+)
+
+func TestMatchPolicy(t *testing.T) {
+	rules := []*PolicyRule{
+		{Kind: model.ContentCheckKindText, Scene: SceneComment, Label: 20001, Action: PolicyActionShadow},
+		{Kind: model.ContentCheckKindText, Label: 20001, Action: PolicyActionReject},
+		{Kind: model.ContentCheckKindImage, Suggest: SuggestRisky, MinLevel: 90, Action: PolicyActionReject},
+		{Kind: model.ContentCheckKindImage, Suggest: SuggestRisky, Action: PolicyActionReview},
+		{Label: 10001, Action: PolicyActionAllow},
+	}
+
+	tests := []struct {
+		name   string
+		rules  []*PolicyRule
+		kind   string
+		scene  int
+		signal PolicySignal
+		want   string
+	}{
+		{"第一条命中的规则生效", rules, model.ContentCheckKindText, SceneComment, PolicySignal{Suggest: SuggestRisky, Label: 20001}, PolicyActionShadow},
+		{"场景不匹配时继续匹配后续规则", rules, model.ContentCheckKindText, SceneForum, PolicySignal{Suggest: SuggestRisky, Label: 20001}, PolicyActionReject},
+		{"零值字段匹配任意检测类型和场景", rules, model.ContentCheckKindAudio, SceneProfile, PolicySignal{Suggest: SuggestRisky, Label: 10001}, PolicyActionAllow},
+		{"达到最低风险等级", rules, model.ContentCheckKindImage, SceneForum, PolicySignal{Suggest: SuggestRisky, Label: 20002, Level: 95}, PolicyActionReject},
+		{"等于最低风险等级", rules, model.ContentCheckKindImage, SceneForum, PolicySignal{Suggest: SuggestRisky, Label: 20002, Level: 90}, PolicyActionReject},
+		{"低于最低风险等级", rules, model.ContentCheckKindImage, SceneForum, PolicySignal{Suggest: SuggestRisky, Label: 20002, Level: 60}, PolicyActionReview},
+		{"检测建议不匹配", rules, model.ContentCheckKindImage, SceneForum, PolicySignal{Suggest: SuggestReview, Label: 20002}, PolicyActionReview},
+		{"未命中时pass通过", nil, model.ContentCheckKindText, SceneForum, PolicySignal{Suggest: SuggestPass, Label: 100}, PolicyActionAllow},
+		{"未命中时空建议通过", nil, model.ContentCheckKindText, SceneForum, PolicySignal{}, PolicyActionAllow},
+		{"未命中时review转人工审核", nil, model.ContentCheckKindText, SceneForum, PolicySignal{Suggest: SuggestReview, Label: 20001}, PolicyActionReview},
+		{"未命中时risky拒绝", nil, model.ContentCheckKindText, SceneForum, PolicySignal{Suggest: SuggestRisky, Label: 20001}, PolicyActionReject},
+		{"未命中时未知建议拒绝", nil, model.ContentCheckKindText, SceneForum, PolicySignal{Suggest: "unknown"}, PolicyActionReject},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchPolicy(tt.rules, tt.kind, tt.scene, tt.signal); got != tt.want {
+				t.Errorf("matchPolicy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComparePolicyAction(t *testing.T) {
+	order := []string{PolicyActionAllow, PolicyActionReview, PolicyActionShadow, PolicyActionReject}
+
+	for i, a := range order {
+		for j, b := range order {
+			got := comparePolicyAction(a, b)
+			switch {
+			case i > j && got <= 0:
+				t.Errorf("comparePolicyAction(%q, %q) = %d, want > 0", a, b, got)
+			case i < j && got >= 0:
+				t.Errorf("comparePolicyAction(%q, %q) = %d, want < 0", a, b, got)
+			case i == j && got != 0:
+				t.Errorf("comparePolicyAction(%q, %q) = %d, want 0", a, b, got)
+			}
+		}
+	}
+}
+
+func TestValidatePolicyRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []*PolicyRule
+		wantErr bool
+	}{
+		{"空策略", nil, false},
+		{"有效规则", []*PolicyRule{
+			{Action: PolicyActionAllow},
+			{Kind: model.ContentCheckKindVideo, Scene: SceneSocial, Suggest: SuggestRisky, MinLevel: 100, Action: PolicyActionReject},
+		}, false},
+		{"规则为空", []*PolicyRule{nil}, true},
+		{"缺少动作", []*PolicyRule{{Kind: model.ContentCheckKindText}}, true},
+		{"动作无效", []*PolicyRule{{Action: "block"}}, true},
+		{"检测类型无效", []*PolicyRule{{Kind: "file", Action: PolicyActionAllow}}, true},
+		{"检测建议无效", []*PolicyRule{{Suggest: "block", Action: PolicyActionAllow}}, true},
+		{"场景为负数", []*PolicyRule{{Scene: -1, Action: PolicyActionAllow}}, true},
+		{"场景超出范围", []*PolicyRule{{Scene: SceneSocial + 1, Action: PolicyActionAllow}}, true},
+		{"风险等级为负数", []*PolicyRule{{MinLevel: -1, Action: PolicyActionAllow}}, true},
+		{"风险等级超出范围", []*PolicyRule{{MinLevel: 101, Action: PolicyActionAllow}}, true},
+		{"后续规则无效", []*PolicyRule{{Action: PolicyActionAllow}, {Action: ""}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validatePolicyRules(tt.rules); (err != nil) != tt.wantErr {
+				t.Errorf("validatePolicyRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	rules := []*PolicyRule{
+		{Label: 20002, Action: PolicyActionShadow},
+		{Suggest: SuggestRisky, Label: 21000, Action: PolicyActionAllow},
+	}
+	service := &ModerationPolicyService{
+		cache: &policyCache{ttl: time.Hour, loadedAt: time.Now(), policy: &ModerationPolicy{Version: 1, Rules: rules}},
+	}
+
+	tests := []struct {
+		name    string
+		signals []PolicySignal
+		want    string
+	}{
+		{"没有检测结果时通过", nil, PolicyActionAllow},
+		{"全部通过", []PolicySignal{{Suggest: SuggestPass, Label: 100}, {Suggest: SuggestPass, Label: 20001}}, PolicyActionAllow},
+		{"取最严格的动作", []PolicySignal{{Suggest: SuggestPass, Label: 100}, {Suggest: SuggestReview, Label: 20001}, {Suggest: SuggestRisky, Label: 20006}}, PolicyActionReject},
+		{"规则动作参与比较", []PolicySignal{{Suggest: SuggestReview, Label: 20001}, {Suggest: SuggestReview, Label: 20002}}, PolicyActionShadow},
+		{"放行规则不降低其他结果的动作", []PolicySignal{{Suggest: SuggestRisky, Label: 21000}, {Suggest: SuggestReview, Label: 20001}}, PolicyActionReview},
+		{"放行规则命中", []PolicySignal{{Suggest: SuggestRisky, Label: 21000}}, PolicyActionAllow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := service.Evaluate(context.Background(), model.ContentCheckKindText, SceneForum, tt.signals); got != tt.want {
+				t.Errorf("Evaluate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,445 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"wxcloudrun-golang/db/dao"
+	"wxcloudrun-golang/db/model"
+)
+
+// ModerationService 人工审核服务
+type ModerationService struct {
+	queueDao        dao.ModerationQueueDao
+	postDao         dao.PostDao
+	commentDao      dao.CommentDao
+	mediaCheckDao   dao.MediaCheckDao
+	userDao         dao.UserDao
+	notificationDao dao.NotificationDao
+}
+
+// NewModerationService 创建人工审核服务实例
+func NewModerationService() *ModerationService {
+	return &ModerationService{
+		queueDao:        dao.NewModerationQueueDao(),
+		postDao:         dao.NewPostDao(),
+		commentDao:      dao.NewCommentDao(),
+		mediaCheckDao:   dao.NewMediaCheckDao(),
+		userDao:         dao.NewUserDao(),
+		notificationDao: dao.NewNotificationDao(),
+	}
+}
+
+// ModerationQueueListResponse 审核队列列表响应
+type ModerationQueueListResponse struct {
+	List       []*model.ModerationQueueModel `json:"list"`
+	Pagination Pagination                    `json:"pagination"`
+}
+
+// newTextReviewItem 根据文本检测结果生成待审核项，审核对象由调用方在内容创建后补全
+func newTextReviewItem(content string, result *MsgSecCheckResponse) *model.ModerationQueueModel {
+	return &model.ModerationQueueModel{
+		Source:  model.ModerationSourceText,
+		Content: content,
+		Reason:  "内容安全检测建议人工审核",
+		TraceId: result.TraceId,
+		Suggest: result.Result.Suggest,
+		Label:   result.Result.Label,
+	}
+}
+
+// newSpamReviewItem 根据反垃圾检测结果生成待审核项
+func newSpamReviewItem(content string, result *SpamCheckResult) *model.ModerationQueueModel {
+	return &model.ModerationQueueModel{
+		Source:  model.ModerationSourceSpam,
+		Content: content,
+		Reason:  result.Reason,
+	}
+}
+
+// Enqueue 将内容加入人工审核队列
+func (s *ModerationService) Enqueue(ctx context.Context, item *model.ModerationQueueModel) error {
+	item.Status = model.ModerationQueuePending
+	if err := s.queueDao.Create(ctx, item); err != nil {
+		return fmt.Errorf("加入审核队列失败: %v", err)
+	}
+	slog.InfoContext(ctx, "内容进入人工审核队列", "queue_id", item.Id, "target_type", item.TargetType,
+		"target_id", item.TargetId, "source", item.Source, "trace_id", item.TraceId)
+	return nil
+}
+
+// EnqueueAll 为刚创建的帖子或评论批量加入审核项，失败只记录日志，内容保持待审核状态
+func (s *ModerationService) EnqueueAll(ctx context.Context, items []*model.ModerationQueueModel, targetType string, targetId, postId, authorId int64) {
+	for _, item := range items {
+		item.TargetType = targetType
+		item.TargetId = targetId
+		item.PostId = postId
+		item.AuthorId = authorId
+		if err := s.Enqueue(ctx, item); err != nil {
+			slog.ErrorContext(ctx, "加入审核队列失败", "target_type", targetType, "target_id", targetId, "error", err)
+		}
+	}
+}
+
+// GetQueue 分页获取审核队列，status小于0时返回全部状态
+func (s *ModerationService) GetQueue(ctx context.Context, status int, targetType string, page, pageSize int) (*ModerationQueueListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 50 {
+		pageSize = 20
+	}
+
+	items, total, err := s.queueDao.GetList(ctx, status, targetType, page, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("获取审核队列失败: %v", err)
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+	return &ModerationQueueListResponse{
+		List: items,
+		Pagination: Pagination{
+			Current:  page,
+			PageSize: pageSize,
+			Total:    total,
+			HasMore:  page < totalPages,
+		},
+	}, nil
+}
+
+// Approve 审核通过
+func (s *ModerationService) Approve(ctx context.Context, id, reviewerId int64, note string) (*model.ModerationQueueModel, error) {
+	return s.resolve(ctx, id, true, reviewerId, note)
+}
+
+// Reject 审核驳回
+func (s *ModerationService) Reject(ctx context.Context, id, reviewerId int64, note string) (*model.ModerationQueueModel, error) {
+	return s.resolve(ctx, id, false, reviewerId, note)
+}
+
+// resolve 处理审核项并同步内容状态、通知作者。先应用审核结论，最后以待审核状态为条件更新审核项：
+// 应用失败时审核项仍为待审核，可以重新处理，各应用步骤都可以重复执行
+func (s *ModerationService) resolve(ctx context.Context, id int64, approved bool, reviewerId int64, note string) (*model.ModerationQueueModel, error) {
+	item, err := s.queueDao.GetById(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("审核项不存在: %v", err)
+	}
+	if item.Status != model.ModerationQueuePending {
+		return nil, fmt.Errorf("审核项已处理")
+	}
+
+	status := model.ModerationQueueRejected
+	if approved {
+		status = model.ModerationQueueApproved
+	}
+	item.ReviewerId = reviewerId
+	item.ReviewNote = note
+	if err := s.apply(ctx, item, approved); err != nil {
+		return nil, err
+	}
+
+	ok, err := s.queueDao.Resolve(ctx, id, status, reviewerId, note)
+	if err != nil {
+		return nil, fmt.Errorf("更新审核项失败: %v", err)
+	}
+	if !ok {
+		s.reapplyResolved(ctx, id, approved)
+		return nil, fmt.Errorf("审核项已处理")
+	}
+	item.Status = status
+
+	// 同一对象的多个审核项同时通过时，应用结论时彼此都还是待审核，审核项更新后再检查一次是否可以恢复展示
+	if approved {
+		if err := s.releaseTarget(ctx, item); err != nil {
+			slog.ErrorContext(ctx, "恢复审核对象展示失败", "queue_id", item.Id, "target_type", item.TargetType,
+				"target_id", item.TargetId, "error", err)
+		}
+	}
+
+	s.notifyAuthor(ctx, item, approved)
+
+	slog.InfoContext(ctx, "审核项已处理", "queue_id", item.Id, "target_type", item.TargetType,
+		"target_id", item.TargetId, "approved", approved, "reviewer_id", reviewerId)
+
+	return item, nil
+}
+
+// apply 按审核项类型应用人工结论
+func (s *ModerationService) apply(ctx context.Context, item *model.ModerationQueueModel, approved bool) error {
+	if item.TargetType == model.ModerationTargetUser {
+		return s.applyProfileDecision(ctx, item, approved)
+	}
+	if item.Source == model.ModerationSourceImage || item.Source == model.ModerationSourceAudio || item.Source == model.ModerationSourceVideo {
+		return s.applyMediaDecision(ctx, item, approved)
+	}
+	if item.TargetType == model.ModerationTargetComment {
+		return s.applyCommentDecision(ctx, item, approved)
+	}
+	return s.applyPostDecision(ctx, item, approved)
+}
+
+// releaseTarget 审核对象的所有审核项都已通过时恢复展示
+func (s *ModerationService) releaseTarget(ctx context.Context, item *model.ModerationQueueModel) error {
+	switch item.TargetType {
+	case model.ModerationTargetPost:
+		return s.applyPostDecision(ctx, item, true)
+	case model.ModerationTargetComment:
+		return s.applyCommentDecision(ctx, item, true)
+	}
+	return nil
+}
+
+// reapplyResolved 审核项被其他审核员同时处理且先完成时，本次已应用的结论可能与之不同，重新应用先完成的结论
+func (s *ModerationService) reapplyResolved(ctx context.Context, id int64, approved bool) {
+	current, err := s.queueDao.GetById(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "获取审核项失败", "queue_id", id, "error", err)
+		return
+	}
+	currentApproved := current.Status == model.ModerationQueueApproved
+	if current.Status == model.ModerationQueuePending || currentApproved == approved {
+		return
+	}
+	slog.WarnContext(ctx, "审核项已被其他审核员处理，重新应用其结论", "queue_id", id, "status", current.Status)
+	if err := s.apply(ctx, current, currentApproved); err != nil {
+		slog.ErrorContext(ctx, "重新应用审核结论失败", "queue_id", id, "error", err)
+	}
+}
+
+// applyMediaDecision 将人工结论写回媒体检测记录，语音评论同步评论审核状态，帖子媒体则重新汇总帖子媒体检测状态
+func (s *ModerationService) applyMediaDecision(ctx context.Context, item *model.ModerationQueueModel, approved bool) error {
+	status := model.MediaCheckStatusFailed
+	if approved {
+		status = model.MediaCheckStatusPassed
+	}
+	if err := s.setMediaCheckStatus(ctx, item, status); err != nil {
+		return err
+	}
+	if item.TargetType == model.ModerationTargetComment {
+		return s.applyCommentDecision(ctx, item, approved)
+	}
+	if err := syncPostMediaCheckStatus(ctx, s.postDao, item.PostId); err != nil {
+		return err
+	}
+	if approved {
+		return s.applyPostDecision(ctx, item, true)
+	}
+	return nil
+}
+
+// setMediaCheckStatus 将人工结论写回审核项对应的媒体检测记录。旧审核项没有记录检测记录ID时按trace_id更新，
+// 两者都为空时拒绝更新，避免误改其他尚未提交的检测记录
+func (s *ModerationService) setMediaCheckStatus(ctx context.Context, item *model.ModerationQueueModel, status int) error {
+	var err error
+	switch {
+	case item.MediaCheckId > 0:
+		err = s.mediaCheckDao.SetStatus(ctx, item.MediaCheckId, status)
+	case item.TraceId != "":
+		err = s.mediaCheckDao.SetStatusByTraceId(ctx, item.TraceId, status)
+	default:
+		return fmt.Errorf("审核项缺少媒体检测记录")
+	}
+	if err != nil {
+		return fmt.Errorf("更新媒体检测状态失败: %v", err)
+	}
+	return nil
+}
+
+// applyProfileDecision 将人工结论应用到用户资料：通过后以待审核值替换当前资料，驳回则丢弃待审核值。
+// 审核期间用户再次修改了该字段时，审核项对应的旧值不再生效
+func (s *ModerationService) applyProfileDecision(ctx context.Context, item *model.ModerationQueueModel, approved bool) error {
+	field := model.ProfileFieldAvatar
+	if item.Source == model.ModerationSourceImage {
+		status := model.MediaCheckStatusFailed
+		if approved {
+			status = model.MediaCheckStatusPassed
+		}
+		if err := s.setMediaCheckStatus(ctx, item, status); err != nil {
+			return err
+		}
+	} else {
+		user, err := s.userDao.GetById(ctx, item.TargetId)
+		if err != nil {
+			return fmt.Errorf("用户不存在: %v", err)
+		}
+		switch item.Content {
+		case user.PendingNickname:
+			field = model.ProfileFieldNickname
+		case user.PendingBio:
+			field = model.ProfileFieldBio
+		default:
+			slog.InfoContext(ctx, "资料已再次修改，审核结论不再生效", "queue_id", item.Id, "user_id", item.TargetId)
+			return nil
+		}
+	}
+
+	if _, err := s.userDao.ApplyPendingField(ctx, item.TargetId, field, item.Content, approved); err != nil {
+		return fmt.Errorf("更新用户资料失败: %v", err)
+	}
+	return nil
+}
+
+// applyPostDecision 同步帖子审核状态，帖子的所有审核项都通过后才恢复展示
+func (s *ModerationService) applyPostDecision(ctx context.Context, item *model.ModerationQueueModel, approved bool) error {
+	post, err := s.postDao.GetById(ctx, item.TargetId)
+	if err != nil {
+		return fmt.Errorf("帖子不存在: %v", err)
+	}
+
+	if !approved {
+		if err := s.postDao.UpdateModerationStatus(ctx, post.Id, model.ModerationStatusRejected, rejectReason(item)); err != nil {
+			return fmt.Errorf("更新帖子审核状态失败: %v", err)
+		}
+		return nil
+	}
+
+	if post.ModerationStatus != model.ModerationStatusPending {
+		return nil
+	}
+	pending, err := s.queueDao.CountPendingByTarget(ctx, model.ModerationTargetPost, post.Id, item.Id)
+	if err != nil {
+		return fmt.Errorf("统计待审核项失败: %v", err)
+	}
+	if pending > 0 {
+		return nil
+	}
+	if err := s.postDao.UpdateModerationStatus(ctx, post.Id, model.ModerationStatusNormal, ""); err != nil {
+		return fmt.Errorf("更新帖子审核状态失败: %v", err)
+	}
+	return nil
+}
+
+// applyCommentDecision 同步评论审核状态，评论恢复展示时计入帖子评论数
+func (s *ModerationService) applyCommentDecision(ctx context.Context, item *model.ModerationQueueModel, approved bool) error {
+	comment, err := s.commentDao.GetById(ctx, item.TargetId)
+	if err != nil {
+		return fmt.Errorf("评论不存在: %v", err)
+	}
+
+	if !approved {
+		if err := s.commentDao.UpdateModerationStatus(ctx, comment.Id, model.ModerationStatusRejected, rejectReason(item)); err != nil {
+			return fmt.Errorf("更新评论审核状态失败: %v", err)
+		}
+		return nil
+	}
+
+	return s.releaseComment(ctx, comment, item.Id)
+}
+
+// releaseComment 评论的所有审核项都通过且语音检测通过后恢复展示，并计入帖子评论数；excludeId为正在处理的审核项
+func (s *ModerationService) releaseComment(ctx context.Context, comment *model.CommentModel, excludeId int64) error {
+	if comment.ModerationStatus != model.ModerationStatusPending {
+		return nil
+	}
+	pending, err := s.queueDao.CountPendingByTarget(ctx, model.ModerationTargetComment, comment.Id, excludeId)
+	if err != nil {
+		return fmt.Errorf("统计待审核项失败: %v", err)
+	}
+	if pending > 0 {
+		return nil
+	}
+	if comment.Audio != "" {
+		checks, err := s.mediaCheckDao.GetByCommentId(ctx, comment.Id)
+		if err != nil {
+			return fmt.Errorf("获取语音检测记录失败: %v", err)
+		}
+		for _, check := range checks {
+			if check.Status != model.MediaCheckStatusPassed {
+				return nil
+			}
+		}
+	}
+
+	// 文本审核和语音检测可能同时完成，只有实际改变状态的一方计入评论数
+	released, err := s.commentDao.ReleasePending(ctx, comment.Id)
+	if err != nil {
+		return fmt.Errorf("更新评论审核状态失败: %v", err)
+	}
+	if !released {
+		return nil
+	}
+	if err := s.postDao.IncrementComments(ctx, comment.PostId); err != nil {
+		// 记录错误但不影响主流程
+		slog.ErrorContext(ctx, "更新帖子评论数失败", "post_id", comment.PostId, "error", err)
+	}
+	return nil
+}
+
+// SettleCommentMedia 根据语音评论的检测动作更新评论：allow 在没有其他待审核项时公开评论，review 进入人工审核，
+// shadow 改为仅作者可见，reject 驳回评论
+func (s *ModerationService) SettleCommentMedia(ctx context.Context, check *model.MediaCheckModel, action, reason, suggest string, label int) error {
+	comment, err := s.commentDao.GetById(ctx, check.CommentId)
+	if err != nil {
+		return fmt.Errorf("评论不存在: %v", err)
+	}
+
+	status := model.ModerationStatusRejected
+	switch action {
+	case PolicyActionAllow:
+		return s.releaseComment(ctx, comment, 0)
+	case PolicyActionReview:
+		return s.Enqueue(ctx, &model.ModerationQueueModel{
+			TargetType:   model.ModerationTargetComment,
+			TargetId:     comment.Id,
+			PostId:       comment.PostId,
+			AuthorId:     comment.AuthorId,
+			Source:       mediaModerationSource(check),
+			Content:      check.MediaURL,
+			Reason:       reason,
+			TraceId:      check.TraceId,
+			MediaCheckId: check.Id,
+			Suggest:      suggest,
+			Label:        label,
+		})
+	case PolicyActionShadow:
+		status = model.ModerationStatusShadowHidden
+	}
+
+	// 已驳回的评论不再改变状态；公开后的评论不会再有检测结论
+	if comment.ModerationStatus == model.ModerationStatusRejected || comment.ModerationStatus == model.ModerationStatusNormal {
+		return nil
+	}
+	if err := s.commentDao.UpdateModerationStatus(ctx, comment.Id, status, reason); err != nil {
+		return fmt.Errorf("更新评论审核状态失败: %v", err)
+	}
+	return nil
+}
+
+// notifyAuthor 通知作者审核结果，失败只记录日志
+func (s *ModerationService) notifyAuthor(ctx context.Context, item *model.ModerationQueueModel, approved bool) {
+	targetName := "帖子"
+	switch item.TargetType {
+	case model.ModerationTargetComment:
+		targetName = "评论"
+	case model.ModerationTargetUser:
+		targetName = "资料"
+	}
+
+	notification := &model.NotificationModel{
+		UserId:     item.AuthorId,
+		TargetType: item.TargetType,
+		TargetId:   item.TargetId,
+	}
+	if approved {
+		notification.Type = model.NotificationModerationApproved
+		notification.Title = fmt.Sprintf("你的%s已通过审核", targetName)
+		notification.Content = fmt.Sprintf("你发布的%s已通过人工审核，现已公开展示。", targetName)
+	} else {
+		notification.Type = model.NotificationModerationRejected
+		notification.Title = fmt.Sprintf("你的%s未通过审核", targetName)
+		notification.Content = fmt.Sprintf("你发布的%s未通过人工审核：%s", targetName, rejectReason(item))
+	}
+
+	if err := s.notificationDao.Create(ctx, notification); err != nil {
+		slog.ErrorContext(ctx, "发送审核结果通知失败", "queue_id", item.Id, "user_id", item.AuthorId, "error", err)
+	}
+}
+
+// rejectReason 驳回原因，优先使用审核备注
+func rejectReason(item *model.ModerationQueueModel) string {
+	if item.ReviewNote != "" {
+		return item.ReviewNote
+	}
+	return "内容不符合社区规范"
+}
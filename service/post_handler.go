@@ -10,12 +10,14 @@ import (
 // PostHandler 帖子处理器
 type PostHandler struct {
 	postService *PostService
+	urlResolver *TempURLResolver
 }
 
 // NewPostHandler 创建帖子处理器实例
 func NewPostHandler() *PostHandler {
 	return &PostHandler{
 		postService: NewPostService(),
+		urlResolver: NewTempURLResolver(),
 	}
 }
 
@@ -53,11 +55,14 @@ func (h *PostHandler) GetPostListHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// 调用服务
-	result, err := h.postService.GetPostList(page, pageSize, category, sort, userId)
+	result, err := h.postService.GetPostList(r.Context(), page, pageSize, category, sort, userId)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if wantTempURLs(r) {
+		h.urlResolver.ResolvePosts(r.Context(), result.List)
+	}
 
 	// 返回响应
 	response := map[string]interface{}{
@@ -100,7 +105,11 @@ func (h *PostHandler) CreatePostHandler(w http.ResponseWriter, r *http.Request)
 	openid := r.Header.Get("x-wx-openid")
 
 	// 调用服务
-	result, err := h.postService.CreatePost(&req, userId, openid)
+	result, err := h.postService.CreatePost(r.Context(), &req, userId, openid)
+	if IsAccountRestricted(err) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -142,11 +151,14 @@ func (h *PostHandler) GetPostDetailHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	// 调用服务
-	result, err := h.postService.GetPostDetail(postId, userId)
+	result, err := h.postService.GetPostDetail(r.Context(), postId, userId)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if wantTempURLs(r) {
+		h.urlResolver.ResolvePosts(r.Context(), []*PostDetail{result})
+	}
 
 	// 返回响应
 	response := map[string]interface{}{
@@ -192,7 +204,7 @@ func (h *PostHandler) DeletePostHandler(w http.ResponseWriter, r *http.Request)
 	userId := userCtx.User.Id
 
 	// 调用服务
-	err = h.postService.SoftDeletePost(postId, userId)
+	err = h.postService.SoftDeletePost(r.Context(), postId, userId)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -248,11 +260,14 @@ func (h *PostHandler) GetMyPostsHandler(w http.ResponseWriter, r *http.Request)
 	userId := userCtx.User.Id
 
 	// 调用服务
-	result, err := h.postService.GetUserPosts(userId, page, pageSize)
+	result, err := h.postService.GetUserPosts(r.Context(), userId, page, pageSize)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if wantTempURLs(r) {
+		h.urlResolver.ResolvePosts(r.Context(), result.List)
+	}
 
 	// 返回响应
 	response := map[string]interface{}{
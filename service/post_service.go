@@ -1,51 +1,77 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"math"
 	"time"
 	"wxcloudrun-golang/db/dao"
 	"wxcloudrun-golang/db/model"
+	"wxcloudrun-golang/logger"
+	"wxcloudrun-golang/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
 )
 
 // PostService 帖子服务
 type PostService struct {
-	postDao         dao.PostDao
-	userDao         dao.UserDao
-	categoryDao     dao.CategoryDao
-	userLikeDao     dao.UserLikeDao
-	imageCheckDao   dao.ImageCheckDao
-	securityService *ContentSecurityService
+	postDao           dao.PostDao
+	userDao           dao.UserDao
+	categoryDao       dao.CategoryDao
+	userLikeDao       dao.UserLikeDao
+	mediaCheckDao     dao.MediaCheckDao
+	videoDao          dao.PostVideoDao
+	cloudStorage      *WechatCloudStorageService
+	urlResolver       *TempURLResolver
+	textModerator     *TextModerator
+	spamService       *SpamService
+	moderationService *ModerationService
+	accountService    *AccountService
+	contentChecks     *ContentCheckService
 }
 
 // NewPostService 创建帖子服务实例
 func NewPostService() *PostService {
 	return &PostService{
-		postDao:         dao.NewPostDao(),
-		userDao:         dao.NewUserDao(),
-		categoryDao:     dao.NewCategoryDao(),
-		userLikeDao:     dao.NewUserLikeDao(),
-		imageCheckDao:   dao.NewImageCheckDao(),
-		securityService: NewContentSecurityService(),
+		postDao:           dao.NewPostDao(),
+		userDao:           dao.NewUserDao(),
+		categoryDao:       dao.NewCategoryDao(),
+		userLikeDao:       dao.NewUserLikeDao(),
+		mediaCheckDao:     dao.NewMediaCheckDao(),
+		videoDao:          dao.NewPostVideoDao(),
+		cloudStorage:      NewWechatCloudStorageService(),
+		urlResolver:       NewTempURLResolver(),
+		textModerator:     NewTextModerator(),
+		spamService:       NewSpamService(),
+		moderationService: NewModerationService(),
+		accountService:    NewAccountService(),
+		contentChecks:     NewContentCheckService(),
 	}
 }
 
 // CreatePostRequest 创建帖子请求
 type CreatePostRequest struct {
-	Title    string   `json:"title"`
-	Content  string   `json:"content"`
-	Category string   `json:"category"`
-	Tags     []string `json:"tags"`
-	Images   []string `json:"images"`
-	IsPublic bool     `json:"isPublic"`
+	Title         string           `json:"title"`
+	Content       string           `json:"content"`
+	Category      string           `json:"category"`
+	Tags          []string         `json:"tags"`
+	Images        []string         `json:"images"`
+	Audio         string           `json:"audio"`         // 语音地址（cloud:// 文件ID或URL），可选
+	AudioDuration int              `json:"audioDuration"` // 语音时长（秒）
+	Video         *VideoAttachment `json:"video"`         // 视频附件，可选
+	IsPublic      bool             `json:"isPublic"`
 }
 
 // CreatePostResponse 创建帖子响应
 type CreatePostResponse struct {
-	PostId    int64     `json:"postId"`
-	CreatedAt time.Time `json:"createdAt"`
-	URL       string    `json:"url"`
+	PostId      int64     `json:"postId"`
+	CreatedAt   time.Time `json:"createdAt"`
+	URL         string    `json:"url"`
+	UnderReview bool      `json:"underReview,omitempty"` // 帖子进入人工审核，审核通过前仅作者可见
 }
 
 // PostListResponse 帖子列表响应
@@ -56,20 +82,25 @@ type PostListResponse struct {
 
 // PostDetail 帖子详情
 type PostDetail struct {
-	Id           int64     `json:"id"`
-	Title        string    `json:"title"`
-	Excerpt      string    `json:"excerpt"`
-	Content      string    `json:"content"`
-	Author       UserInfo  `json:"author"`
-	Category     string    `json:"category"`
-	CategoryName string    `json:"categoryName"`
-	Tags         []string  `json:"tags"`
-	Images       []string  `json:"images"`
-	Stats        PostStats `json:"stats"`
-	IsLiked      bool      `json:"isLiked"`
-	IsCollected  bool      `json:"isCollected"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
+	Id            int64        `json:"id"`
+	Title         string       `json:"title"`
+	Excerpt       string       `json:"excerpt"`
+	Content       string       `json:"content"`
+	Author        UserInfo     `json:"author"`
+	Category      string       `json:"category"`
+	CategoryName  string       `json:"categoryName"`
+	Tags          []string     `json:"tags"`
+	Images        []string     `json:"images"`
+	ImageURLs     []string     `json:"imageUrls,omitempty"` // 图片的临时访问地址，与images一一对应，请求带 resolveUrls=true 时返回
+	Audio         string       `json:"audio,omitempty"`
+	AudioURL      string       `json:"audioUrl,omitempty"`
+	AudioDuration int          `json:"audioDuration,omitempty"`
+	Video         *VideoDetail `json:"video,omitempty"`
+	Stats         PostStats    `json:"stats"`
+	IsLiked       bool         `json:"isLiked"`
+	IsCollected   bool         `json:"isCollected"`
+	CreatedAt     time.Time    `json:"createdAt"`
+	UpdatedAt     time.Time    `json:"updatedAt"`
 }
 
 // UserInfo 用户信息
@@ -77,6 +108,7 @@ type UserInfo struct {
 	Id         int64  `json:"id"`
 	Nickname   string `json:"nickname"`
 	Avatar     string `json:"avatar"`
+	AvatarURL  string `json:"avatarUrl,omitempty"` // 头像的临时访问地址，请求带 resolveUrls=true 时返回
 	Bio        string `json:"bio"`
 	Level      int    `json:"level"`
 	IsVerified bool   `json:"isVerified"`
@@ -99,37 +131,86 @@ type Pagination struct {
 }
 
 // CreatePost 创建帖子
-func (s *PostService) CreatePost(req *CreatePostRequest, authorId int64, openid string) (*CreatePostResponse, error) {
+func (s *PostService) CreatePost(ctx context.Context, req *CreatePostRequest, authorId int64, openid string) (resp *CreatePostResponse, err error) {
+	ctx, span := tracing.Start(ctx, "PostService.CreatePost")
+	span.SetAttributes(attribute.Int64("user.id", authorId), attribute.Int("post.image_count", len(req.Images)))
+	defer func() { tracing.End(span, err) }()
+
+	if err := validateAudio(req.Audio, req.AudioDuration); err != nil {
+		return nil, err
+	}
+	if err := validateVideo(req.Video, s.cloudStorage); err != nil {
+		return nil, err
+	}
+
 	// 验证分类是否存在
-	category, err := s.categoryDao.GetByCode(req.Category)
+	category, err := sharedCategoryCache.GetActiveByCode(ctx, s.categoryDao, req.Category)
 	if err != nil {
 		return nil, fmt.Errorf("分类不存在: %v", err)
 	}
 
-	// 内容安全校验
-	if openid != "" {
-		// 检查标题安全性（使用论坛场景）
-		if req.Title != "" {
-			isSafe, err := s.securityService.IsContentSafe(openid, req.Title, SceneForum)
-			if err != nil {
-				return nil, fmt.Errorf("标题安全检测失败: %v", err)
-			}
-			if !isSafe {
-				return nil, fmt.Errorf("标题包含违规内容，请修改后重试")
-			}
+	// 反垃圾检测（先于微信内容安全检测，避免垃圾内容消耗检测额度）
+	author, err := s.userDao.GetById(ctx, authorId)
+	if err != nil {
+		return nil, fmt.Errorf("用户不存在: %v", err)
+	}
+	if err := checkAccountWritable(author); err != nil {
+		return nil, err
+	}
+	moderationStatus := model.ModerationStatusNormal
+	moderationReason := ""
+	var reviewItems []*model.ModerationQueueModel
+	spamResult, spamErr := s.spamService.CheckPost(ctx, author, req.Title, req.Content)
+	if spamErr != nil {
+		// 反垃圾检测异常时不阻断发布
+		slog.ErrorContext(ctx, "反垃圾检测失败", "user_id", authorId, "error", spamErr)
+	} else {
+		moderationStatus, err = spamModerationStatus(spamResult)
+		if err != nil {
+			return nil, err
 		}
-
-		// 检查内容安全性（使用论坛场景）
-		if req.Content != "" {
-			isSafe, err := s.securityService.IsContentSafe(openid, req.Content, SceneForum)
-			if err != nil {
-				return nil, fmt.Errorf("内容安全检测失败: %v", err)
-			}
-			if !isSafe {
-				return nil, fmt.Errorf("内容包含违规信息，请修改后重试")
-			}
+		moderationReason = spamResult.Reason
+		if moderationStatus == model.ModerationStatusPending {
+			reviewItems = append(reviewItems, newSpamReviewItem(req.Title+"\n"+req.Content, spamResult))
 		}
+	}
 
+	// 记录本次发帖的全部内容检测，帖子被拒绝时目标ID为0
+	checkLog := NewContentCheckLog(model.ContentCheckTargetPost, authorId)
+	var createdPostId int64
+	defer func() { s.contentChecks.Save(ctx, checkLog, createdPostId, createdPostId) }()
+
+	// 内容安全校验（本地关键词过滤 + 微信内容安全检测），按审核策略处理：
+	// 待审核的内容先发布为待审核状态，命中静默隐藏策略的内容仅作者可见
+	shadowed := false
+	for _, field := range []struct{ name, text, violation, message string }{
+		{"title", req.Title, "post_title", "标题包含违规内容，请修改后重试"},
+		{"content", req.Content, "post_content", "内容包含违规信息，请修改后重试"},
+	} {
+		if field.text == "" {
+			continue
+		}
+		outcome, err := s.textModerator.Check(ctx, checkLog, openid, field.name, SceneForum, field.text)
+		if err != nil {
+			return nil, err
+		}
+		switch outcome.Action {
+		case PolicyActionAllow:
+		case PolicyActionReview:
+			reviewItems = append(reviewItems, outcome.ReviewItem(field.text))
+		case PolicyActionShadow:
+			shadowed = true
+		default:
+			s.accountService.RecordViolation(ctx, authorId, field.violation)
+			return nil, errors.New(field.message)
+		}
+	}
+	if shadowed {
+		moderationStatus = model.ModerationStatusShadowHidden
+		moderationReason = "内容命中审核策略"
+	} else if len(reviewItems) > 0 && moderationStatus == model.ModerationStatusNormal {
+		moderationStatus = model.ModerationStatusPending
+		moderationReason = "内容待人工审核"
 	}
 
 	// 处理标签和图片
@@ -152,124 +233,118 @@ func (s *PostService) CreatePost(req *CreatePostRequest, authorId int64, openid
 		CategoryName:     category.Name,
 		Tags:             string(tagsJSON),
 		Images:           string(imagesJSON),
+		Audio:            req.Audio,
+		AudioDuration:    req.AudioDuration,
 		ImageCheckStatus: 0, // 初始状态：待检测
+		ModerationStatus: moderationStatus,
+		ModerationReason: moderationReason,
 		IsPublic:         req.IsPublic,
 	}
 
-	// 检查图片安全性（使用论坛场景）
-	if len(req.Images) > 0 {
-		// 先创建帖子，获取帖子ID
-		err = s.postDao.Create(post)
-		if err != nil {
-			return nil, fmt.Errorf("创建帖子失败: %v", err)
+	// 有图片、语音或视频的帖子：在同一事务中写入帖子、待检测记录和检测任务，由后台worker提交检测，
+	// 单个媒体提交失败时单独重试，请求无需等待微信接口；所有媒体检测通过前帖子不公开展示
+	var mediaChecks []*model.MediaCheckModel
+	for _, imageURL := range req.Images {
+		if imageURL != "" {
+			mediaChecks = append(mediaChecks, &model.MediaCheckModel{
+				MediaType: model.MediaTypeImage,
+				MediaURL:  imageURL,
+				Status:    model.MediaCheckStatusPending,
+			})
 		}
-
-		// 设置帖子状态为检测中
-		post.ImageCheckStatus = 1 // 检测中
-		err = s.postDao.Update(post)
-		if err != nil {
-			return nil, fmt.Errorf("更新帖子状态失败: %v", err)
+	}
+	if req.Audio != "" {
+		mediaChecks = append(mediaChecks, &model.MediaCheckModel{
+			MediaType: model.MediaTypeAudio,
+			MediaURL:  req.Audio,
+			Status:    model.MediaCheckStatusPending,
+		})
+	}
+	// 视频封面按帖子图片检测，视频由视频检测器检测
+	var video *model.PostVideoModel
+	if req.Video != nil {
+		video = &model.PostVideoModel{
+			FileId:   req.Video.FileId,
+			Duration: req.Video.Duration,
+			Cover:    req.Video.Cover,
+			Width:    req.Video.Width,
+			Height:   req.Video.Height,
 		}
-
-		// 检测每张图片
-		fmt.Printf("=== 开始检测帖子图片 - PostId: %d ===\n", post.Id)
-		fmt.Printf("需要检测 %d 张图片\n", len(req.Images))
-		
-		for i, imageURL := range req.Images {
-			if imageURL != "" {
-				fmt.Printf("检测图片 %d: %s\n", i+1, imageURL)
-				
-				var result *MediaCheckResponse
-				var err error
-				
-				// 判断是否为云存储文件ID，如果是则进行转换
-				if s.securityService.cloudStorage.ValidateCloudID(imageURL) {
-					fmt.Printf("检测到云存储文件ID，进行转换: %s\n", imageURL)
-					
-					// 使用云存储文件ID进行检测
-					result, err = s.securityService.CheckCloudStorageImageSecurity(imageURL, openid, SceneForum)
-					if err != nil {
-						fmt.Printf("❌ 云存储图片%d检测失败: %v\n", i+1, err)
-						return nil, fmt.Errorf("云存储图片安全检测失败: %v", err)
-					}
-				} else {
-					// 直接使用URL进行检测
-					result, err = s.securityService.CheckImageSecurity(imageURL, openid, SceneForum)
-					if err != nil {
-						fmt.Printf("❌ 图片%d检测失败: %v\n", i+1, err)
-						return nil, fmt.Errorf("图片安全检测失败: %v", err)
-					}
-				}
-				
-				// 检查检测请求是否成功提交
-				if !s.securityService.IsMediaCheckSuccess(result) {
-					errorMsg := s.securityService.GetMediaCheckError(result)
-					fmt.Printf("❌ 图片%d检测请求失败: %s\n", i+1, errorMsg)
-					return nil, fmt.Errorf("图片安全检测请求失败: %s", errorMsg)
-				}
-				
-				// 记录检测请求到数据库
-				imageCheck := &model.ImageCheckModel{
-					PostId:   post.Id,
-					ImageURL: imageURL,
-					TraceId:  result.TraceId,
-					Status:   model.ImageCheckStatusChecking, // 检测中
-				}
-				
-				err = s.imageCheckDao.Create(imageCheck)
-				if err != nil {
-					fmt.Printf("❌ 记录图片%d检测信息失败: %v\n", i+1, err)
-					return nil, fmt.Errorf("记录图片检测信息失败: %v", err)
-				}
-				
-				fmt.Printf("✅ 图片%d检测请求已提交，追踪ID: %s\n", i+1, result.TraceId)
-			}
+		mediaChecks = append(mediaChecks, &model.MediaCheckModel{
+			MediaType: model.MediaTypeImage,
+			MediaURL:  req.Video.Cover,
+			Status:    model.MediaCheckStatusPending,
+		}, &model.MediaCheckModel{
+			MediaType: model.MediaTypeVideo,
+			MediaURL:  req.Video.FileId,
+			Status:    model.MediaCheckStatusPending,
+		})
+	}
+	if len(mediaChecks) > 0 {
+		post.ImageCheckStatus = model.MediaCheckStatusChecking
+		err = s.postDao.CreateWithMediaChecks(ctx, post, video, mediaChecks, func(check *model.MediaCheckModel) (*model.JobModel, error) {
+			return newMediaCheckJob(check, openid)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("创建帖子失败: %v", err)
 		}
-		
-		fmt.Printf("=== 图片检测请求提交完成 ===\n")
+		span.SetAttributes(attribute.Int64("post.id", post.Id))
+		createdPostId = post.Id
+		s.moderationService.EnqueueAll(ctx, reviewItems, model.ModerationTargetPost, post.Id, post.Id, authorId)
+		slog.InfoContext(ctx, "帖子媒体检测任务已创建", "post_id", post.Id, "media_count", len(mediaChecks), "has_audio", req.Audio != "", "has_video", video != nil)
 
 		// 返回帖子信息，但状态为检测中
 		return &CreatePostResponse{
-			PostId:    post.Id,
-			CreatedAt: post.CreatedAt,
+			PostId:      post.Id,
+			CreatedAt:   post.CreatedAt,
+			UnderReview: post.ModerationStatus == model.ModerationStatusPending,
 		}, nil
 	}
 
-	err = s.postDao.Create(post)
+	err = s.postDao.Create(ctx, post)
 	if err != nil {
 		return nil, fmt.Errorf("创建帖子失败: %v", err)
 	}
+	createdPostId = post.Id
+	s.moderationService.EnqueueAll(ctx, reviewItems, model.ModerationTargetPost, post.Id, post.Id, authorId)
 
 	// 更新分类帖子数量
-	err = s.categoryDao.IncrementPostCount(req.Category)
+	err = s.categoryDao.IncrementPostCount(ctx, req.Category)
 	if err != nil {
 		// 记录错误但不影响主流程
-		fmt.Printf("更新分类帖子数量失败: %v\n", err)
+		slog.ErrorContext(ctx, "更新分类帖子数量失败", "category", req.Category, "error", err)
 	}
 
 	return &CreatePostResponse{
-		PostId:    post.Id,
-		CreatedAt: post.CreatedAt,
+		PostId:      post.Id,
+		CreatedAt:   post.CreatedAt,
+		UnderReview: post.ModerationStatus == model.ModerationStatusPending,
 	}, nil
 }
 
 // GetPostDetail 获取帖子详情
-func (s *PostService) GetPostDetail(postId int64, userId int64) (*PostDetail, error) {
+func (s *PostService) GetPostDetail(ctx context.Context, postId int64, userId int64) (*PostDetail, error) {
 	// 获取帖子信息
-	post, err := s.postDao.GetById(postId)
+	post, err := s.postDao.GetById(ctx, postId)
 	if err != nil {
 		return nil, fmt.Errorf("帖子不存在: %v", err)
 	}
 
-	// 增加浏览量
+	// 未通过审核或图片待人工审核的帖子只对作者本人可见
+	if (post.ModerationStatus != model.ModerationStatusNormal || post.ImageCheckStatus == model.MediaCheckStatusReview) && post.AuthorId != userId {
+		return nil, fmt.Errorf("帖子不存在")
+	}
+
+	// 增加浏览量（异步执行，不随请求取消）
+	viewCtx := logger.WithRequestId(context.Background(), logger.RequestIdFromContext(ctx))
 	go func() {
-		if err := s.postDao.IncrementViews(postId); err != nil {
-			fmt.Printf("增加浏览量失败: %v\n", err)
+		if err := s.postDao.IncrementViews(viewCtx, postId); err != nil {
+			slog.ErrorContext(viewCtx, "增加浏览量失败", "post_id", postId, "error", err)
 		}
 	}()
 
 	// 获取作者信息
-	author, err := s.userDao.GetById(post.AuthorId)
+	author, err := s.userDao.GetById(ctx, post.AuthorId)
 	if err != nil {
 		// 如果获取作者信息失败，使用默认信息
 		author = &model.UserModel{
@@ -288,10 +363,19 @@ func (s *PostService) GetPostDetail(postId int64, userId int64) (*PostDetail, er
 	json.Unmarshal([]byte(post.Tags), &tags)
 	json.Unmarshal([]byte(post.Images), &images)
 
+	// 视频附件，播放地址换取失败时只返回文件ID
+	var videoDetail *VideoDetail
+	if video, err := s.videoDao.GetByPostId(ctx, post.Id); err == nil {
+		videoDetail = newVideoDetail(video)
+		videoDetail.URL = s.urlResolver.URL(ctx, video.FileId)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		slog.ErrorContext(ctx, "获取帖子视频失败", "post_id", post.Id, "error", err)
+	}
+
 	// 检查是否点赞
 	isLiked := false
 	if userId != 0 {
-		likedPostIds, err := s.userLikeDao.GetUserLikedPostIds(userId)
+		likedPostIds, err := s.userLikeDao.GetUserLikedPostIds(ctx, userId)
 		if err == nil {
 			for _, likedId := range likedPostIds {
 				if likedId == post.Id {
@@ -315,10 +399,13 @@ func (s *PostService) GetPostDetail(postId int64, userId int64) (*PostDetail, er
 			Level:      author.Level,
 			IsVerified: author.IsVerified,
 		},
-		Category:     post.Category,
-		CategoryName: post.CategoryName,
-		Tags:         tags,
-		Images:       images,
+		Category:      post.Category,
+		CategoryName:  post.CategoryName,
+		Tags:          tags,
+		Images:        images,
+		Audio:         post.Audio,
+		AudioDuration: post.AudioDuration,
+		Video:         videoDetail,
 		Stats: PostStats{
 			Likes:    post.Likes,
 			Comments: post.Comments,
@@ -334,10 +421,37 @@ func (s *PostService) GetPostDetail(postId int64, userId int64) (*PostDetail, er
 	return postDetail, nil
 }
 
+// loadVideos 批量获取帖子的视频附件，失败时只记录日志，列表不返回视频
+func (s *PostService) loadVideos(ctx context.Context, posts []*model.PostModel) map[int64]*model.PostVideoModel {
+	postIds := make([]int64, 0, len(posts))
+	for _, post := range posts {
+		postIds = append(postIds, post.Id)
+	}
+	videos, err := s.videoDao.GetByPostIds(ctx, postIds)
+	if err != nil {
+		slog.ErrorContext(ctx, "获取帖子视频失败", "error", err)
+	}
+	return videos
+}
+
+// newVideoDetail 构造视频详情，没有视频时返回nil
+func newVideoDetail(video *model.PostVideoModel) *VideoDetail {
+	if video == nil {
+		return nil
+	}
+	return &VideoDetail{
+		FileId:   video.FileId,
+		Duration: video.Duration,
+		Cover:    video.Cover,
+		Width:    video.Width,
+		Height:   video.Height,
+	}
+}
+
 // SoftDeletePost 逻辑删除帖子
-func (s *PostService) SoftDeletePost(postId int64, userId int64) error {
+func (s *PostService) SoftDeletePost(ctx context.Context, postId int64, userId int64) error {
 	// 获取帖子信息
-	post, err := s.postDao.GetById(postId)
+	post, err := s.postDao.GetById(ctx, postId)
 	if err != nil {
 		return fmt.Errorf("帖子不存在: %v", err)
 	}
@@ -348,7 +462,7 @@ func (s *PostService) SoftDeletePost(postId int64, userId int64) error {
 	}
 
 	// 执行逻辑删除
-	err = s.postDao.SoftDelete(postId)
+	err = s.postDao.SoftDelete(ctx, postId)
 	if err != nil {
 		return fmt.Errorf("删除帖子失败: %v", err)
 	}
@@ -357,7 +471,7 @@ func (s *PostService) SoftDeletePost(postId int64, userId int64) error {
 }
 
 // GetPostList 获取帖子列表
-func (s *PostService) GetPostList(page, pageSize int, category, sort string, userId int64) (*PostListResponse, error) {
+func (s *PostService) GetPostList(ctx context.Context, page, pageSize int, category, sort string, userId int64) (*PostListResponse, error) {
 	// 参数验证
 	if page < 1 {
 		page = 1
@@ -367,7 +481,7 @@ func (s *PostService) GetPostList(page, pageSize int, category, sort string, use
 	}
 
 	// 获取帖子列表（只显示图片检测通过的帖子）
-	posts, total, err := s.postDao.GetListWithImageCheck(page, pageSize, category, sort)
+	posts, total, err := s.postDao.GetListWithImageCheck(ctx, page, pageSize, category, sort, userId)
 	if err != nil {
 		return nil, fmt.Errorf("获取帖子列表失败: %v", err)
 	}
@@ -375,18 +489,19 @@ func (s *PostService) GetPostList(page, pageSize int, category, sort string, use
 	// 获取用户点赞的帖子ID列表
 	var likedPostIds []int64
 	if userId != 0 {
-		likedPostIds, err = s.userLikeDao.GetUserLikedPostIds(userId)
+		likedPostIds, err = s.userLikeDao.GetUserLikedPostIds(ctx, userId)
 		if err != nil {
 			// 记录错误但不影响主流程
-			fmt.Printf("获取用户点赞列表失败: %v\n", err)
+			slog.ErrorContext(ctx, "获取用户点赞列表失败", "user_id", userId, "error", err)
 		}
 	}
 
 	// 构建响应数据
+	videos := s.loadVideos(ctx, posts)
 	postDetails := make([]*PostDetail, 0, len(posts))
 	for _, post := range posts {
 		// 获取作者信息
-		author, err := s.userDao.GetById(post.AuthorId)
+		author, err := s.userDao.GetById(ctx, post.AuthorId)
 		if err != nil {
 			// 如果获取作者信息失败，使用默认信息
 			author = &model.UserModel{
@@ -429,10 +544,13 @@ func (s *PostService) GetPostList(page, pageSize int, category, sort string, use
 				Level:      author.Level,
 				IsVerified: author.IsVerified,
 			},
-			Category:     post.Category,
-			CategoryName: post.CategoryName,
-			Tags:         tags,
-			Images:       images,
+			Category:      post.Category,
+			CategoryName:  post.CategoryName,
+			Tags:          tags,
+			Images:        images,
+			Audio:         post.Audio,
+			AudioDuration: post.AudioDuration,
+			Video:         newVideoDetail(videos[post.Id]),
 			Stats: PostStats{
 				Likes:    post.Likes,
 				Comments: post.Comments,
@@ -463,7 +581,7 @@ func (s *PostService) GetPostList(page, pageSize int, category, sort string, use
 }
 
 // GetUserPosts 获取用户发布的帖子列表
-func (s *PostService) GetUserPosts(userId int64, page, pageSize int) (*PostListResponse, error) {
+func (s *PostService) GetUserPosts(ctx context.Context, userId int64, page, pageSize int) (*PostListResponse, error) {
 	// 参数验证
 	if page < 1 {
 		page = 1
@@ -473,16 +591,17 @@ func (s *PostService) GetUserPosts(userId int64, page, pageSize int) (*PostListR
 	}
 
 	// 获取用户发布的帖子列表
-	posts, total, err := s.postDao.GetUserPosts(userId, page, pageSize)
+	posts, total, err := s.postDao.GetUserPosts(ctx, userId, page, pageSize)
 	if err != nil {
 		return nil, fmt.Errorf("获取用户帖子列表失败: %v", err)
 	}
 
 	// 构建响应数据
+	videos := s.loadVideos(ctx, posts)
 	postDetails := make([]*PostDetail, 0, len(posts))
 	for _, post := range posts {
 		// 获取作者信息
-		author, err := s.userDao.GetById(post.AuthorId)
+		author, err := s.userDao.GetById(ctx, post.AuthorId)
 		if err != nil {
 			// 如果获取作者信息失败，使用默认信息
 			author = &model.UserModel{
@@ -517,10 +636,13 @@ func (s *PostService) GetUserPosts(userId int64, page, pageSize int) (*PostListR
 				Level:      author.Level,
 				IsVerified: author.IsVerified,
 			},
-			Category:     post.Category,
-			CategoryName: post.CategoryName,
-			Tags:         tags,
-			Images:       images,
+			Category:      post.Category,
+			CategoryName:  post.CategoryName,
+			Tags:          tags,
+			Images:        images,
+			Audio:         post.Audio,
+			AudioDuration: post.AudioDuration,
+			Video:         newVideoDetail(videos[post.Id]),
 			Stats: PostStats{
 				Likes:    post.Likes,
 				Comments: post.Comments,
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"unicode/utf8"
+	"wxcloudrun-golang/db/dao"
+	"wxcloudrun-golang/db/model"
+)
+
+// ProfileUpdate 资料修改内容，空字段表示不修改
+type ProfileUpdate struct {
+	Nickname string `json:"nickname"`
+	Avatar   string `json:"avatar"`
+	Bio      string `json:"bio"`
+}
+
+// ProfileModerationService 用户资料审核：昵称和简介以资料场景做文本检测，头像提交异步图片检测。
+// 新资料在通过审核前保存在 pending_ 字段中仅本人可见，其他用户看到的仍是原资料
+type ProfileModerationService struct {
+	userDao           dao.UserDao
+	notificationDao   dao.NotificationDao
+	textModerator     *TextModerator
+	moderationService *ModerationService
+	accountService    *AccountService
+	contentChecks     *ContentCheckService
+}
+
+// NewProfileModerationService 创建资料审核服务实例
+func NewProfileModerationService() *ProfileModerationService {
+	return &ProfileModerationService{
+		userDao:           dao.NewUserDao(),
+		notificationDao:   dao.NewNotificationDao(),
+		textModerator:     NewTextModerator(),
+		moderationService: NewModerationService(),
+		accountService:    NewAccountService(),
+		contentChecks:     NewContentCheckService(),
+	}
+}
+
+// UpdateProfile 检测并保存资料修改：检测通过的昵称和简介立即生效，需人工审核的先保存为待审核值并进入审核队列，
+// 新头像在检测通过前保存为待审核值。保存成功后user更新为最新资料
+func (s *ProfileModerationService) UpdateProfile(ctx context.Context, user *model.UserModel, openid string, update *ProfileUpdate) error {
+	checkLog := NewContentCheckLog(model.ContentCheckTargetUser, user.Id)
+	defer s.contentChecks.Save(ctx, checkLog, user.Id, 0)
+
+	fields := make(map[string]interface{})
+	var reviewItems []*model.ModerationQueueModel
+	for _, field := range []struct {
+		name, text, current, pending, message string
+		maxLen                                int
+	}{
+		{model.ProfileFieldNickname, update.Nickname, user.Nickname, user.PendingNickname, "昵称包含违规内容，请修改后重试", 50},
+		{model.ProfileFieldBio, update.Bio, user.Bio, user.PendingBio, "简介包含违规信息，请修改后重试", 200},
+	} {
+		pendingColumn := "pending_" + field.name
+		if field.text == "" || field.text == field.pending {
+			continue
+		}
+		if field.text == field.current {
+			// 改回当前值，撤销待审核的修改
+			if field.pending != "" {
+				fields[pendingColumn] = ""
+			}
+			continue
+		}
+		if utf8.RuneCountInString(field.text) > field.maxLen {
+			return fmt.Errorf("%s不能超过%d个字符", profileFieldName(field.name), field.maxLen)
+		}
+
+		outcome, err := s.textModerator.Check(ctx, checkLog, openid, field.name, SceneProfile, field.text)
+		if err != nil {
+			return err
+		}
+		switch outcome.Action {
+		case PolicyActionAllow:
+			fields[field.name] = field.text
+			fields[pendingColumn] = ""
+		case PolicyActionReview:
+			fields[pendingColumn] = field.text
+			reviewItems = append(reviewItems, outcome.ReviewItem(field.text))
+		case PolicyActionShadow:
+			// 静默隐藏：保留为待审核值，仅本人可见，不进入审核队列
+			fields[pendingColumn] = field.text
+		default:
+			s.accountService.RecordViolation(ctx, user.Id, "profile_"+field.name)
+			return errors.New(field.message)
+		}
+	}
+
+	// 新头像先保存为待审核值，检测通过后替换当前头像
+	var avatarCheck *model.MediaCheckModel
+	switch {
+	case update.Avatar == "" || update.Avatar == user.PendingAvatar:
+	case update.Avatar == user.Avatar:
+		if user.PendingAvatar != "" {
+			fields["pending_avatar"] = ""
+		}
+	default:
+		fields["pending_avatar"] = update.Avatar
+		avatarCheck = &model.MediaCheckModel{
+			MediaURL: update.Avatar,
+			Status:   model.MediaCheckStatusPending,
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+	err := s.userDao.UpdateProfile(ctx, user.Id, fields, avatarCheck, func(check *model.MediaCheckModel) (*model.JobModel, error) {
+		return newMediaCheckJob(check, openid)
+	})
+	if err != nil {
+		return fmt.Errorf("更新用户资料失败: %v", err)
+	}
+	s.moderationService.EnqueueAll(ctx, reviewItems, model.ModerationTargetUser, user.Id, 0, user.Id)
+	if avatarCheck != nil {
+		slog.InfoContext(ctx, "头像检测任务已创建", "user_id", user.Id, "check_id", avatarCheck.Id)
+	}
+
+	updated, err := s.userDao.GetById(ctx, user.Id)
+	if err != nil {
+		return fmt.Errorf("获取用户资料失败: %v", err)
+	}
+	*user = *updated
+	return nil
+}
+
+// SettleAvatarCheck 根据头像检测的处理动作更新资料：allow 替换当前头像，review 进入人工审核，
+// shadow 保持仅本人可见，reject 丢弃待审核头像并通知用户。用户已再次更换头像时旧检测结论不再生效
+func (s *ProfileModerationService) SettleAvatarCheck(ctx context.Context, check *model.MediaCheckModel, action, reason, suggest string, label int) error {
+	switch action {
+	case PolicyActionShadow:
+		return nil
+	case PolicyActionReview:
+		return s.moderationService.Enqueue(ctx, &model.ModerationQueueModel{
+			TargetType:   model.ModerationTargetUser,
+			TargetId:     check.UserId,
+			AuthorId:     check.UserId,
+			Source:       model.ModerationSourceImage,
+			Content:      check.MediaURL,
+			Reason:       reason,
+			TraceId:      check.TraceId,
+			MediaCheckId: check.Id,
+			Suggest:      suggest,
+			Label:        label,
+		})
+	}
+
+	approved := action == PolicyActionAllow
+	applied, err := s.userDao.ApplyPendingField(ctx, check.UserId, model.ProfileFieldAvatar, check.MediaURL, approved)
+	if err != nil {
+		return fmt.Errorf("更新头像失败: %v", err)
+	}
+	if !applied {
+		slog.InfoContext(ctx, "头像已再次更换，忽略旧的检测结论", "user_id", check.UserId, "check_id", check.Id)
+		return nil
+	}
+	slog.InfoContext(ctx, "头像检测完成", "user_id", check.UserId, "check_id", check.Id, "approved", approved)
+	if approved {
+		return nil
+	}
+
+	notification := &model.NotificationModel{
+		UserId:     check.UserId,
+		Type:       model.NotificationModerationRejected,
+		TargetType: model.ModerationTargetUser,
+		TargetId:   check.UserId,
+		Title:      "你的头像未通过审核",
+		Content:    fmt.Sprintf("你上传的头像未通过审核：%s，已恢复为原头像。", reason),
+	}
+	if err := s.notificationDao.Create(ctx, notification); err != nil {
+		slog.ErrorContext(ctx, "发送头像审核结果通知失败", "user_id", check.UserId, "error", err)
+	}
+	return nil
+}
+
+// profileFieldName 资料字段的中文名称
+func profileFieldName(field string) string {
+	switch field {
+	case model.ProfileFieldNickname:
+		return "昵称"
+	case model.ProfileFieldAvatar:
+		return "头像"
+	default:
+		return "简介"
+	}
+}
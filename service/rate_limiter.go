@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"wxcloudrun-golang/db/dao"
+	"wxcloudrun-golang/metrics"
+)
+
+// 限流路由名称
+const (
+	RateLimitPostCreate    = "post_create"
+	RateLimitCommentCreate = "comment_create"
+	RateLimitLikeToggle    = "like_toggle"
+	RateLimitReportCreate  = "report_create"
+	RateLimitMediaUpload   = "media_upload"
+)
+
+// RateLimitBudget 单个路由的限流额度，Limit为0表示不限制
+type RateLimitBudget struct {
+	UserLimit int           // 每个用户在窗口内允许的请求数
+	IPLimit   int           // 每个IP在窗口内允许的请求数
+	Window    time.Duration // 窗口长度
+}
+
+// defaultRateLimitBudgets 各写接口的默认限流额度，可通过环境变量 RATE_LIMIT_<ROUTE> 覆盖，
+// 格式为 "用户额度/IP额度/窗口"，如 RATE_LIMIT_POST_CREATE=5/20/1m
+var defaultRateLimitBudgets = map[string]RateLimitBudget{
+	RateLimitPostCreate:    {UserLimit: 5, IPLimit: 20, Window: time.Minute},
+	RateLimitCommentCreate: {UserLimit: 10, IPLimit: 40, Window: time.Minute},
+	RateLimitLikeToggle:    {UserLimit: 30, IPLimit: 120, Window: time.Minute},
+	RateLimitReportCreate:  {UserLimit: 10, IPLimit: 40, Window: time.Hour},
+	RateLimitMediaUpload:   {UserLimit: 30, IPLimit: 120, Window: time.Hour},
+}
+
+// RateLimitStore 限流计数存储，多实例部署时需使用共享存储
+type RateLimitStore interface {
+	// Incr 对key在当前窗口的计数加一，返回加一后的计数和窗口剩余时间
+	Incr(ctx context.Context, key string, window time.Duration) (int, time.Duration, error)
+}
+
+// RateLimiter 写接口限流器
+type RateLimiter struct {
+	store   RateLimitStore
+	budgets map[string]RateLimitBudget
+}
+
+// NewRateLimiter 创建限流器实例，RATE_LIMIT_STORE=mysql 时使用数据库共享计数，默认为单实例内存计数
+func NewRateLimiter() *RateLimiter {
+	var store RateLimitStore
+	switch os.Getenv("RATE_LIMIT_STORE") {
+	case "mysql":
+		store = NewMySQLRateLimitStore(dao.NewRateLimitDao())
+	default:
+		store = NewMemoryRateLimitStore()
+	}
+
+	budgets := make(map[string]RateLimitBudget, len(defaultRateLimitBudgets))
+	for route, budget := range defaultRateLimitBudgets {
+		envKey := "RATE_LIMIT_" + strings.ToUpper(route)
+		if value := os.Getenv(envKey); value != "" {
+			parsed, err := parseRateLimitBudget(value)
+			if err != nil {
+				slog.Warn("限流配置无效，使用默认值", "env", envKey, "value", value, "error", err)
+			} else {
+				budget = parsed
+			}
+		}
+		budgets[route] = budget
+	}
+
+	return &RateLimiter{store: store, budgets: budgets}
+}
+
+// Limit 为处理器增加限流，需在 UserMiddleware 之后使用以获取用户信息
+func (l *RateLimiter) Limit(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		budget, ok := l.budgets[route]
+		if !ok {
+			next(w, r)
+			return
+		}
+
+		var keys []rateLimitKey
+		userCtx := GetUserFromContext(r)
+		if userCtx != nil && userCtx.User != nil && budget.UserLimit > 0 {
+			keys = append(keys, rateLimitKey{
+				key:   fmt.Sprintf("%s:user:%d", route, userCtx.User.Id),
+				limit: budget.UserLimit,
+			})
+		}
+		if ip := clientIP(r); ip != "" && budget.IPLimit > 0 {
+			keys = append(keys, rateLimitKey{
+				key:   fmt.Sprintf("%s:ip:%s", route, ip),
+				limit: budget.IPLimit,
+			})
+		}
+
+		for _, k := range keys {
+			count, retryAfter, err := l.store.Incr(r.Context(), k.key, budget.Window)
+			if err != nil {
+				// 限流存储异常时放行，避免影响正常业务
+				slog.ErrorContext(r.Context(), "限流计数失败", "key", k.key, "error", err)
+				continue
+			}
+			if count > k.limit {
+				slog.WarnContext(r.Context(), "请求被限流", "route", route, "key", k.key, "count", count, "limit", k.limit)
+				metrics.ObserveRateLimited(route)
+				seconds := int(retryAfter.Seconds())
+				if seconds < 1 {
+					seconds = 1
+				}
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+				http.Error(w, "Too many requests, please try again later", http.StatusTooManyRequests)
+				return
+			}
+		}
+
+		next(w, r)
+	}
+}
+
+// rateLimitKey 限流计数键及其额度
+type rateLimitKey struct {
+	key   string
+	limit int
+}
+
+// clientIP 获取客户端IP，X-Original-Forwarded-For 可能包含多级代理，取第一个
+func clientIP(r *http.Request) string {
+	ip := r.Header.Get("X-Original-Forwarded-For")
+	if ip == "" {
+		if userCtx := GetUserFromContext(r); userCtx != nil {
+			ip = userCtx.IP
+		}
+	}
+	if i := strings.Index(ip, ","); i >= 0 {
+		ip = ip[:i]
+	}
+	return strings.TrimSpace(ip)
+}
+
+// parseRateLimitBudget 解析 "用户额度/IP额度/窗口" 格式的限流配置
+func parseRateLimitBudget(value string) (RateLimitBudget, error) {
+	parts := strings.Split(value, "/")
+	if len(parts) != 3 {
+		return RateLimitBudget{}, fmt.Errorf("格式应为 用户额度/IP额度/窗口")
+	}
+	userLimit, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return RateLimitBudget{}, fmt.Errorf("用户额度无效: %v", err)
+	}
+	ipLimit, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return RateLimitBudget{}, fmt.Errorf("IP额度无效: %v", err)
+	}
+	window, err := time.ParseDuration(parts[2])
+	if err != nil || window <= 0 {
+		return RateLimitBudget{}, fmt.Errorf("窗口无效: %s", parts[2])
+	}
+	return RateLimitBudget{UserLimit: userLimit, IPLimit: ipLimit, Window: window}, nil
+}
+
+// windowBounds 计算固定窗口的开始时间和剩余时间
+func windowBounds(now time.Time, window time.Duration) (time.Time, time.Duration) {
+	start := now.Truncate(window)
+	return start, start.Add(window).Sub(now)
+}
+
+// MemoryRateLimitStore 单实例内存限流存储
+type MemoryRateLimitStore struct {
+	mu       sync.Mutex
+	counters map[string]*memoryCounter
+}
+
+// memoryCounter 内存窗口计数，expiresAt为窗口结束时间
+type memoryCounter struct {
+	windowStart time.Time
+	expiresAt   time.Time
+	count       int
+}
+
+// NewMemoryRateLimitStore 创建内存限流存储，并定期清理过期计数
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	store := &MemoryRateLimitStore{counters: make(map[string]*memoryCounter)}
+	go store.cleanupLoop(10 * time.Minute)
+	return store
+}
+
+// Incr 对key在当前窗口的计数加一
+func (s *MemoryRateLimitStore) Incr(ctx context.Context, key string, window time.Duration) (int, time.Duration, error) {
+	start, remaining := windowBounds(time.Now(), window)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter, ok := s.counters[key]
+	if !ok || !counter.windowStart.Equal(start) {
+		counter = &memoryCounter{windowStart: start, expiresAt: start.Add(window)}
+		s.counters[key] = counter
+	}
+	counter.count++
+	return counter.count, remaining, nil
+}
+
+// cleanupLoop 定期清理过期的计数
+func (s *MemoryRateLimitStore) cleanupLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.deleteExpired(time.Now())
+	}
+}
+
+// deleteExpired 删除窗口已结束的计数，窗口长于清理间隔的计数在窗口内保留
+func (s *MemoryRateLimitStore) deleteExpired(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, counter := range s.counters {
+		if !now.Before(counter.expiresAt) {
+			delete(s.counters, key)
+		}
+	}
+}
+
+// MySQLRateLimitStore 基于MySQL的限流存储，供多个云托管实例共享限流状态
+type MySQLRateLimitStore struct {
+	rateLimitDao dao.RateLimitDao
+}
+
+// NewMySQLRateLimitStore 创建MySQL限流存储，并定期清理过期计数
+func NewMySQLRateLimitStore(rateLimitDao dao.RateLimitDao) *MySQLRateLimitStore {
+	store := &MySQLRateLimitStore{rateLimitDao: rateLimitDao}
+	go store.cleanupLoop(10 * time.Minute)
+	return store
+}
+
+// Incr 对key在当前窗口的计数加一
+func (s *MySQLRateLimitStore) Incr(ctx context.Context, key string, window time.Duration) (int, time.Duration, error) {
+	start, remaining := windowBounds(time.Now(), window)
+	count, err := s.rateLimitDao.Increment(ctx, key, start.Unix(), start.Add(window))
+	if err != nil {
+		return 0, 0, err
+	}
+	return count, remaining, nil
+}
+
+// cleanupLoop 定期清理过期的计数
+func (s *MySQLRateLimitStore) cleanupLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := s.rateLimitDao.DeleteExpired(context.Background(), time.Now()); err != nil {
+			slog.Error("清理过期限流计数失败", "error", err)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryRateLimitStoreDeleteExpired(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	store := &MemoryRateLimitStore{counters: map[string]*memoryCounter{
+		"hour":   {windowStart: start, expiresAt: start.Add(time.Hour), count: 3},
+		"minute": {windowStart: start, expiresAt: start.Add(time.Minute), count: 3},
+	}}
+
+	// 清理间隔（10分钟）后，小时窗口的计数仍在窗口内，不能被清理
+	store.deleteExpired(start.Add(10*time.Minute + time.Second))
+	if _, ok := store.counters["minute"]; ok {
+		t.Error("分钟窗口的计数应已清理")
+	}
+	if counter, ok := store.counters["hour"]; !ok || counter.count != 3 {
+		t.Error("小时窗口的计数不应在窗口内被清理")
+	}
+
+	store.deleteExpired(start.Add(time.Hour))
+	if len(store.counters) != 0 {
+		t.Errorf("窗口结束后应清理全部计数，剩余 %d", len(store.counters))
+	}
+}
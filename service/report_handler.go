@@ -0,0 +1,139 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"wxcloudrun-golang/db/model"
+)
+
+// ReportHandler 举报处理器
+type ReportHandler struct {
+	reportService *ReportService
+}
+
+// NewReportHandler 创建举报处理器实例
+func NewReportHandler() *ReportHandler {
+	return &ReportHandler{
+		reportService: NewReportService(),
+	}
+}
+
+// CreateReportHandler 提交举报处理器
+// POST /api/reports
+func (h *ReportHandler) CreateReportHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CreateReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userCtx := GetUserFromContext(r)
+	if userCtx == nil || userCtx.User == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	report, err := h.reportService.CreateReport(r.Context(), userCtx.User.Id, &req)
+	if err != nil {
+		if errors.Is(err, ErrAlreadyReported) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := map[string]interface{}{
+		"code":    200,
+		"message": "举报成功",
+		"data": map[string]interface{}{
+			"reportId": report.Id,
+		},
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleAdminReportRequests 处理后台举报管理请求
+// GET  /api/admin/reports?status=&targetType=    获取举报列表，status默认0（待处理），传all返回全部
+// POST /api/admin/reports/{id}/resolve           处理举报，请求体 {"valid": true, "note": ""}
+// GET  /api/admin/reports/reporters/{userId}     获取举报人历史
+func (h *ReportHandler) HandleAdminReportRequests(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	pathParts := strings.Split(path, "/")
+
+	switch {
+	case path == "/api/admin/reports":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		status := model.ReportStatusPending
+		if statusStr := r.URL.Query().Get("status"); statusStr == "all" {
+			status = -1
+		} else if st, err := strconv.Atoi(statusStr); err == nil {
+			status = st
+		}
+		page, pageSize := parsePageParams(r)
+		result, err := h.reportService.GetReports(r.Context(), status, r.URL.Query().Get("targetType"), page, pageSize)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeAdminResponse(w, "success", result)
+	case len(pathParts) == 6 && pathParts[4] == "reporters":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		reporterId, err := strconv.ParseInt(pathParts[5], 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		page, pageSize := parsePageParams(r)
+		result, err := h.reportService.GetReporterHistory(r.Context(), reporterId, page, pageSize)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeAdminResponse(w, "success", result)
+	case len(pathParts) == 6 && pathParts[5] == "resolve":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.ParseInt(pathParts[4], 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid report ID", http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			Valid bool   `json:"valid"`
+			Note  string `json:"note"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.reportService.ResolveReport(r.Context(), GetUserFromContext(r), id, req.Valid, req.Note); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeAdminResponse(w, "处理成功", nil)
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,393 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"strconv"
+	"unicode/utf8"
+	"wxcloudrun-golang/db/dao"
+	"wxcloudrun-golang/db/model"
+)
+
+// defaultReportHideThreshold 默认自动隐藏阈值：同一内容累计多少个待处理举报后自动隐藏
+const defaultReportHideThreshold = 5
+
+// reportHiddenReason 因举报自动隐藏时记录的审核原因，驳回举报时据此恢复展示
+const reportHiddenReason = "多次被举报，等待处理"
+
+// ErrAlreadyReported 重复举报
+var ErrAlreadyReported = errors.New("你已举报过该内容，请等待处理")
+
+// ReportService 举报服务
+type ReportService struct {
+	reportDao         dao.ReportDao
+	postDao           dao.PostDao
+	commentDao        dao.CommentDao
+	userDao           dao.UserDao
+	notificationDao   dao.NotificationDao
+	auditService      *AuditService
+	moderationService *ModerationService
+	hideThreshold     int
+}
+
+// NewReportService 创建举报服务实例，自动隐藏阈值可通过 REPORT_HIDE_THRESHOLD 配置，0表示不自动隐藏
+func NewReportService() *ReportService {
+	threshold := defaultReportHideThreshold
+	if value, err := strconv.Atoi(os.Getenv("REPORT_HIDE_THRESHOLD")); err == nil && value >= 0 {
+		threshold = value
+	}
+	return &ReportService{
+		reportDao:         dao.NewReportDao(),
+		postDao:           dao.NewPostDao(),
+		commentDao:        dao.NewCommentDao(),
+		userDao:           dao.NewUserDao(),
+		notificationDao:   dao.NewNotificationDao(),
+		auditService:      NewAuditService(),
+		moderationService: NewModerationService(),
+		hideThreshold:     threshold,
+	}
+}
+
+// CreateReportRequest 创建举报请求
+type CreateReportRequest struct {
+	TargetType  string `json:"targetType"`
+	TargetId    int64  `json:"targetId"`
+	Reason      string `json:"reason"`
+	Description string `json:"description"`
+}
+
+// ReportListResponse 举报列表响应
+type ReportListResponse struct {
+	List       []*model.ReportModel `json:"list"`
+	Pagination Pagination           `json:"pagination"`
+}
+
+// ReporterStats 举报人历史统计
+type ReporterStats struct {
+	Total     int64 `json:"total"`
+	Pending   int64 `json:"pending"`
+	Valid     int64 `json:"valid"`
+	Dismissed int64 `json:"dismissed"`
+}
+
+// ReporterHistoryResponse 举报人历史响应
+type ReporterHistoryResponse struct {
+	ReporterId int64                `json:"reporterId"`
+	Stats      ReporterStats        `json:"stats"`
+	List       []*model.ReportModel `json:"list"`
+	Pagination Pagination           `json:"pagination"`
+}
+
+// CreateReport 创建举报，同一对象待处理举报数达到阈值时自动隐藏帖子或评论
+func (s *ReportService) CreateReport(ctx context.Context, reporterId int64, req *CreateReportRequest) (*model.ReportModel, error) {
+	if _, ok := model.ReportReasons[req.Reason]; !ok {
+		return nil, fmt.Errorf("无效的举报原因: %s", req.Reason)
+	}
+	if utf8.RuneCountInString(req.Description) > 500 {
+		return nil, fmt.Errorf("补充说明不能超过500字")
+	}
+
+	authorId, err := s.getTargetAuthor(ctx, req.TargetType, req.TargetId)
+	if err != nil {
+		return nil, err
+	}
+	if authorId == reporterId {
+		return nil, fmt.Errorf("不能举报自己")
+	}
+
+	report := &model.ReportModel{
+		ReporterId:     reporterId,
+		TargetType:     req.TargetType,
+		TargetId:       req.TargetId,
+		TargetAuthorId: authorId,
+		Reason:         req.Reason,
+		Description:    req.Description,
+		Status:         model.ReportStatusPending,
+	}
+	if err := s.reportDao.Create(ctx, report); err != nil {
+		if errors.Is(err, dao.ErrDuplicateReport) {
+			return nil, ErrAlreadyReported
+		}
+		return nil, fmt.Errorf("提交举报失败: %v", err)
+	}
+
+	slog.InfoContext(ctx, "收到举报", "report_id", report.Id, "reporter_id", reporterId,
+		"target_type", req.TargetType, "target_id", req.TargetId, "reason", req.Reason)
+
+	if err := s.hideIfThresholdReached(ctx, req.TargetType, req.TargetId); err != nil {
+		// 自动隐藏失败不影响举报提交
+		slog.ErrorContext(ctx, "举报自动隐藏失败", "target_type", req.TargetType, "target_id", req.TargetId, "error", err)
+	}
+
+	return report, nil
+}
+
+// getTargetAuthor 校验举报对象并返回其作者ID
+func (s *ReportService) getTargetAuthor(ctx context.Context, targetType string, targetId int64) (int64, error) {
+	switch targetType {
+	case model.ReportTargetPost:
+		post, err := s.postDao.GetById(ctx, targetId)
+		if err != nil {
+			return 0, fmt.Errorf("帖子不存在: %v", err)
+		}
+		return post.AuthorId, nil
+	case model.ReportTargetComment:
+		comment, err := s.commentDao.GetById(ctx, targetId)
+		if err != nil {
+			return 0, fmt.Errorf("评论不存在: %v", err)
+		}
+		return comment.AuthorId, nil
+	case model.ReportTargetUser:
+		user, err := s.userDao.GetById(ctx, targetId)
+		if err != nil {
+			return 0, fmt.Errorf("用户不存在: %v", err)
+		}
+		return user.Id, nil
+	default:
+		return 0, fmt.Errorf("无效的举报对象类型: %s", targetType)
+	}
+}
+
+// hideIfThresholdReached 待处理举报数达到阈值时将正常展示的帖子或评论转为待审核，并加入人工审核队列
+func (s *ReportService) hideIfThresholdReached(ctx context.Context, targetType string, targetId int64) error {
+	if s.hideThreshold <= 0 || targetType == model.ReportTargetUser {
+		return nil
+	}
+
+	count, err := s.reportDao.CountPendingByTarget(ctx, targetType, targetId)
+	if err != nil {
+		return fmt.Errorf("统计举报数失败: %v", err)
+	}
+	if count < int64(s.hideThreshold) {
+		return nil
+	}
+
+	item := &model.ModerationQueueModel{
+		TargetType: targetType,
+		TargetId:   targetId,
+		Source:     model.ModerationSourceReport,
+		Reason:     reportHiddenReason,
+	}
+	switch targetType {
+	case model.ReportTargetPost:
+		post, err := s.postDao.GetById(ctx, targetId)
+		if err != nil {
+			return fmt.Errorf("帖子不存在: %v", err)
+		}
+		if post.ModerationStatus != model.ModerationStatusNormal {
+			return nil
+		}
+		err = s.postDao.UpdateModerationStatus(ctx, targetId, model.ModerationStatusPending, reportHiddenReason)
+		if err != nil {
+			return fmt.Errorf("隐藏帖子失败: %v", err)
+		}
+		item.PostId, item.AuthorId, item.Content = post.Id, post.AuthorId, post.Content
+	case model.ReportTargetComment:
+		comment, err := s.commentDao.GetById(ctx, targetId)
+		if err != nil {
+			return fmt.Errorf("评论不存在: %v", err)
+		}
+		if comment.ModerationStatus != model.ModerationStatusNormal {
+			return nil
+		}
+		err = s.commentDao.UpdateModerationStatus(ctx, targetId, model.ModerationStatusPending, reportHiddenReason)
+		if err != nil {
+			return fmt.Errorf("隐藏评论失败: %v", err)
+		}
+		item.PostId, item.AuthorId, item.Content = comment.PostId, comment.AuthorId, comment.Content
+	}
+
+	slog.InfoContext(ctx, "举报数达到阈值，内容已自动隐藏", "target_type", targetType, "target_id", targetId, "reports", count)
+	return s.moderationService.Enqueue(ctx, item)
+}
+
+// GetReports 分页获取举报列表，status小于0时返回全部状态
+func (s *ReportService) GetReports(ctx context.Context, status int, targetType string, page, pageSize int) (*ReportListResponse, error) {
+	page, pageSize = normalizeReportPage(page, pageSize)
+
+	reports, total, err := s.reportDao.GetList(ctx, status, targetType, page, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("获取举报列表失败: %v", err)
+	}
+
+	return &ReportListResponse{
+		List:       reports,
+		Pagination: newPagination(page, pageSize, total),
+	}, nil
+}
+
+// ResolveReport 处理举报。同一对象的全部待处理举报一起处理：属实时下架内容，驳回时恢复因举报被隐藏的内容
+func (s *ReportService) ResolveReport(ctx context.Context, operator *UserContext, id int64, valid bool, note string) error {
+	report, err := s.reportDao.GetById(ctx, id)
+	if err != nil {
+		return fmt.Errorf("举报不存在: %v", err)
+	}
+
+	status := model.ReportStatusDismissed
+	if valid {
+		status = model.ReportStatusValid
+	}
+	reporterIds, err := s.reportDao.ResolveByTarget(ctx, report.TargetType, report.TargetId, status, operator.User.Id, note)
+	if err != nil {
+		return fmt.Errorf("处理举报失败: %v", err)
+	}
+	if len(reporterIds) == 0 {
+		return fmt.Errorf("举报已处理")
+	}
+
+	if valid {
+		err = s.takedownTarget(ctx, report, note)
+	} else {
+		err = s.restoreTarget(ctx, report)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.notifyReporters(ctx, report, reporterIds, valid)
+
+	s.auditService.Record(ctx, operator, model.AuditActionResolveReport, report.TargetType, report.TargetId, map[string]interface{}{
+		"report_id": report.Id,
+		"valid":     valid,
+		"note":      note,
+		"reports":   len(reporterIds),
+	})
+	return nil
+}
+
+// takedownTarget 举报属实时下架帖子或评论并通知作者；举报用户时由管理员另行处理账号
+func (s *ReportService) takedownTarget(ctx context.Context, report *model.ReportModel, note string) error {
+	reason := "举报属实：" + model.ReportReasons[report.Reason]
+	if note != "" {
+		reason = "举报属实：" + note
+	}
+
+	var targetName string
+	switch report.TargetType {
+	case model.ReportTargetPost:
+		targetName = "帖子"
+		if err := s.postDao.UpdateModerationStatus(ctx, report.TargetId, model.ModerationStatusRejected, reason); err != nil {
+			return fmt.Errorf("下架帖子失败: %v", err)
+		}
+	case model.ReportTargetComment:
+		targetName = "评论"
+		if err := s.commentDao.UpdateModerationStatus(ctx, report.TargetId, model.ModerationStatusRejected, reason); err != nil {
+			return fmt.Errorf("下架评论失败: %v", err)
+		}
+	default:
+		return nil
+	}
+
+	notification := &model.NotificationModel{
+		UserId:     report.TargetAuthorId,
+		Type:       model.NotificationModerationRejected,
+		Title:      fmt.Sprintf("你的%s已被下架", targetName),
+		Content:    fmt.Sprintf("你发布的%s因%s已被下架。", targetName, reason),
+		TargetType: report.TargetType,
+		TargetId:   report.TargetId,
+	}
+	if err := s.notificationDao.Create(ctx, notification); err != nil {
+		slog.ErrorContext(ctx, "发送下架通知失败", "target_type", report.TargetType, "target_id", report.TargetId, "error", err)
+	}
+	return nil
+}
+
+// restoreTarget 举报被驳回时恢复因举报自动隐藏的内容
+func (s *ReportService) restoreTarget(ctx context.Context, report *model.ReportModel) error {
+	switch report.TargetType {
+	case model.ReportTargetPost:
+		post, err := s.postDao.GetById(ctx, report.TargetId)
+		if err != nil {
+			return fmt.Errorf("帖子不存在: %v", err)
+		}
+		if post.ModerationStatus == model.ModerationStatusPending && post.ModerationReason == reportHiddenReason {
+			if err := s.postDao.UpdateModerationStatus(ctx, post.Id, model.ModerationStatusNormal, ""); err != nil {
+				return fmt.Errorf("恢复帖子失败: %v", err)
+			}
+		}
+	case model.ReportTargetComment:
+		comment, err := s.commentDao.GetById(ctx, report.TargetId)
+		if err != nil {
+			return fmt.Errorf("评论不存在: %v", err)
+		}
+		if comment.ModerationStatus == model.ModerationStatusPending && comment.ModerationReason == reportHiddenReason {
+			if err := s.commentDao.UpdateModerationStatus(ctx, comment.Id, model.ModerationStatusNormal, ""); err != nil {
+				return fmt.Errorf("恢复评论失败: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// notifyReporters 通知举报人处理结果，失败只记录日志
+func (s *ReportService) notifyReporters(ctx context.Context, report *model.ReportModel, reporterIds []int64, valid bool) {
+	content := "感谢你的举报，经核实被举报内容未违反社区规范。"
+	if valid {
+		content = "感谢你的举报，经核实举报属实，相关内容已处理。"
+	}
+	for _, reporterId := range reporterIds {
+		notification := &model.NotificationModel{
+			UserId:     reporterId,
+			Type:       model.NotificationReportResolved,
+			Title:      "你的举报已处理",
+			Content:    content,
+			TargetType: report.TargetType,
+			TargetId:   report.TargetId,
+		}
+		if err := s.notificationDao.Create(ctx, notification); err != nil {
+			slog.ErrorContext(ctx, "发送举报处理通知失败", "reporter_id", reporterId, "error", err)
+		}
+	}
+}
+
+// GetReporterHistory 获取举报人的历史举报及处理结果统计，用于识别恶意举报
+func (s *ReportService) GetReporterHistory(ctx context.Context, reporterId int64, page, pageSize int) (*ReporterHistoryResponse, error) {
+	page, pageSize = normalizeReportPage(page, pageSize)
+
+	reports, total, err := s.reportDao.GetByReporter(ctx, reporterId, page, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("获取举报记录失败: %v", err)
+	}
+	counts, err := s.reportDao.CountByReporterAndStatus(ctx, reporterId)
+	if err != nil {
+		return nil, fmt.Errorf("统计举报记录失败: %v", err)
+	}
+
+	return &ReporterHistoryResponse{
+		ReporterId: reporterId,
+		Stats: ReporterStats{
+			Total:     counts[model.ReportStatusPending] + counts[model.ReportStatusValid] + counts[model.ReportStatusDismissed],
+			Pending:   counts[model.ReportStatusPending],
+			Valid:     counts[model.ReportStatusValid],
+			Dismissed: counts[model.ReportStatusDismissed],
+		},
+		List:       reports,
+		Pagination: newPagination(page, pageSize, total),
+	}, nil
+}
+
+// normalizeReportPage 规范化分页参数
+func normalizeReportPage(page, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 50 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
+
+// newPagination 根据总数生成分页信息
+func newPagination(page, pageSize int, total int64) Pagination {
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+	return Pagination{
+		Current:  page,
+		PageSize: pageSize,
+		Total:    total,
+		HasMore:  page < totalPages,
+	}
+}
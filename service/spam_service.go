@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"log/slog"
+	"math/bits"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"wxcloudrun-golang/db/dao"
+	"wxcloudrun-golang/db/model"
+)
+
+// 垃圾内容处理动作
+const (
+	SpamActionReject = "reject" // 直接拒绝
+	SpamActionReview = "review" // 进入待审核，仅作者可见
+	SpamActionShadow = "shadow" // 静默隐藏，仅作者可见且不提示
+)
+
+// ErrSpamRejected 内容被反垃圾规则拒绝
+var ErrSpamRejected = errors.New("内容疑似垃圾信息，请修改后重试")
+
+// contactPatterns 链接和联系方式匹配规则
+var contactPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(https?://[^\s]+|www\.[^\s]+|[a-z0-9-]+\.(com|cn|net|org|top|xyz|cc|vip|me)\b)`), // 链接和域名
+	regexp.MustCompile(`(?i)(微信|威信|薇信|vx|wx|v信|加v|weixin)\s*[:：号]?\s*[a-z][-_a-z0-9]{5,19}`),                 // 微信号
+	regexp.MustCompile(`(?i)(qq|扣扣|企鹅)\s*[:：号群]?\s*[1-9][0-9]{4,11}`),                                        // QQ号
+}
+
+// phonePattern 手机号，前后紧邻其他数字时不算，避免把订单号、流水号等长数字误判为手机号
+var phonePattern = regexp.MustCompile(`1[3-9][0-9]{9}`)
+
+// SpamConfig 反垃圾配置
+type SpamConfig struct {
+	Action                 string        // 命中后的处理动作
+	RecentWindow           time.Duration // 近期内容的时间范围
+	RecentLimit            int           // 参与比对的近期内容条数上限
+	DuplicateDistance      int           // SimHash海明距离不超过该值视为近似重复
+	DuplicateMinLength     int           // 参与重复检测的最短内容长度（字符数）
+	MaxContactMatches      int           // 允许的链接/联系方式数量
+	MaxContactDensity      float64       // 链接/联系方式字符占比上限
+	NewAccountAge          time.Duration // 注册时长小于该值视为新账号
+	NewAccountPostLimit    int           // 新账号在近期时间范围内的发帖上限
+	NewAccountCommentLimit int           // 新账号在近期时间范围内的评论上限
+}
+
+// loadSpamConfig 从环境变量加载反垃圾配置
+func loadSpamConfig() SpamConfig {
+	config := SpamConfig{
+		Action:                 SpamActionReview,
+		RecentWindow:           24 * time.Hour,
+		RecentLimit:            50,
+		DuplicateDistance:      3,
+		DuplicateMinLength:     10,
+		MaxContactMatches:      2,
+		MaxContactDensity:      0.3,
+		NewAccountAge:          24 * time.Hour,
+		NewAccountPostLimit:    3,
+		NewAccountCommentLimit: 10,
+	}
+
+	switch action := os.Getenv("SPAM_ACTION"); action {
+	case SpamActionReject, SpamActionReview, SpamActionShadow:
+		config.Action = action
+	case "":
+	default:
+		slog.Warn("反垃圾处理动作无效，使用默认值", "value", action, "default", config.Action)
+	}
+	if hours, err := strconv.Atoi(os.Getenv("SPAM_NEW_ACCOUNT_HOURS")); err == nil && hours >= 0 {
+		config.NewAccountAge = time.Duration(hours) * time.Hour
+	}
+	if limit, err := strconv.Atoi(os.Getenv("SPAM_NEW_ACCOUNT_POST_LIMIT")); err == nil && limit > 0 {
+		config.NewAccountPostLimit = limit
+	}
+	if limit, err := strconv.Atoi(os.Getenv("SPAM_NEW_ACCOUNT_COMMENT_LIMIT")); err == nil && limit > 0 {
+		config.NewAccountCommentLimit = limit
+	}
+	return config
+}
+
+// SpamCheckResult 反垃圾检测结果
+type SpamCheckResult struct {
+	IsSpam bool   `json:"isSpam"`
+	Reason string `json:"reason"`
+	Action string `json:"action"`
+}
+
+// SpamService 反垃圾服务：近似重复、链接/联系方式密度、新账号限频
+type SpamService struct {
+	postDao    dao.PostDao
+	commentDao dao.CommentDao
+	config     SpamConfig
+}
+
+// NewSpamService 创建反垃圾服务实例
+func NewSpamService() *SpamService {
+	return &SpamService{
+		postDao:    dao.NewPostDao(),
+		commentDao: dao.NewCommentDao(),
+		config:     loadSpamConfig(),
+	}
+}
+
+// CheckPost 检测帖子是否为垃圾内容
+func (s *SpamService) CheckPost(ctx context.Context, author *model.UserModel, title, content string) (*SpamCheckResult, error) {
+	recent, err := s.postDao.GetRecentByAuthor(ctx, author.Id, time.Now().Add(-s.config.RecentWindow), s.config.RecentLimit)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]string, 0, len(recent))
+	for _, post := range recent {
+		history = append(history, post.Title+"\n"+post.Content)
+	}
+	return s.check(ctx, author, title+"\n"+content, history, s.config.NewAccountPostLimit), nil
+}
+
+// CheckComment 检测评论是否为垃圾内容
+func (s *SpamService) CheckComment(ctx context.Context, author *model.UserModel, content string) (*SpamCheckResult, error) {
+	recent, err := s.commentDao.GetRecentByAuthor(ctx, author.Id, time.Now().Add(-s.config.RecentWindow), s.config.RecentLimit)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]string, 0, len(recent))
+	for _, comment := range recent {
+		history = append(history, comment.Content)
+	}
+	return s.check(ctx, author, content, history, s.config.NewAccountCommentLimit), nil
+}
+
+// check 依次执行新账号限频、链接/联系方式密度和近似重复检测
+func (s *SpamService) check(ctx context.Context, author *model.UserModel, text string, history []string, newAccountLimit int) *SpamCheckResult {
+	result := &SpamCheckResult{Action: s.config.Action}
+	isNewAccount := time.Since(author.CreatedAt) < s.config.NewAccountAge
+
+	contactMatches, contactDensity := contactInfoStats(text)
+
+	switch {
+	case isNewAccount && len(history) >= newAccountLimit:
+		result.IsSpam = true
+		result.Reason = "新账号发布过于频繁"
+	case isNewAccount && contactMatches > 0:
+		result.IsSpam = true
+		result.Reason = "新账号发布链接或联系方式"
+	case contactMatches > s.config.MaxContactMatches || contactDensity > s.config.MaxContactDensity:
+		result.IsSpam = true
+		result.Reason = "链接或联系方式过多"
+	default:
+		if s.isNearDuplicate(text, history) {
+			result.IsSpam = true
+			result.Reason = "与近期发布的内容重复"
+		}
+	}
+
+	if result.IsSpam {
+		slog.InfoContext(ctx, "命中反垃圾规则", "user_id", author.Id, "reason", result.Reason, "action", result.Action,
+			"contact_matches", contactMatches, "new_account", isNewAccount)
+	}
+	return result
+}
+
+// spamModerationStatus 根据反垃圾检测结果确定内容的审核状态，动作为拒绝时返回 ErrSpamRejected
+func spamModerationStatus(result *SpamCheckResult) (int, error) {
+	if result == nil || !result.IsSpam {
+		return model.ModerationStatusNormal, nil
+	}
+	switch result.Action {
+	case SpamActionReject:
+		return 0, ErrSpamRejected
+	case SpamActionShadow:
+		return model.ModerationStatusShadowHidden, nil
+	default:
+		return model.ModerationStatusPending, nil
+	}
+}
+
+// isNearDuplicate 判断内容是否与近期内容近似重复
+func (s *SpamService) isNearDuplicate(text string, history []string) bool {
+	normalized := normalizeSpamText(text)
+	if len([]rune(normalized)) < s.config.DuplicateMinLength {
+		return false
+	}
+	hash := simhash(normalized)
+	for _, previous := range history {
+		if bits.OnesCount64(hash^simhash(normalizeSpamText(previous))) <= s.config.DuplicateDistance {
+			return true
+		}
+	}
+	return false
+}
+
+// contactInfoStats 统计链接/联系方式的数量及其字符占比
+func contactInfoStats(text string) (int, float64) {
+	total := len([]rune(text))
+	if total == 0 {
+		return 0, 0
+	}
+	matches := 0
+	matchedRunes := 0
+	for _, pattern := range contactPatterns {
+		for _, match := range pattern.FindAllString(text, -1) {
+			matches++
+			matchedRunes += len([]rune(match))
+		}
+	}
+	for _, loc := range phonePattern.FindAllStringIndex(text, -1) {
+		if isASCIIDigitAt(text, loc[0]-1) || isASCIIDigitAt(text, loc[1]) {
+			continue
+		}
+		matches++
+		matchedRunes += loc[1] - loc[0]
+	}
+	return matches, float64(matchedRunes) / float64(total)
+}
+
+// isASCIIDigitAt 判断text第i个字节是否为数字，越界时返回false
+func isASCIIDigitAt(text string, i int) bool {
+	return i >= 0 && i < len(text) && text[i] >= '0' && text[i] <= '9'
+}
+
+// normalizeSpamText 统一大小写并去除空白和标点，避免插入符号绕过重复检测
+func normalizeSpamText(text string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+// simhash 以相邻两个字符为特征计算64位SimHash
+func simhash(text string) uint64 {
+	runes := []rune(text)
+	if len(runes) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	addFeature := func(feature string) {
+		hasher := fnv.New64a()
+		hasher.Write([]byte(feature))
+		hash := hasher.Sum64()
+		for i := 0; i < 64; i++ {
+			if hash&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+
+	if len(runes) == 1 {
+		addFeature(string(runes))
+	}
+	for i := 0; i+1 < len(runes); i++ {
+		addFeature(string(runes[i : i+2]))
+	}
+
+	var result uint64
+	for i := 0; i < 64; i++ {
+		if weights[i] > 0 {
+			result |= 1 << uint(i)
+		}
+	}
+	return result
+}
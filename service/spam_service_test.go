@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"math/bits"
+	"testing"
+	"time"
+	"wxcloudrun-golang/db/model"
+)
+
+func TestContactInfoStats(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		wantMatches int
+	}{
+		{name: "链接", text: "详情见 https://example.com/a?id=1", wantMatches: 1},
+		{name: "域名", text: "上 abc-shop.top 看看", wantMatches: 1},
+		{name: "微信号", text: "有意加微信：abc_12345 详聊", wantMatches: 1},
+		{name: "QQ号", text: "QQ号 123456789", wantMatches: 1},
+		{name: "手机号", text: "电话13812345678", wantMatches: 1},
+		{name: "逗号分隔的两个手机号", text: "13812345678,13912345678", wantMatches: 2},
+		{name: "多种联系方式", text: "加vx abcdef1 或扣扣 10001000，官网 www.example.cn", wantMatches: 3},
+		{name: "正文中的数字", text: "我今年25岁，花了128元买了3斤苹果", wantMatches: 0},
+		{name: "日期和版本号", text: "会议定在2023.10.19下午3点，发布1.2.3版本", wantMatches: 0},
+		{name: "长订单号不是手机号", text: "订单号202310191234567890已发货", wantMatches: 0},
+		{name: "手机号位数不够", text: "编号1381234567", wantMatches: 0},
+		{name: "技术名词不是域名", text: "用了node.js和vue写的", wantMatches: 0},
+		{name: "只提到微信", text: "微信上说吧", wantMatches: 0},
+		{name: "空文本", text: "", wantMatches: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, density := contactInfoStats(tt.text)
+			if matches != tt.wantMatches {
+				t.Errorf("contactInfoStats(%q) matches = %d, want %d", tt.text, matches, tt.wantMatches)
+			}
+			if (matches == 0) != (density == 0) || density > 1 {
+				t.Errorf("contactInfoStats(%q) density = %v", tt.text, density)
+			}
+		})
+	}
+}
+
+func TestSimhashDistance(t *testing.T) {
+	const base = "今天在楼下捡到一只橘色的小猫，很亲人，有人丢了吗？请联系我"
+
+	tests := []struct {
+		name    string
+		a, b    string
+		maxDist int // 海明距离上限，-1表示不限
+		minDist int
+	}{
+		{name: "相同内容", a: base, b: base, maxDist: 0, minDist: 0},
+		{name: "插入空白和符号", a: base, b: "今天 在楼下！捡到一只橘色的小猫~~很亲人 有人丢了吗请联系我", maxDist: 0, minDist: 0},
+		{name: "大小写不同", a: "Selling iPhone 15 Pro cheap", b: "selling IPHONE 15 pro CHEAP", maxDist: 0, minDist: 0},
+		{name: "末尾多一个字", a: base, b: base + "哦", maxDist: 3, minDist: 0},
+		{name: "无关内容", a: base, b: "周末去爬山吗，天气预报说周六是晴天，适合出去走走", maxDist: -1, minDist: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dist := bits.OnesCount64(simhash(normalizeSpamText(tt.a)) ^ simhash(normalizeSpamText(tt.b)))
+			if (tt.maxDist >= 0 && dist > tt.maxDist) || dist < tt.minDist {
+				t.Errorf("海明距离 = %d, want [%d, %d]", dist, tt.minDist, tt.maxDist)
+			}
+		})
+	}
+
+	if simhash("") != 0 {
+		t.Error("空文本的SimHash应为0")
+	}
+}
+
+func TestSpamServiceCheck(t *testing.T) {
+	config := SpamConfig{
+		Action:                 SpamActionReview,
+		DuplicateDistance:      3,
+		DuplicateMinLength:     10,
+		MaxContactMatches:      2,
+		MaxContactDensity:      0.3,
+		NewAccountAge:          24 * time.Hour,
+		NewAccountPostLimit:    3,
+		NewAccountCommentLimit: 10,
+	}
+	newAccount := &model.UserModel{Id: 1, CreatedAt: time.Now().Add(-time.Hour)}
+	oldAccount := &model.UserModel{Id: 2, CreatedAt: time.Now().Add(-30 * 24 * time.Hour)}
+	history := []string{"早", "午饭吃什么", "晚安"}
+	const text = "今天在楼下捡到一只橘色的小猫，很亲人，有人丢了吗"
+
+	tests := []struct {
+		name       string
+		author     *model.UserModel
+		text       string
+		history    []string
+		limit      int
+		wantReason string
+	}{
+		{name: "新账号达到发布上限", author: newAccount, text: text, history: history, limit: 3, wantReason: "新账号发布过于频繁"},
+		{name: "新账号未达到发布上限", author: newAccount, text: text, history: history[:2], limit: 3},
+		{name: "老账号不限频", author: oldAccount, text: text, history: history, limit: 3},
+		{name: "新账号发布联系方式", author: newAccount, text: "小猫找主人，电话13812345678", limit: 3, wantReason: "新账号发布链接或联系方式"},
+		{name: "新账号正文中的数字不算联系方式", author: newAccount, text: "小猫大概3个月大，2.5斤重，今天10点在3号楼捡到", limit: 3},
+		{name: "老账号少量联系方式", author: oldAccount, text: "橘色小猫找主人，昨晚在3号楼楼下发现的，很亲人，有消息请联系，电话13812345678，谢谢大家帮忙转发", limit: 3},
+		{name: "老账号联系方式过多", author: oldAccount, text: "加vx abcdef1，QQ 10001000，电话13812345678", limit: 3, wantReason: "链接或联系方式过多"},
+		{name: "与近期内容重复", author: oldAccount, text: text, history: []string{"今天在楼下捡到一只橘色的小猫！很亲人，有人丢了吗？"}, limit: 3, wantReason: "与近期发布的内容重复"},
+		{name: "短内容不做重复检测", author: oldAccount, text: "好的", history: []string{"好的"}, limit: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &SpamService{config: config}
+			result := s.check(context.Background(), tt.author, tt.text, tt.history, tt.limit)
+			if result.IsSpam != (tt.wantReason != "") || result.Reason != tt.wantReason {
+				t.Errorf("check() = %v/%q, want reason %q", result.IsSpam, result.Reason, tt.wantReason)
+			}
+			if result.Action != config.Action {
+				t.Errorf("Action = %s, want %s", result.Action, config.Action)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	"wxcloudrun-golang/db/model"
+)
+
+// tempURLBatchSize 每次 batchdownloadfile 请求最多换取的文件数
+const tempURLBatchSize = 50
+
+// tempURLCacheMaxEntries 临时地址缓存的最大条目数，超出时先清理过期条目，仍超出则清空
+const tempURLCacheMaxEntries = 10000
+
+// tempURLEntry 缓存的临时地址
+type tempURLEntry struct {
+	url       string
+	expiresAt time.Time
+}
+
+// tempURLCache 云存储临时地址缓存，条目在有效期结束前失效，避免返回给客户端时已过期
+type tempURLCache struct {
+	mu      sync.Mutex
+	entries map[string]tempURLEntry
+}
+
+// sharedTempURLCache 进程内共享的临时地址缓存
+var sharedTempURLCache = &tempURLCache{entries: make(map[string]tempURLEntry)}
+
+// get 获取未过期的临时地址
+func (c *tempURLCache) get(fileId string, now time.Time) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[fileId]
+	if !ok || !now.Before(entry.expiresAt) {
+		return "", false
+	}
+	return entry.url, true
+}
+
+// set 写入临时地址
+func (c *tempURLCache) set(urls map[string]string, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries)+len(urls) > tempURLCacheMaxEntries {
+		now := time.Now()
+		for fileId, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, fileId)
+			}
+		}
+		if len(c.entries)+len(urls) > tempURLCacheMaxEntries {
+			c.entries = make(map[string]tempURLEntry)
+		}
+	}
+	for fileId, url := range urls {
+		c.entries[fileId] = tempURLEntry{url: url, expiresAt: expiresAt}
+	}
+}
+
+// TempURLResolver 将响应中的云存储文件ID（cloud://）换取为临时HTTPS地址。同一页的文件ID去重后合并请求，
+// 地址有效期为 TEMP_URL_MAX_AGE（默认2h），缓存到有效期剩余10%时重新换取
+type TempURLResolver struct {
+	cloudStorage *WechatCloudStorageService
+	cache        *tempURLCache
+	maxAge       time.Duration
+}
+
+// NewTempURLResolver 创建临时地址解析器
+func NewTempURLResolver() *TempURLResolver {
+	maxAge := envDuration("TEMP_URL_MAX_AGE", 2*time.Hour)
+	if maxAge < time.Minute {
+		maxAge = time.Minute
+	}
+	return &TempURLResolver{
+		cloudStorage: NewWechatCloudStorageService(),
+		cache:        sharedTempURLCache,
+		maxAge:       maxAge,
+	}
+}
+
+// wantTempURLs 请求是否要求在响应中返回临时地址（resolveUrls=true）
+func wantTempURLs(r *http.Request) bool {
+	want, _ := strconv.ParseBool(r.URL.Query().Get("resolveUrls"))
+	return want
+}
+
+// Resolve 批量换取临时地址，返回文件ID到地址的映射。非云存储文件ID原样返回；换取失败的文件不在结果中，只记录日志
+func (r *TempURLResolver) Resolve(ctx context.Context, fileIds []string) map[string]string {
+	urls := make(map[string]string, len(fileIds))
+	now := time.Now()
+	var missing []string
+	for _, fileId := range fileIds {
+		if fileId == "" {
+			continue
+		}
+		if _, seen := urls[fileId]; seen {
+			continue
+		}
+		if !r.cloudStorage.ValidateCloudID(fileId) {
+			urls[fileId] = fileId
+			continue
+		}
+		if url, ok := r.cache.get(fileId, now); ok {
+			urls[fileId] = url
+			continue
+		}
+		urls[fileId] = ""
+		missing = append(missing, fileId)
+	}
+
+	maxAge := int(r.maxAge / time.Second)
+	expiresAt := now.Add(r.maxAge * 9 / 10)
+	for start := 0; start < len(missing); start += tempURLBatchSize {
+		end := start + tempURLBatchSize
+		if end > len(missing) {
+			end = len(missing)
+		}
+		fetched, err := r.cloudStorage.GetTempFileURLs(ctx, missing[start:end], maxAge)
+		if err != nil {
+			slog.WarnContext(ctx, "换取云存储临时地址失败", "file_count", end-start, "error", err)
+			continue
+		}
+		r.cache.set(fetched, expiresAt)
+		for fileId, url := range fetched {
+			urls[fileId] = url
+		}
+	}
+	for fileId, url := range urls {
+		if url == "" {
+			delete(urls, fileId)
+		}
+	}
+	return urls
+}
+
+// URL 换取单个文件的临时地址，失败时返回空
+func (r *TempURLResolver) URL(ctx context.Context, fileId string) string {
+	return r.Resolve(ctx, []string{fileId})[fileId]
+}
+
+// ResolvePosts 为帖子列表的图片、语音、视频和作者头像填充临时地址，整页合并换取
+func (r *TempURLResolver) ResolvePosts(ctx context.Context, posts []*PostDetail) {
+	var fileIds []string
+	for _, post := range posts {
+		fileIds = append(fileIds, post.Images...)
+		fileIds = append(fileIds, post.Audio, post.Author.Avatar)
+		if post.Video != nil {
+			fileIds = append(fileIds, post.Video.FileId, post.Video.Cover)
+		}
+	}
+	urls := r.Resolve(ctx, fileIds)
+
+	for _, post := range posts {
+		post.ImageURLs = make([]string, len(post.Images))
+		for i, image := range post.Images {
+			post.ImageURLs[i] = urls[image]
+		}
+		post.AudioURL = urls[post.Audio]
+		post.Author.AvatarURL = urls[post.Author.Avatar]
+		if post.Video != nil {
+			post.Video.URL = urls[post.Video.FileId]
+			post.Video.CoverURL = urls[post.Video.Cover]
+		}
+	}
+}
+
+// ResolveComments 为评论及其回复的语音和作者头像填充临时地址，整页合并换取
+func (r *TempURLResolver) ResolveComments(ctx context.Context, comments []*CommentDetail) {
+	var fileIds []string
+	var collect func(list []*CommentDetail)
+	collect = func(list []*CommentDetail) {
+		for _, comment := range list {
+			fileIds = append(fileIds, comment.Audio, comment.Author.Avatar)
+			collect(comment.Replies)
+		}
+	}
+	collect(comments)
+	urls := r.Resolve(ctx, fileIds)
+
+	var fill func(list []*CommentDetail)
+	fill = func(list []*CommentDetail) {
+		for _, comment := range list {
+			comment.AudioURL = urls[comment.Audio]
+			comment.Author.AvatarURL = urls[comment.Author.Avatar]
+			fill(comment.Replies)
+		}
+	}
+	fill(comments)
+}
+
+// ResolveUser 为用户资料的头像和待审核头像填充临时地址
+func (r *TempURLResolver) ResolveUser(ctx context.Context, user *model.UserModel) {
+	urls := r.Resolve(ctx, []string{user.Avatar, user.PendingAvatar})
+	user.AvatarURL = urls[user.Avatar]
+	user.PendingAvatarURL = urls[user.PendingAvatar]
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+	"wxcloudrun-golang/db/model"
+)
+
+// ErrContentCheckUnavailable 降级模式为reject时，微信内容安全接口不可用直接拒绝发布
+var ErrContentCheckUnavailable = errors.New("内容安全检测暂不可用，请稍后重试")
+
+// 微信内容安全接口不可用时的降级处理，由 WECHAT_DEGRADED_ACTION 配置
+const (
+	DegradedActionReview = "review" // 默认：内容进入待审核状态，人工审核后展示
+	DegradedActionAllow  = "allow"  // 仅按本地词库判定，未命中直接发布
+	DegradedActionReject = "reject" // 拒绝发布，提示用户稍后重试
+)
+
+// TextCheckOutcome 单个文本字段的检测结论
+type TextCheckOutcome struct {
+	Action   string               // 处理动作：allow/review/shadow/reject
+	Result   *MsgSecCheckResponse // 微信检测结果，未调用或调用失败时为空
+	Keywords *KeywordMatch        // 本地关键词过滤结果
+	Fallback bool                 // 微信接口不可用，仅按本地词库判定
+}
+
+// ReviewItem 生成待审核项，审核对象由调用方在内容创建后补全
+func (o *TextCheckOutcome) ReviewItem(content string) *model.ModerationQueueModel {
+	if o.Result != nil && !o.Keywords.Matched() {
+		return newTextReviewItem(content, o.Result)
+	}
+	if o.Fallback && !o.Keywords.Matched() {
+		return &model.ModerationQueueModel{
+			Source:  model.ModerationSourceDegraded,
+			Content: content,
+			Reason:  "内容安全检测暂不可用，待人工审核",
+		}
+	}
+	item := &model.ModerationQueueModel{
+		Source:  model.ModerationSourceKeyword,
+		Content: content,
+		Reason:  truncateRunes("命中关键词："+strings.Join(o.Keywords.Words, "、"), 200),
+	}
+	if o.Result != nil {
+		item.TraceId = o.Result.TraceId
+		item.Suggest = o.Result.Result.Suggest
+		item.Label = o.Result.Result.Label
+	}
+	return item
+}
+
+// TextModerator 文本审核流程：本地关键词过滤 → 微信内容安全检测 → 审核策略。
+// 命中拒绝词时不再调用微信接口；微信接口不可用（重试失败或已熔断）时按降级模式处理
+type TextModerator struct {
+	keywordFilter   *KeywordFilterService
+	securityService *ContentSecurityService
+	policyService   *ModerationPolicyService
+	degradedAction  string
+}
+
+// NewTextModerator 创建文本审核实例
+func NewTextModerator() *TextModerator {
+	return &TextModerator{
+		keywordFilter:   NewKeywordFilterService(),
+		securityService: NewContentSecurityService(),
+		policyService:   NewModerationPolicyService(),
+		degradedAction:  degradedActionFromEnv(),
+	}
+}
+
+// degradedActionFromEnv 读取降级模式配置，未配置或无效时为review
+func degradedActionFromEnv() string {
+	switch action := os.Getenv("WECHAT_DEGRADED_ACTION"); action {
+	case DegradedActionAllow, DegradedActionReject:
+		return action
+	case "", DegradedActionReview:
+		return DegradedActionReview
+	default:
+		slog.Warn("WECHAT_DEGRADED_ACTION 配置无效，使用review", "value", action)
+		return DegradedActionReview
+	}
+}
+
+// Check 检测单个文本字段并记录到checkLog，openid为空时仅做本地关键词过滤。
+// 仅在降级模式为reject且微信接口不可用时返回 ErrContentCheckUnavailable
+func (m *TextModerator) Check(ctx context.Context, checkLog *ContentCheckLog, openid, field string, scene int, content string) (*TextCheckOutcome, error) {
+	outcome := &TextCheckOutcome{Action: PolicyActionAllow}
+
+	matchStart := time.Now()
+	outcome.Keywords = m.keywordFilter.Check(ctx, content)
+	if outcome.Keywords.Matched() {
+		checkLog.AddKeyword(field, scene, content, outcome.Keywords, time.Since(matchStart))
+		if outcome.Keywords.Action == model.KeywordActionReject {
+			outcome.Action = PolicyActionReject
+			return outcome, nil
+		}
+		outcome.Action = PolicyActionReview
+	}
+	if openid == "" {
+		return outcome, nil
+	}
+
+	checkStart := time.Now()
+	result, err := m.securityService.CheckText(ctx, openid, content, scene)
+	checkLog.AddText(field, scene, content, result, err, time.Since(checkStart))
+	if err != nil {
+		slog.WarnContext(ctx, "文本内容安全检测失败，按降级模式处理", "field", field, "scene", scene,
+			"keyword_action", outcome.Action, "degraded_action", m.degradedAction, "error", err)
+		outcome.Fallback = true
+		switch m.degradedAction {
+		case DegradedActionReject:
+			return nil, ErrContentCheckUnavailable
+		case DegradedActionReview:
+			if outcome.Action == PolicyActionAllow {
+				outcome.Action = PolicyActionReview
+			}
+		}
+		return outcome, nil
+	}
+
+	outcome.Result = result
+	if action := m.policyService.EvaluateText(ctx, scene, result); comparePolicyAction(action, outcome.Action) > 0 {
+		outcome.Action = action
+	}
+	return outcome, nil
+}
@@ -31,9 +31,30 @@ func (h *UserHandler) HandleUserRequests(w http.ResponseWriter, r *http.Request)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
-	case path == "/api/user/list":
+	case path == "/api/user/notifications":
 		if r.Method == http.MethodGet {
-			h.userService.GetUserList(w, r)
+			h.userService.GetNotifications(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	case path == "/api/user/notifications/read":
+		if r.Method == http.MethodPost {
+			h.userService.MarkNotificationsRead(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	case path == "/api/user/blocks":
+		switch r.Method {
+		case http.MethodGet:
+			h.userService.GetBlockedUsers(w, r)
+		case http.MethodPost:
+			h.userService.BlockUser(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	case strings.HasPrefix(path, "/api/user/blocks/"):
+		if r.Method == http.MethodDelete {
+			h.userService.UnblockUser(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
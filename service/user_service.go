@@ -2,19 +2,40 @@ package service
 
 import (
 	"encoding/json"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 	"wxcloudrun-golang/db/dao"
 )
 
 // UserService 用户服务
 type UserService struct {
-	userDao dao.UserDao
+	userDao           dao.UserDao
+	notificationDao   dao.NotificationDao
+	userBlockDao      dao.UserBlockDao
+	profileModeration *ProfileModerationService
+	urlResolver       *TempURLResolver
+}
+
+// maxBlockedUsers 单个用户最多可拉黑的人数，拉黑列表会作为子查询参与帖子和评论列表的过滤
+const maxBlockedUsers = 1000
+
+// BlockedUserInfo 拉黑列表项
+type BlockedUserInfo struct {
+	User      UserInfo  `json:"user"`
+	BlockedAt time.Time `json:"blockedAt"`
 }
 
 // NewUserService 创建用户服务实例
 func NewUserService() *UserService {
 	return &UserService{
-		userDao: dao.NewUserDao(),
+		userDao:           dao.NewUserDao(),
+		notificationDao:   dao.NewNotificationDao(),
+		userBlockDao:      dao.NewUserBlockDao(),
+		profileModeration: NewProfileModerationService(),
+		urlResolver:       NewTempURLResolver(),
 	}
 }
 
@@ -25,6 +46,9 @@ func (s *UserService) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "User not found", http.StatusNotFound)
 		return
 	}
+	if wantTempURLs(r) {
+		s.urlResolver.ResolveUser(r.Context(), userCtx.User)
+	}
 
 	// 返回用户信息
 	response := map[string]interface{}{
@@ -46,33 +70,20 @@ func (s *UserService) UpdateUserProfile(w http.ResponseWriter, r *http.Request)
 	}
 
 	// 解析请求体
-	var updateData struct {
-		Nickname string `json:"nickname"`
-		Avatar   string `json:"avatar"`
-		Bio      string `json:"bio"`
-	}
-
+	var updateData ProfileUpdate
 	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	// 更新用户信息
-	if updateData.Nickname != "" {
-		userCtx.User.Nickname = updateData.Nickname
-	}
-	if updateData.Avatar != "" {
-		userCtx.User.Avatar = updateData.Avatar
-	}
-	if updateData.Bio != "" {
-		userCtx.User.Bio = updateData.Bio
-	}
-
-	// 保存到数据库
-	if err := s.userDao.UpdateUser(userCtx.User); err != nil {
-		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+	// 检测并保存资料，待审核的昵称、简介和检测中的头像在审核通过前仅本人可见
+	if err := s.profileModeration.UpdateProfile(r.Context(), userCtx.User, userCtx.User.OpenId, &updateData); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if wantTempURLs(r) {
+		s.urlResolver.ResolveUser(r.Context(), userCtx.User)
+	}
 
 	// 返回更新后的用户信息
 	response := map[string]interface{}{
@@ -99,42 +110,216 @@ func (s *UserService) GetUserById(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "Not implemented", http.StatusNotImplemented)
 }
 
-// GetUserList 获取用户列表（管理员功能）
-func (s *UserService) GetUserList(w http.ResponseWriter, r *http.Request) {
-	// 检查当前用户是否有管理员权限
+// GetNotifications 获取当前用户的站内通知
+func (s *UserService) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	userCtx := GetUserFromContext(r)
+	if userCtx == nil || userCtx.User == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	page := 1
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p > 0 {
+		page = p
+	}
+	pageSize := 20
+	if ps, err := strconv.Atoi(r.URL.Query().Get("pageSize")); err == nil && ps > 0 && ps <= 50 {
+		pageSize = ps
+	}
+
+	notifications, total, err := s.notificationDao.GetByUserId(r.Context(), userCtx.User.Id, page, pageSize)
+	if err != nil {
+		http.Error(w, "Failed to get notifications", http.StatusInternalServerError)
+		return
+	}
+	unread, err := s.notificationDao.CountUnread(r.Context(), userCtx.User.Id)
+	if err != nil {
+		http.Error(w, "Failed to get notifications", http.StatusInternalServerError)
+		return
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+	response := map[string]interface{}{
+		"code": 0,
+		"msg":  "success",
+		"data": map[string]interface{}{
+			"list":   notifications,
+			"unread": unread,
+			"pagination": Pagination{
+				Current:  page,
+				PageSize: pageSize,
+				Total:    total,
+				HasMore:  page < totalPages,
+			},
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// MarkNotificationsRead 将当前用户的通知标记为已读，ids为空时全部标记
+func (s *UserService) MarkNotificationsRead(w http.ResponseWriter, r *http.Request) {
 	userCtx := GetUserFromContext(r)
 	if userCtx == nil || userCtx.User == nil {
 		http.Error(w, "Authentication required", http.StatusUnauthorized)
 		return
 	}
 
-	// 这里可以添加管理员权限检查
-	// if userCtx.User.Level < 10 { // 假设10级以上为管理员
-	//     http.Error(w, "Permission denied", http.StatusForbidden)
-	//     return
-	// }
+	var req struct {
+		Ids []int64 `json:"ids"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := s.notificationDao.MarkRead(r.Context(), userCtx.User.Id, req.Ids); err != nil {
+		http.Error(w, "Failed to mark notifications", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"code": 0,
+		"msg":  "success",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetBlockedUsers 获取当前用户的拉黑列表
+func (s *UserService) GetBlockedUsers(w http.ResponseWriter, r *http.Request) {
+	userCtx := GetUserFromContext(r)
+	if userCtx == nil || userCtx.User == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
 
-	// 获取分页参数
 	page := 1
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p > 0 {
+		page = p
+	}
 	pageSize := 20
+	if ps, err := strconv.Atoi(r.URL.Query().Get("pageSize")); err == nil && ps > 0 && ps <= 50 {
+		pageSize = ps
+	}
 
-	users, total, err := s.userDao.GetUsersByPage(page, pageSize)
+	blocks, total, err := s.userBlockDao.GetList(r.Context(), userCtx.User.Id, page, pageSize)
 	if err != nil {
-		http.Error(w, "Failed to get user list", http.StatusInternalServerError)
+		http.Error(w, "Failed to get blocked users", http.StatusInternalServerError)
 		return
 	}
 
+	list := make([]*BlockedUserInfo, 0, len(blocks))
+	for _, block := range blocks {
+		info := UserInfo{Id: block.BlockedId, Nickname: "未知用户", Level: 1}
+		if user, err := s.userDao.GetById(r.Context(), block.BlockedId); err == nil {
+			info = UserInfo{
+				Id:         user.Id,
+				Nickname:   user.Nickname,
+				Avatar:     user.Avatar,
+				Bio:        user.Bio,
+				Level:      user.Level,
+				IsVerified: user.IsVerified,
+			}
+		}
+		list = append(list, &BlockedUserInfo{User: info, BlockedAt: block.CreatedAt})
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
 	response := map[string]interface{}{
 		"code": 0,
 		"msg":  "success",
 		"data": map[string]interface{}{
-			"users":     users,
-			"total":     total,
-			"page":      page,
-			"pageSize":  pageSize,
+			"list": list,
+			"pagination": Pagination{
+				Current:  page,
+				PageSize: pageSize,
+				Total:    total,
+				HasMore:  page < totalPages,
+			},
 		},
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+// BlockUser 拉黑用户，被拉黑用户的帖子和评论不再出现在当前用户的列表中，且无法评论当前用户的帖子
+func (s *UserService) BlockUser(w http.ResponseWriter, r *http.Request) {
+	userCtx := GetUserFromContext(r)
+	if userCtx == nil || userCtx.User == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		UserId int64 `json:"userId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserId <= 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.UserId == userCtx.User.Id {
+		http.Error(w, "不能拉黑自己", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.userDao.GetById(r.Context(), req.UserId); err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	count, err := s.userBlockDao.Count(r.Context(), userCtx.User.Id)
+	if err != nil {
+		http.Error(w, "Failed to block user", http.StatusInternalServerError)
+		return
+	}
+	if count >= maxBlockedUsers {
+		http.Error(w, "拉黑人数已达上限", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.userBlockDao.Block(r.Context(), userCtx.User.Id, req.UserId); err != nil {
+		http.Error(w, "Failed to block user", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"code": 0,
+		"msg":  "success",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// UnblockUser 取消拉黑用户
+func (s *UserService) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	userCtx := GetUserFromContext(r)
+	if userCtx == nil || userCtx.User == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	blockedId, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/user/blocks/"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.userBlockDao.Unblock(r.Context(), userCtx.User.Id, blockedId); err != nil {
+		http.Error(w, "Failed to unblock user", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"code": 0,
+		"msg":  "success",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+	"wxcloudrun-golang/db/dao"
+	"wxcloudrun-golang/db/model"
+)
+
+// VideoCheckResult 视频检测结论
+type VideoCheckResult struct {
+	TraceId string  // 检测追踪ID，记录在检测记录和审核队列中
+	Suggest string  // 检测建议：pass/review/risky
+	Label   int     // 命中的标签，取值同图片检测
+	Prob    float64 // 置信度
+}
+
+// VideoChecker 视频检测器。微信内容安全接口不支持视频，视频检测由可替换的实现完成，
+// 通过 RegisterVideoChecker 注册，VIDEO_CHECKER 选择使用的实现（默认stub）
+type VideoChecker interface {
+	// Check 检测视频，playURL为视频的临时播放地址
+	Check(ctx context.Context, video *model.PostVideoModel, playURL string) (*VideoCheckResult, error)
+}
+
+var (
+	videoCheckersMu sync.RWMutex
+	videoCheckers   = map[string]func() VideoChecker{
+		"stub": newStubVideoChecker,
+	}
+)
+
+// RegisterVideoChecker 注册视频检测器实现，同名实现会被替换
+func RegisterVideoChecker(name string, factory func() VideoChecker) {
+	videoCheckersMu.Lock()
+	defer videoCheckersMu.Unlock()
+	videoCheckers[name] = factory
+}
+
+// newVideoChecker 按 VIDEO_CHECKER 创建视频检测器，未注册的名称使用stub
+func newVideoChecker() VideoChecker {
+	name := os.Getenv("VIDEO_CHECKER")
+	if name == "" {
+		name = "stub"
+	}
+	videoCheckersMu.RLock()
+	factory, ok := videoCheckers[name]
+	videoCheckersMu.RUnlock()
+	if !ok {
+		slog.Warn("未注册的视频检测器，使用stub", "video_checker", name)
+		factory = newStubVideoChecker
+	}
+	return factory()
+}
+
+// stubVideoChecker 本地视频检测器，不做实际检测，按 VIDEO_STUB_SUGGEST（默认pass）返回结论，用于开发和测试
+type stubVideoChecker struct {
+	suggest string
+}
+
+// newStubVideoChecker 创建本地视频检测器
+func newStubVideoChecker() VideoChecker {
+	suggest := SuggestPass
+	if value := os.Getenv("VIDEO_STUB_SUGGEST"); value != "" {
+		suggest = normalizeSuggest(value)
+	}
+	return &stubVideoChecker{suggest: suggest}
+}
+
+// Check 返回配置的结论
+func (c *stubVideoChecker) Check(ctx context.Context, video *model.PostVideoModel, playURL string) (*VideoCheckResult, error) {
+	return &VideoCheckResult{
+		TraceId: fmt.Sprintf("stub-video-%d-%d", video.PostId, time.Now().UnixNano()),
+		Suggest: c.suggest,
+		Label:   100,
+	}, nil
+}
+
+// VideoModerationService 视频审核：视频检测记录与图片共用 image_checks 和检测任务，
+// 由视频检测器同步给出结论后按审核策略处理，帖子的所有媒体检测通过后才公开展示
+type VideoModerationService struct {
+	mediaCheckDao     dao.MediaCheckDao
+	postDao           dao.PostDao
+	videoDao          dao.PostVideoDao
+	cloudStorage      *WechatCloudStorageService
+	checker           VideoChecker
+	policyService     *ModerationPolicyService
+	moderationService *ModerationService
+	accountService    *AccountService
+}
+
+// NewVideoModerationService 创建视频审核服务实例
+func NewVideoModerationService() *VideoModerationService {
+	return &VideoModerationService{
+		mediaCheckDao:     dao.NewMediaCheckDao(),
+		postDao:           dao.NewPostDao(),
+		videoDao:          dao.NewPostVideoDao(),
+		cloudStorage:      NewWechatCloudStorageService(),
+		checker:           newVideoChecker(),
+		policyService:     NewModerationPolicyService(),
+		moderationService: NewModerationService(),
+		accountService:    NewAccountService(),
+	}
+}
+
+// CheckVideo 检测帖子视频并处理结论。markSubmitted 记录本次检测的trace_id，返回false表示记录已被其他任务处理
+func (s *VideoModerationService) CheckVideo(ctx context.Context, check *model.MediaCheckModel, checkLog *ContentCheckLog,
+	markSubmitted func(traceId string) (bool, error)) error {
+	video, err := s.videoDao.GetByPostId(ctx, check.PostId)
+	if err != nil {
+		return fmt.Errorf("获取帖子视频失败: %v", err)
+	}
+	playURL, err := resolveVideoPlayURL(ctx, s.cloudStorage, video.FileId)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	result, err := s.checker.Check(ctx, video, playURL)
+	checkLog.AddVideo("video", SceneForum, video.FileId, result, err, time.Since(start))
+	if err != nil {
+		return fmt.Errorf("视频检测失败: %v", err)
+	}
+
+	submitted, err := markSubmitted(result.TraceId)
+	if err != nil {
+		return fmt.Errorf("记录视频检测提交结果失败: %v", err)
+	}
+	if !submitted {
+		slog.WarnContext(ctx, "视频检测记录已被其他任务提交", "check_id", check.Id, "trace_id", result.TraceId)
+		return nil
+	}
+	check.TraceId = result.TraceId
+
+	verdict := &MediaVerdict{Suggest: normalizeSuggest(result.Suggest), Label: result.Label, Prob: result.Prob}
+	action := s.policyService.EvaluateMedia(ctx, model.ContentCheckKindVideo, SceneForum, verdict)
+	status := model.MediaCheckStatusFailed
+	switch action {
+	case PolicyActionAllow, PolicyActionShadow:
+		status = model.MediaCheckStatusPassed
+	case PolicyActionReview:
+		status = model.MediaCheckStatusReview
+	}
+
+	applied, err := s.mediaCheckDao.ApplyResult(ctx, check.Id, time.Now().Unix(), status, verdict.Suggest, 0, "", nil)
+	if err != nil {
+		return fmt.Errorf("更新视频检测记录失败: %v", err)
+	}
+	if !applied {
+		return nil
+	}
+
+	if action == PolicyActionReject {
+		if post, err := s.postDao.GetById(ctx, check.PostId); err == nil {
+			s.accountService.RecordPostViolation(ctx, post.AuthorId, post.Id, model.ContentCheckKindVideo)
+		}
+	}
+	if err := settlePostMedia(ctx, s.postDao, s.moderationService, check, action, verdict.Suggest, verdict.Label); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "视频检测完成", "check_id", check.Id, "post_id", check.PostId, "trace_id", result.TraceId,
+		"suggest", verdict.Suggest, "action", action, "label", verdict.Label)
+	return nil
+}
+
+// resolveVideoPlayURL 将视频文件ID解析为播放地址：云存储文件ID换取临时下载地址，其他地址原样返回
+func resolveVideoPlayURL(ctx context.Context, cloudStorage *WechatCloudStorageService, fileId string) (string, error) {
+	if !cloudStorage.ValidateCloudID(fileId) {
+		return fileId, nil
+	}
+	url, err := cloudStorage.GetFileDownloadURL(ctx, fileId)
+	if err != nil {
+		return "", fmt.Errorf("获取视频播放地址失败: %v", err)
+	}
+	return url, nil
+}
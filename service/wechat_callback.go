@@ -1,13 +1,18 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"wxcloudrun-golang/db/dao"
 	"wxcloudrun-golang/db/model"
+	"wxcloudrun-golang/metrics"
+
+	"gorm.io/gorm"
 )
 
 // WechatMediaCheckCallback 微信媒体检测回调数据结构
@@ -35,17 +40,46 @@ type WechatMediaCheckCallback struct {
 	} `json:"result"`
 }
 
+// 回调处理结果，用于监控指标
+const (
+	callbackOutcomeVerify       = "verify"
+	callbackOutcomeUnauthorized = "unauthorized"
+	callbackOutcomeInvalid      = "invalid"
+	callbackOutcomeUnknownEvent = "unknown_event"
+	callbackOutcomeError        = "error"
+	callbackOutcomeDuplicate    = "duplicate"
+	callbackOutcomePassed       = "passed"
+	callbackOutcomeRejected     = "rejected"
+	callbackOutcomeReview       = "review"
+)
+
 // WechatCallbackHandler 微信回调处理器
 type WechatCallbackHandler struct {
-	imageCheckDao dao.ImageCheckDao
-	postDao       dao.PostDao
+	mediaCheckDao     dao.MediaCheckDao
+	postDao           dao.PostDao
+	commentDao        dao.CommentDao
+	moderationService *ModerationService
+	accountService    *AccountService
+	contentChecks     *ContentCheckService
+	verifier          *CallbackVerifier
+	verdictEngine     *MediaVerdictEngine
+	policyService     *ModerationPolicyService
+	profileModeration *ProfileModerationService
 }
 
 // NewWechatCallbackHandler 创建微信回调处理器
-func NewWechatCallbackHandler() *WechatCallbackHandler {
+func NewWechatCallbackHandler(verifier *CallbackVerifier) *WechatCallbackHandler {
 	return &WechatCallbackHandler{
-		imageCheckDao: dao.NewImageCheckDao(),
-		postDao:       dao.NewPostDao(),
+		mediaCheckDao:     dao.NewMediaCheckDao(),
+		postDao:           dao.NewPostDao(),
+		commentDao:        dao.NewCommentDao(),
+		moderationService: NewModerationService(),
+		accountService:    NewAccountService(),
+		contentChecks:     NewContentCheckService(),
+		verifier:          verifier,
+		verdictEngine:     NewMediaVerdictEngine(),
+		policyService:     NewModerationPolicyService(),
+		profileModeration: NewProfileModerationService(),
 	}
 }
 
@@ -54,32 +88,54 @@ func (h *WechatCallbackHandler) HandleMediaCheckCallback(w http.ResponseWriter,
 	// 设置响应头
 	w.Header().Set("Content-Type", "application/json")
 
+	ctx := r.Context()
+
+	// 消息推送服务器配置校验（GET请求带echostr），签名通过后原样返回echostr
+	if r.Method == http.MethodGet {
+		echo, err := h.verifier.VerifyEcho(ctx, r)
+		if err != nil {
+			slog.WarnContext(ctx, "回调服务器配置校验失败", "error", err)
+			metrics.ObserveCallback("", callbackOutcomeUnauthorized)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		metrics.ObserveCallback("", callbackOutcomeVerify)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(echo))
+		return
+	}
+
 	// 只允许POST请求
-	//if r.Method != "POST" {
-	//	http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	//	return
-	//}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 
 	// 读取请求体
-	body, err := io.ReadAll(r.Body)
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
 	if err != nil {
-		log.Printf("读取回调请求体失败: %v", err)
+		slog.ErrorContext(ctx, "读取回调请求体失败", "error", err)
+		metrics.ObserveCallback("", callbackOutcomeInvalid)
 		http.Error(w, "Failed to read request body", http.StatusBadRequest)
 		return
 	}
 
-	// 打印回调请求详情
-	fmt.Printf("=== 微信回调请求详情 ===\n")
-	fmt.Printf("请求方法: %s\n", r.Method)
-	fmt.Printf("请求URL: %s\n", r.URL.String())
-	fmt.Printf("请求头:\n")
-	for key, values := range r.Header {
-		for _, value := range values {
-			fmt.Printf("  %s: %s\n", key, value)
+	// 校验回调来源，安全模式下返回解密后的消息
+	body, err = h.verifier.Verify(ctx, r, body)
+	if err != nil {
+		if !errors.Is(err, ErrCallbackUnauthorized) {
+			slog.ErrorContext(ctx, "校验回调来源失败", "error", err)
+			metrics.ObserveCallback("", callbackOutcomeError)
+			http.Error(w, "Failed to verify callback", http.StatusInternalServerError)
+			return
 		}
+		slog.WarnContext(ctx, "拒绝来源不可信的回调", "error", err, "source", r.Header.Get("X-WX-SOURCE"))
+		metrics.ObserveCallback("", callbackOutcomeUnauthorized)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
 	}
-	fmt.Printf("请求体: %s\n", string(body))
-	fmt.Printf("=== 微信回调请求详情结束 ===\n")
+
+	slog.DebugContext(ctx, "收到微信回调", "method", r.Method, "body_size", len(body))
 
 	// 首先尝试解析为验证请求格式
 	var verifyRequest struct {
@@ -87,7 +143,9 @@ func (h *WechatCallbackHandler) HandleMediaCheckCallback(w http.ResponseWriter,
 	}
 	if err := json.Unmarshal(body, &verifyRequest); err == nil && verifyRequest.Action == "CheckContainerPath" {
 		// 这是验证请求，直接返回成功
-		log.Printf("收到验证请求: %s", verifyRequest.Action)
+		slog.InfoContext(ctx, "收到验证请求", "action", verifyRequest.Action)
+		metrics.ObserveCallback(verifyRequest.Action, callbackOutcomeVerify)
+		h.consumeNonce(ctx, r)
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("success"))
 		return
@@ -96,224 +154,346 @@ func (h *WechatCallbackHandler) HandleMediaCheckCallback(w http.ResponseWriter,
 	// 解析回调数据
 	var callback WechatMediaCheckCallback
 	if err := json.Unmarshal(body, &callback); err != nil {
-		log.Printf("解析回调数据失败: %v", err)
+		slog.WarnContext(ctx, "解析回调数据失败", "error", err)
+		metrics.ObserveCallback("", callbackOutcomeInvalid)
 		http.Error(w, "Invalid callback data", http.StatusBadRequest)
 		return
 	}
 
 	// 验证回调类型
 	if callback.Event != "wxa_media_check" {
-		log.Printf("未知的回调事件类型: %s", callback.Event)
+		slog.WarnContext(ctx, "未知的回调事件类型", "event", callback.Event)
+		metrics.ObserveCallback("", callbackOutcomeUnknownEvent)
 		http.Error(w, "Unknown event type", http.StatusBadRequest)
 		return
 	}
 
+	// 回调中的appid需与配置的小程序一致
+	if appId := h.verifier.AppId(); appId != "" && callback.Appid != appId {
+		slog.WarnContext(ctx, "回调appid不匹配", "appid", callback.Appid)
+		metrics.ObserveCallback(callback.Event, callbackOutcomeUnauthorized)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// 推送时间超出允许范围的回调视为重放
+	if !h.verifier.FreshCreateTime(callback.CreateTime) {
+		slog.WarnContext(ctx, "回调推送时间超出允许范围", "trace_id", callback.TraceId, "create_time", callback.CreateTime)
+		metrics.ObserveCallback(callback.Event, callbackOutcomeUnauthorized)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// 待提交的检测记录trace_id为空，缺少trace_id的回调不能参与匹配；CreateTime用于识别重复推送
+	if callback.TraceId == "" || callback.CreateTime <= 0 {
+		slog.WarnContext(ctx, "回调缺少trace_id或CreateTime", "trace_id", callback.TraceId, "create_time", callback.CreateTime)
+		metrics.ObserveCallback(callback.Event, callbackOutcomeInvalid)
+		http.Error(w, "Missing trace_id or CreateTime", http.StatusBadRequest)
+		return
+	}
+
 	// 处理媒体检测结果
-	err = h.processMediaCheckResult(&callback)
+	status, applied, err := h.processMediaCheckResult(ctx, &callback)
 	if err != nil {
-		log.Printf("处理媒体检测结果失败: %v", err)
+		slog.ErrorContext(ctx, "处理媒体检测结果失败", "trace_id", callback.TraceId, "error", err)
+		metrics.ObserveCallback(callback.Event, callbackOutcomeError)
 		http.Error(w, "Failed to process media check result", http.StatusInternalServerError)
 		return
 	}
+	switch {
+	case !applied:
+		// 重复或过期的回调同样返回成功，避免微信继续重试推送
+		metrics.ObserveCallback(callback.Event, callbackOutcomeDuplicate)
+	case status == model.MediaCheckStatusPassed:
+		metrics.ObserveCallback(callback.Event, callbackOutcomePassed)
+	case status == model.MediaCheckStatusReview:
+		metrics.ObserveCallback(callback.Event, callbackOutcomeReview)
+	default:
+		metrics.ObserveCallback(callback.Event, callbackOutcomeRejected)
+	}
+	h.consumeNonce(ctx, r)
 
 	// 返回成功响应
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("success"))
 }
 
-// processMediaCheckResult 处理媒体检测结果
-func (h *WechatCallbackHandler) processMediaCheckResult(callback *WechatMediaCheckCallback) error {
-	fmt.Printf("=== 处理媒体检测回调 ===\n")
-	fmt.Printf("回调数据详情:\n")
-	fmt.Printf("  - ToUserName: %s\n", callback.ToUserName)
-	fmt.Printf("  - FromUserName: %s\n", callback.FromUserName)
-	fmt.Printf("  - CreateTime: %d\n", callback.CreateTime)
-	fmt.Printf("  - MsgType: %s\n", callback.MsgType)
-	fmt.Printf("  - Event: %s\n", callback.Event)
-	fmt.Printf("  - Appid: %s\n", callback.Appid)
-	fmt.Printf("  - TraceId: %s\n", callback.TraceId)
-	fmt.Printf("  - Version: %d\n", callback.Version)
-	fmt.Printf("  - Errcode: %d\n", callback.Errcode)
-	fmt.Printf("  - Errmsg: %s\n", callback.Errmsg)
-	fmt.Printf("  - Result.Suggest: %s\n", callback.Result.Suggest)
-	fmt.Printf("  - Result.Label: %d\n", callback.Result.Label)
-
-	if len(callback.Detail) > 0 {
-		fmt.Printf("  - Detail数组:\n")
-		for i, detail := range callback.Detail {
-			fmt.Printf("    [%d] Strategy: %s, Errcode: %d, Suggest: %s, Label: %d, Prob: %.2f\n",
-				i, detail.Strategy, detail.Errcode, detail.Suggest, detail.Label, detail.Prob)
-		}
-	} else {
-		fmt.Printf("  - Detail数组: 空\n")
+// consumeNonce 回调处理成功后记录签名请求的nonce；处理失败返回500时不记录，微信重试的同一推送仍可通过校验。
+// 记录失败只影响重放检查，重复推送由检测记录的result_time去重
+func (h *WechatCallbackHandler) consumeNonce(ctx context.Context, r *http.Request) {
+	if err := h.verifier.ConsumeNonce(ctx, r); err != nil {
+		slog.WarnContext(ctx, "记录回调nonce失败", "error", err)
+	}
+}
+
+// processMediaCheckResult 处理媒体检测结果，返回图片检测状态；
+// 同一trace_id重复推送或晚于已有结果之前推送的回调不会改变检测记录，此时applied为false
+func (h *WechatCallbackHandler) processMediaCheckResult(ctx context.Context, callback *WechatMediaCheckCallback) (status int, applied bool, err error) {
+	slog.InfoContext(ctx, "处理媒体检测回调",
+		"trace_id", callback.TraceId,
+		"appid", callback.Appid,
+		"from_openid", callback.FromUserName,
+		"create_time", callback.CreateTime,
+		"errcode", callback.Errcode,
+		"suggest", callback.Result.Suggest,
+		"label", callback.Result.Label,
+		"detail_count", len(callback.Detail),
+	)
+	for i, detail := range callback.Detail {
+		slog.DebugContext(ctx, "媒体检测回调详情", "trace_id", callback.TraceId, "index", i,
+			"strategy", detail.Strategy, "errcode", detail.Errcode, "suggest", detail.Suggest,
+			"label", detail.Label, "prob", detail.Prob)
 	}
-	fmt.Printf("=== 回调数据详情结束 ===\n")
 
 	// 根据trace_id查找对应的检测记录
-	fmt.Printf("查找检测记录 - TraceId: %s\n", callback.TraceId)
-	imageCheck, err := h.imageCheckDao.GetByTraceId(callback.TraceId)
+	mediaCheck, err := h.mediaCheckDao.GetByTraceId(ctx, callback.TraceId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 超时重新提交后trace_id已更新，旧trace_id的回调直接忽略
+		slog.WarnContext(ctx, "回调trace_id无对应检测记录，忽略", "trace_id", callback.TraceId)
+		return 0, false, nil
+	}
 	if err != nil {
-		fmt.Printf("❌ 未找到对应的检测记录: %v\n", err)
-		return fmt.Errorf("未找到对应的检测记录: %v", err)
+		return 0, false, fmt.Errorf("获取检测记录失败: %v", err)
 	}
-	fmt.Printf("✅ 找到检测记录 - PostId: %d, ImageURL: %s, Status: %d\n",
-		imageCheck.PostId, imageCheck.ImageURL, imageCheck.Status)
 
-	// 确定检测状态和建议
-	var status int
+	// 综合整体结果和所有检测策略确定检测状态
 	var suggest string
 	var label int
 	var prob float64
-	var strategy string
+	var details []*model.MediaCheckDetailModel
+	action := PolicyActionReject
+	scene := SceneForum
+	switch {
+	case mediaCheck.UserId != 0:
+		scene = SceneProfile
+	case mediaCheck.CommentId != 0:
+		scene = SceneComment
+	}
 
 	if callback.Errcode == 0 {
-		// 检测成功
-		if len(callback.Detail) > 0 {
-			detail := callback.Detail[0]
-			suggest = detail.Suggest
-			label = detail.Label
-			prob = detail.Prob
-			strategy = detail.Strategy
-		} else {
-			suggest = callback.Result.Suggest
-			label = callback.Result.Label
+		verdict := h.verdictEngine.Judge(callback)
+		suggest, label, prob = verdict.Suggest, verdict.Label, verdict.Prob
+		for _, d := range verdict.Details {
+			details = append(details, &model.MediaCheckDetailModel{
+				Strategy: d.Strategy,
+				Errcode:  d.Errcode,
+				Suggest:  d.Suggest,
+				Label:    d.Label,
+				Prob:     d.Prob,
+				Verdict:  d.Verdict,
+			})
 		}
 
-		// 根据建议确定状态
-		switch suggest {
-		case "pass":
-			status = model.ImageCheckStatusPassed
-		case "review":
-			status = model.ImageCheckStatusFailed
-		case "risky":
-			status = model.ImageCheckStatusFailed
+		// 按审核策略确定状态，review交由人工审核；静默隐藏的媒体记为通过，帖子或评论改为仅作者可见
+		action = h.policyService.EvaluateMedia(ctx, mediaCheckKind(mediaCheck), scene, verdict)
+		switch action {
+		case PolicyActionAllow, PolicyActionShadow:
+			status = model.MediaCheckStatusPassed
+		case PolicyActionReview:
+			status = model.MediaCheckStatusReview
 		default:
-			status = model.ImageCheckStatusFailed
+			status = model.MediaCheckStatusFailed
 		}
 	} else {
 		// 检测失败
-		status = model.ImageCheckStatusFailed
+		status = model.MediaCheckStatusFailed
 		suggest = "failed"
-		label = 0
-		prob = 0
-		strategy = ""
-	}
-
-	// 更新检测记录
-	fmt.Printf("更新检测记录:\n")
-	fmt.Printf("  - TraceId: %s\n", callback.TraceId)
-	fmt.Printf("  - Status: %d\n", status)
-	fmt.Printf("  - Suggest: %s\n", suggest)
-	fmt.Printf("  - Label: %d\n", label)
-	fmt.Printf("  - Prob: %.2f\n", prob)
-	fmt.Printf("  - Strategy: %s\n", strategy)
-	fmt.Printf("  - Errcode: %d\n", callback.Errcode)
-	fmt.Printf("  - Errmsg: %s\n", callback.Errmsg)
-
-	err = h.imageCheckDao.UpdateStatus(
-		callback.TraceId,
+	}
+
+	// 更新检测记录，重复推送（CreateTime相同）、过期的回调以及超时处理或人工审核后迟到的回调不会覆盖已有结果
+	applied, err = h.mediaCheckDao.ApplyResult(
+		ctx,
+		mediaCheck.Id,
+		callback.CreateTime,
 		status,
 		suggest,
-		label,
-		prob,
-		strategy,
 		callback.Errcode,
 		callback.Errmsg,
+		details,
 	)
 	if err != nil {
-		fmt.Printf("❌ 更新检测记录失败: %v\n", err)
-		return fmt.Errorf("更新检测记录失败: %v", err)
+		return 0, false, fmt.Errorf("更新检测记录失败: %v", err)
 	}
-	fmt.Printf("✅ 检测记录更新成功\n")
-
-	// 检查帖子的所有图片是否都检测完成
-	err = h.checkPostImageCheckStatus(imageCheck.PostId)
-	if err != nil {
-		return fmt.Errorf("检查帖子图片检测状态失败: %v", err)
+	if !applied {
+		slog.InfoContext(ctx, "忽略重复、过期或已有结论的媒体检测回调", "trace_id", callback.TraceId,
+			"create_time", callback.CreateTime, "result_time", mediaCheck.ResultTime, "status", mediaCheck.Status)
+		return mediaCheck.Status, false, nil
 	}
+	h.contentChecks.RecordMediaResult(ctx, callback.TraceId, suggest, label, prob, callback.Errcode, callback.Errmsg)
 
-	log.Printf("媒体检测结果处理完成 - TraceId: %s, Status: %d, Suggest: %s",
-		callback.TraceId, status, suggest)
-
-	return nil
-}
-
-// checkPostImageCheckStatus 检查帖子的所有图片检测状态
-func (h *WechatCallbackHandler) checkPostImageCheckStatus(postId int64) error {
-	fmt.Printf("=== 检查帖子图片检测状态 - PostId: %d ===\n", postId)
-
-	// 获取帖子的所有图片检测记录
-	imageChecks, err := h.imageCheckDao.GetByPostId(postId)
-	if err != nil {
-		fmt.Printf("❌ 获取帖子图片检测记录失败: %v\n", err)
-		return fmt.Errorf("获取帖子图片检测记录失败: %v", err)
+	// 头像检测：通过后替换头像，违规时恢复原头像
+	if mediaCheck.UserId != 0 {
+		if err := h.settleAvatarCheck(ctx, mediaCheck, callback, action, suggest, label); err != nil {
+			return 0, false, err
+		}
+		return status, true, nil
 	}
 
-	fmt.Printf("帖子共有 %d 张图片需要检测\n", len(imageChecks))
+	// 语音评论：通过后公开评论，违规时驳回评论
+	if mediaCheck.CommentId != 0 {
+		if err := h.settleCommentMedia(ctx, mediaCheck, callback, action, suggest, label); err != nil {
+			return 0, false, err
+		}
+		return status, true, nil
+	}
 
-	if len(imageChecks) == 0 {
-		fmt.Printf("没有图片，无需处理\n")
-		return nil // 没有图片，无需处理
+	// 按策略判定为违规的媒体计入作者的违规次数，同一帖子的多个违规媒体只计一次
+	if callback.Errcode == 0 && action == PolicyActionReject {
+		if post, err := h.postDao.GetById(ctx, mediaCheck.PostId); err == nil {
+			h.accountService.RecordPostViolation(ctx, post.AuthorId, post.Id, mediaCheckKind(mediaCheck))
+		}
 	}
 
-	// 检查是否所有图片都检测完成
-	allCompleted := true
-	allPassed := true
+	if err := settlePostMedia(ctx, h.postDao, h.moderationService, mediaCheck, action, suggest, label); err != nil {
+		return 0, false, err
+	}
 
-	fmt.Printf("检查每张图片的检测状态:\n")
-	for i, check := range imageChecks {
-		statusText := "未知"
-		switch check.Status {
-		case model.ImageCheckStatusPending:
-			statusText = "待检测"
-		case model.ImageCheckStatusChecking:
-			statusText = "检测中"
-		case model.ImageCheckStatusPassed:
-			statusText = "检测通过"
-		case model.ImageCheckStatusFailed:
-			statusText = "检测失败"
-		}
+	slog.InfoContext(ctx, "媒体检测结果处理完成", "trace_id", callback.TraceId, "post_id", mediaCheck.PostId,
+		"status", status, "suggest", suggest, "action", action, "label", label, "prob", prob)
 
-		fmt.Printf("  [%d] 图片: %s, 状态: %d (%s), 建议: %s\n",
-			i+1, check.ImageURL, check.Status, statusText, check.Suggest)
+	return status, true, nil
+}
 
-		if check.Status == model.ImageCheckStatusPending || check.Status == model.ImageCheckStatusChecking {
-			allCompleted = false
+// settlePostMedia 按帖子媒体的处理动作更新帖子：需要人工审核的媒体进入审核队列，命中静默隐藏策略时帖子仅作者可见，
+// 然后重新汇总帖子所有媒体的检测状态
+func settlePostMedia(ctx context.Context, postDao dao.PostDao, moderationService *ModerationService, mediaCheck *model.MediaCheckModel,
+	action, suggest string, label int) error {
+	switch action {
+	case PolicyActionReview:
+		post, err := postDao.GetById(ctx, mediaCheck.PostId)
+		if err != nil {
+			return fmt.Errorf("获取帖子失败: %v", err)
 		}
-		if check.Status == model.ImageCheckStatusFailed {
-			allPassed = false
+		err = moderationService.Enqueue(ctx, &model.ModerationQueueModel{
+			TargetType:   model.ModerationTargetPost,
+			TargetId:     post.Id,
+			PostId:       post.Id,
+			AuthorId:     post.AuthorId,
+			Source:       mediaModerationSource(mediaCheck),
+			Content:      mediaCheck.MediaURL,
+			Reason:       mediaName(mediaCheck) + "内容安全检测建议人工审核",
+			TraceId:      mediaCheck.TraceId,
+			MediaCheckId: mediaCheck.Id,
+			Suggest:      suggest,
+			Label:        label,
+		})
+		if err != nil {
+			return err
+		}
+	case PolicyActionShadow:
+		err := postDao.UpdateModerationStatus(ctx, mediaCheck.PostId, model.ModerationStatusShadowHidden, mediaName(mediaCheck)+"命中审核策略")
+		if err != nil {
+			return fmt.Errorf("隐藏帖子失败: %v", err)
 		}
 	}
 
-	fmt.Printf("检测完成状态: %t, 全部通过: %t\n", allCompleted, allPassed)
-
-	if !allCompleted {
-		fmt.Printf("还有图片在检测中，等待下次回调\n")
-		return nil // 还有图片在检测中
+	// 检查帖子的所有媒体是否都检测完成
+	if err := syncPostMediaCheckStatus(ctx, postDao, mediaCheck.PostId); err != nil {
+		return fmt.Errorf("检查帖子媒体检测状态失败: %v", err)
 	}
+	return nil
+}
 
-	// 所有图片检测完成，更新帖子状态
-	var postStatus int
-	var statusText string
-	if allPassed {
-		postStatus = model.ImageCheckStatusPassed // 所有图片检测通过
-		statusText = "检测通过"
-	} else {
-		postStatus = model.ImageCheckStatusFailed // 有图片检测失败
-		statusText = "检测失败"
+// settleAvatarCheck 按头像检测结论更新用户资料，违规头像计入用户的违规次数
+func (h *WechatCallbackHandler) settleAvatarCheck(ctx context.Context, mediaCheck *model.MediaCheckModel, callback *WechatMediaCheckCallback,
+	action, suggest string, label int) error {
+	reason := "头像不符合社区规范"
+	switch {
+	case callback.Errcode != 0:
+		reason = "头像检测失败"
+	case action == PolicyActionReview:
+		reason = "头像内容安全检测建议人工审核"
+	case action == PolicyActionReject:
+		h.accountService.RecordViolation(ctx, mediaCheck.UserId, "avatar")
+	}
+	if err := h.profileModeration.SettleAvatarCheck(ctx, mediaCheck, action, reason, suggest, label); err != nil {
+		return fmt.Errorf("处理头像检测结果失败: %v", err)
 	}
+	slog.InfoContext(ctx, "头像检测结果处理完成", "trace_id", callback.TraceId, "user_id", mediaCheck.UserId,
+		"suggest", suggest, "action", action, "label", label)
+	return nil
+}
 
-	fmt.Printf("更新帖子状态: %d (%s)\n", postStatus, statusText)
+// settleCommentMedia 按语音评论的检测结论更新评论状态，违规语音计入作者的违规次数
+func (h *WechatCallbackHandler) settleCommentMedia(ctx context.Context, mediaCheck *model.MediaCheckModel, callback *WechatMediaCheckCallback,
+	action, suggest string, label int) error {
+	name := mediaName(mediaCheck)
+	reason := name + "不符合社区规范"
+	switch {
+	case callback.Errcode != 0:
+		reason = name + "检测失败"
+	case action == PolicyActionReview:
+		reason = name + "内容安全检测建议人工审核"
+	case action == PolicyActionShadow:
+		reason = name + "命中审核策略"
+	case action == PolicyActionReject:
+		if comment, err := h.commentDao.GetById(ctx, mediaCheck.CommentId); err == nil {
+			h.accountService.RecordViolation(ctx, comment.AuthorId, "comment_"+mediaCheckKind(mediaCheck))
+		}
+	}
+	if err := h.moderationService.SettleCommentMedia(ctx, mediaCheck, action, reason, suggest, label); err != nil {
+		return fmt.Errorf("处理评论%s检测结果失败: %v", name, err)
+	}
+	slog.InfoContext(ctx, "评论媒体检测结果处理完成", "trace_id", callback.TraceId, "comment_id", mediaCheck.CommentId,
+		"suggest", suggest, "action", action, "label", label)
+	return nil
+}
 
-	// 更新帖子状态
-	err = h.postDao.UpdateImageCheckStatus(postId, postStatus)
+// syncPostMediaCheckStatus 汇总帖子所有图片和语音的检测状态并更新到帖子上，汇总在锁定帖子行的事务中进行，
+// 避免同一帖子的多个回调同时到达时各自基于不完整的结果覆盖帖子状态
+func syncPostMediaCheckStatus(ctx context.Context, postDao dao.PostDao, postId int64) error {
+	var imageCount int
+	var postStatus int
+	var completed bool
+	err := postDao.SyncMediaCheckStatus(ctx, postId, func(checks []*model.MediaCheckModel) (int, bool) {
+		imageCount = len(checks)
+		postStatus, completed = aggregateMediaCheckStatus(checks)
+		return postStatus, completed
+	})
 	if err != nil {
-		fmt.Printf("❌ 更新帖子图片检测状态失败: %v\n", err)
 		return fmt.Errorf("更新帖子图片检测状态失败: %v", err)
 	}
 
-	fmt.Printf("✅ 帖子图片检测完成 - PostId: %d, Status: %d (%s)\n", postId, postStatus, statusText)
-	fmt.Printf("=== 检查帖子图片检测状态结束 ===\n")
+	if imageCount == 0 {
+		return nil // 没有图片，无需处理
+	}
+	if !completed {
+		slog.DebugContext(ctx, "还有图片在检测中，等待下次回调", "post_id", postId, "image_count", imageCount)
+		return nil
+	}
+
+	slog.InfoContext(ctx, "帖子图片检测完成", "post_id", postId, "status", postStatus, "image_count", imageCount)
 
 	return nil
 }
+
+// aggregateMediaCheckStatus 根据所有图片的检测状态计算帖子的图片检测状态，还有图片未完成检测时返回false
+func aggregateMediaCheckStatus(mediaChecks []*model.MediaCheckModel) (int, bool) {
+	if len(mediaChecks) == 0 {
+		return 0, false
+	}
+
+	// 检查是否所有图片都检测完成
+	anyFailed := false
+	anyReview := false
+
+	for _, check := range mediaChecks {
+		switch check.Status {
+		case model.MediaCheckStatusPending, model.MediaCheckStatusChecking:
+			return 0, false // 还有图片在检测中
+		case model.MediaCheckStatusFailed:
+			anyFailed = true
+		case model.MediaCheckStatusReview:
+			anyReview = true
+		}
+	}
+
+	switch {
+	case anyFailed:
+		return model.MediaCheckStatusFailed, true // 有图片检测失败
+	case anyReview:
+		return model.MediaCheckStatusReview, true // 有图片待人工审核，审核前不展示
+	default:
+		return model.MediaCheckStatusPassed, true // 所有图片检测通过
+	}
+}
@@ -2,24 +2,38 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"time"
+	"wxcloudrun-golang/metrics"
+	"wxcloudrun-golang/tracing"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // WechatCloudStorageService 微信云存储服务
 type WechatCloudStorageService struct {
-	client *http.Client
+	client       *http.Client
+	uploadClient *http.Client // 上传文件内容到COS，不经过微信接口熔断
 }
 
 // NewWechatCloudStorageService 创建微信云存储服务实例
 func NewWechatCloudStorageService() *WechatCloudStorageService {
 	return &WechatCloudStorageService{
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: newWechatTransport(otelhttp.NewTransport(http.DefaultTransport), cloudStorageBreaker),
+		},
+		uploadClient: &http.Client{
+			Timeout:   60 * time.Second,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
 		},
 	}
 }
@@ -49,7 +63,17 @@ type BatchDownloadFileResponse struct {
 }
 
 // GetFileDownloadURL 获取单个文件的下载URL
-func (s *WechatCloudStorageService) GetFileDownloadURL(cloudID string) (string, error) {
+func (s *WechatCloudStorageService) GetFileDownloadURL(ctx context.Context, cloudID string) (downloadURL string, err error) {
+	ctx, span := tracing.Start(ctx, "WechatCloudStorageService.GetFileDownloadURL")
+	span.SetAttributes(attribute.Int("cloud.file_count", 1))
+	start := time.Now()
+	var response BatchDownloadFileResponse
+	responded := false
+	defer func() {
+		observeBatchDownload(response.Errcode, responded, err, time.Since(start))
+		tracing.End(span, err)
+	}()
+
 	// 获取环境ID
 	envID := os.Getenv("ENV_ID")
 	if envID == "" {
@@ -77,7 +101,7 @@ func (s *WechatCloudStorageService) GetFileDownloadURL(cloudID string) (string,
 	}
 
 	// 创建HTTP请求
-	req, err := http.NewRequest("POST", "http://api.weixin.qq.com/tcb/batchdownloadfile", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", "http://api.weixin.qq.com/tcb/batchdownloadfile", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", fmt.Errorf("创建HTTP请求失败: %v", err)
 	}
@@ -85,15 +109,6 @@ func (s *WechatCloudStorageService) GetFileDownloadURL(cloudID string) (string,
 	// 设置请求头
 	req.Header.Set("Content-Type", "application/json")
 
-	// 打印请求详情
-	fmt.Printf("=== 微信云存储下载URL请求详情 ===\n")
-	fmt.Printf("请求URL: %s\n", req.URL.String())
-	fmt.Printf("请求方法: %s\n", req.Method)
-	fmt.Printf("环境ID: %s\n", envID)
-	fmt.Printf("云存储文件ID: %s\n", cloudID)
-	fmt.Printf("请求体JSON: %s\n", string(jsonData))
-	fmt.Printf("=== 微信云存储下载URL请求详情结束 ===\n")
-
 	// 发送请求
 	resp, err := s.client.Do(req)
 	if err != nil {
@@ -107,17 +122,15 @@ func (s *WechatCloudStorageService) GetFileDownloadURL(cloudID string) (string,
 		return "", fmt.Errorf("读取响应内容失败: %v", err)
 	}
 
-	// 打印响应详情
-	fmt.Printf("=== 微信云存储下载URL响应详情 ===\n")
-	fmt.Printf("响应状态码: %d\n", resp.StatusCode)
-	fmt.Printf("响应体: %s\n", string(body))
-	fmt.Printf("=== 微信云存储下载URL响应详情结束 ===\n")
-
-	// 解析响应
-	var response BatchDownloadFileResponse
+	// 解析响应，响应中的下载URL带临时签名，只记录错误码和文件数
 	if err := json.Unmarshal(body, &response); err != nil {
+		slog.DebugContext(ctx, "云存储下载URL响应无法解析", "env", envID, "cloud_id", cloudID, "status", resp.StatusCode, "size", len(body))
 		return "", fmt.Errorf("解析响应失败: %v", err)
 	}
+	slog.DebugContext(ctx, "云存储下载URL响应", "env", envID, "cloud_id", cloudID, "status", resp.StatusCode,
+		"errcode", response.Errcode, "errmsg", response.Errmsg, "file_count", len(response.FileList))
+
+	responded = true
 
 	// 检查错误码
 	if response.Errcode != 0 {
@@ -142,11 +155,26 @@ func (s *WechatCloudStorageService) GetFileDownloadURL(cloudID string) (string,
 }
 
 // GetMultipleFileDownloadURLs 获取多个文件的下载URL
-func (s *WechatCloudStorageService) GetMultipleFileDownloadURLs(cloudIDs []string) (map[string]string, error) {
+func (s *WechatCloudStorageService) GetMultipleFileDownloadURLs(ctx context.Context, cloudIDs []string) (urls map[string]string, err error) {
+	return s.GetTempFileURLs(ctx, cloudIDs, 86400) // 24小时有效期
+}
+
+// GetTempFileURLs 获取多个文件的临时下载URL，maxAge为有效期（秒）
+func (s *WechatCloudStorageService) GetTempFileURLs(ctx context.Context, cloudIDs []string, maxAge int) (urls map[string]string, err error) {
 	if len(cloudIDs) == 0 {
 		return nil, fmt.Errorf("文件ID列表不能为空")
 	}
 
+	ctx, span := tracing.Start(ctx, "WechatCloudStorageService.GetTempFileURLs")
+	span.SetAttributes(attribute.Int("cloud.file_count", len(cloudIDs)))
+	start := time.Now()
+	var response BatchDownloadFileResponse
+	responded := false
+	defer func() {
+		observeBatchDownload(response.Errcode, responded, err, time.Since(start))
+		tracing.End(span, err)
+	}()
+
 	// 获取环境ID
 	envID := os.Getenv("ENV_ID")
 	if envID == "" {
@@ -165,7 +193,7 @@ func (s *WechatCloudStorageService) GetMultipleFileDownloadURLs(cloudIDs []strin
 			MaxAge  int    `json:"max_age"`
 		}{
 			FileID: cloudID,
-			MaxAge: 86400, // 24小时有效期
+			MaxAge: maxAge,
 		}
 	}
 
@@ -182,7 +210,7 @@ func (s *WechatCloudStorageService) GetMultipleFileDownloadURLs(cloudIDs []strin
 	}
 
 	// 创建HTTP请求
-	req, err := http.NewRequest("POST", "http://api.weixin.qq.com/tcb/batchdownloadfile", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", "http://api.weixin.qq.com/tcb/batchdownloadfile", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("创建HTTP请求失败: %v", err)
 	}
@@ -190,15 +218,6 @@ func (s *WechatCloudStorageService) GetMultipleFileDownloadURLs(cloudIDs []strin
 	// 设置请求头
 	req.Header.Set("Content-Type", "application/json")
 
-	// 打印请求详情
-	fmt.Printf("=== 微信云存储批量下载URL请求详情 ===\n")
-	fmt.Printf("请求URL: %s\n", req.URL.String())
-	fmt.Printf("请求方法: %s\n", req.Method)
-	fmt.Printf("环境ID: %s\n", envID)
-	fmt.Printf("文件数量: %d\n", len(cloudIDs))
-	fmt.Printf("请求体JSON: %s\n", string(jsonData))
-	fmt.Printf("=== 微信云存储批量下载URL请求详情结束 ===\n")
-
 	// 发送请求
 	resp, err := s.client.Do(req)
 	if err != nil {
@@ -212,17 +231,15 @@ func (s *WechatCloudStorageService) GetMultipleFileDownloadURLs(cloudIDs []strin
 		return nil, fmt.Errorf("读取响应内容失败: %v", err)
 	}
 
-	// 打印响应详情
-	fmt.Printf("=== 微信云存储批量下载URL响应详情 ===\n")
-	fmt.Printf("响应状态码: %d\n", resp.StatusCode)
-	fmt.Printf("响应体: %s\n", string(body))
-	fmt.Printf("=== 微信云存储批量下载URL响应详情结束 ===\n")
-
-	// 解析响应
-	var response BatchDownloadFileResponse
+	// 解析响应，只记录错误码和文件数
 	if err := json.Unmarshal(body, &response); err != nil {
+		slog.DebugContext(ctx, "云存储批量下载URL响应无法解析", "env", envID, "file_count", len(cloudIDs), "status", resp.StatusCode, "size", len(body))
 		return nil, fmt.Errorf("解析响应失败: %v", err)
 	}
+	slog.DebugContext(ctx, "云存储批量下载URL响应", "env", envID, "file_count", len(cloudIDs), "status", resp.StatusCode,
+		"errcode", response.Errcode, "errmsg", response.Errmsg, "result_count", len(response.FileList))
+
+	responded = true
 
 	// 检查错误码
 	if response.Errcode != 0 {
@@ -235,13 +252,216 @@ func (s *WechatCloudStorageService) GetMultipleFileDownloadURLs(cloudIDs []strin
 		if fileInfo.Status == 0 && fileInfo.DownloadURL != "" {
 			result[fileInfo.FileID] = fileInfo.DownloadURL
 		} else {
-			fmt.Printf("警告: 文件 %s 获取下载URL失败: %s\n", fileInfo.FileID, fileInfo.Errmsg)
+			slog.WarnContext(ctx, "文件获取下载URL失败", "cloud_id", fileInfo.FileID, "errmsg", fileInfo.Errmsg)
 		}
 	}
 
 	return result, nil
 }
 
+// observeBatchDownload 记录batchdownloadfile调用指标，未拿到微信响应时按传输错误统计
+func observeBatchDownload(errcode int, responded bool, err error, elapsed time.Duration) {
+	if responded {
+		err = nil
+	}
+	metrics.ObserveWechatCall(metrics.APIBatchDownloadFile, errcode, err, elapsed)
+}
+
+// UploadFileRequest 获取文件上传链接请求结构
+type UploadFileRequest struct {
+	Env  string `json:"env"`
+	Path string `json:"path"`
+}
+
+// UploadFileResponse 获取文件上传链接响应结构
+type UploadFileResponse struct {
+	Errcode       int    `json:"errcode"`
+	Errmsg        string `json:"errmsg"`
+	URL           string `json:"url"`
+	Token         string `json:"token"`
+	Authorization string `json:"authorization"`
+	FileID        string `json:"file_id"`
+	CosFileID     string `json:"cos_file_id"`
+}
+
+// UploadFile 上传文件到云存储：先通过 tcb/uploadfile 获取上传链接和签名，再以表单方式上传文件内容，返回云存储文件ID
+func (s *WechatCloudStorageService) UploadFile(ctx context.Context, path string, data []byte) (fileID string, err error) {
+	ctx, span := tracing.Start(ctx, "WechatCloudStorageService.UploadFile")
+	span.SetAttributes(attribute.String("cloud.path", path), attribute.Int("cloud.file_size", len(data)))
+	defer func() { tracing.End(span, err) }()
+
+	var response UploadFileResponse
+	if err := s.postTcb(ctx, metrics.APIUploadFile, UploadFileRequest{Env: s.GetEnvironmentID(), Path: path}, &response, func() int {
+		return response.Errcode
+	}); err != nil {
+		return "", err
+	}
+	if response.Errcode != 0 {
+		return "", fmt.Errorf("微信云存储API错误: %s (错误码: %d)", response.Errmsg, response.Errcode)
+	}
+	if response.URL == "" || response.FileID == "" {
+		return "", fmt.Errorf("未获取到上传链接")
+	}
+
+	if err := s.postToCos(ctx, path, data, &response); err != nil {
+		// uploadfile 已经占用了文件路径，上传失败时删除，避免留下无内容的文件记录
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		if deleteErr := s.DeleteFiles(cleanupCtx, []string{response.FileID}); deleteErr != nil {
+			slog.WarnContext(ctx, "删除上传失败的云存储文件失败", "file_id", response.FileID, "error", deleteErr)
+		}
+		return "", err
+	}
+
+	slog.InfoContext(ctx, "云存储文件上传成功", "path", path, "file_id", response.FileID, "size", len(data))
+	return response.FileID, nil
+}
+
+// postToCos 按COS表单上传格式上传文件内容，file字段必须放在最后
+func (s *WechatCloudStorageService) postToCos(ctx context.Context, path string, data []byte, response *UploadFileResponse) error {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	fields := [][2]string{
+		{"key", path},
+		{"Signature", response.Authorization},
+		{"x-cos-security-token", response.Token},
+		{"x-cos-meta-fileid", response.CosFileID},
+	}
+	for _, field := range fields {
+		if err := writer.WriteField(field[0], field[1]); err != nil {
+			return fmt.Errorf("构建上传表单失败: %v", err)
+		}
+	}
+	part, err := writer.CreateFormFile("file", path)
+	if err != nil {
+		return fmt.Errorf("构建上传表单失败: %v", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return fmt.Errorf("构建上传表单失败: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("构建上传表单失败: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", response.URL, &buf)
+	if err != nil {
+		return fmt.Errorf("创建HTTP请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := s.uploadClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("上传文件失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("上传文件失败: 状态码 %d, %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// BatchDeleteFileRequest 批量删除文件请求结构
+type BatchDeleteFileRequest struct {
+	Env        string   `json:"env"`
+	FileIDList []string `json:"fileid_list"`
+}
+
+// FileDeleteInfo 文件删除结果
+type FileDeleteInfo struct {
+	FileID string `json:"fileid"`
+	Status int    `json:"status"`
+	Errmsg string `json:"errmsg"`
+}
+
+// BatchDeleteFileResponse 批量删除文件响应结构
+type BatchDeleteFileResponse struct {
+	Errcode    int              `json:"errcode"`
+	Errmsg     string           `json:"errmsg"`
+	DeleteList []FileDeleteInfo `json:"delete_list"`
+}
+
+// DeleteFiles 批量删除云存储文件，任一文件删除失败时返回错误
+func (s *WechatCloudStorageService) DeleteFiles(ctx context.Context, cloudIDs []string) (err error) {
+	if len(cloudIDs) == 0 {
+		return nil
+	}
+
+	ctx, span := tracing.Start(ctx, "WechatCloudStorageService.DeleteFiles")
+	span.SetAttributes(attribute.Int("cloud.file_count", len(cloudIDs)))
+	defer func() { tracing.End(span, err) }()
+
+	var response BatchDeleteFileResponse
+	if err := s.postTcb(ctx, metrics.APIBatchDeleteFile, BatchDeleteFileRequest{Env: s.GetEnvironmentID(), FileIDList: cloudIDs}, &response, func() int {
+		return response.Errcode
+	}); err != nil {
+		return err
+	}
+	if response.Errcode != 0 {
+		return fmt.Errorf("微信云存储API错误: %s (错误码: %d)", response.Errmsg, response.Errcode)
+	}
+
+	failed := 0
+	for _, fileInfo := range response.DeleteList {
+		if fileInfo.Status != 0 {
+			failed++
+			slog.WarnContext(ctx, "云存储文件删除失败", "cloud_id", fileInfo.FileID, "errmsg", fileInfo.Errmsg)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d个文件删除失败", failed)
+	}
+	return nil
+}
+
+// postTcb 调用云存储接口并解析响应，errcode在解析后读取响应中的错误码用于记录指标
+func (s *WechatCloudStorageService) postTcb(ctx context.Context, api string, request interface{}, response interface{}, errcode func() int) (err error) {
+	start := time.Now()
+	responded := false
+	defer func() {
+		observeErr := err
+		code := 0
+		if responded {
+			observeErr = nil
+			code = errcode()
+		}
+		metrics.ObserveWechatCall(api, code, observeErr, time.Since(start))
+	}()
+
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("序列化请求数据失败: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "http://api.weixin.qq.com/tcb/"+api, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("创建HTTP请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应内容失败: %v", err)
+	}
+
+	// 响应中含有上传签名和临时密钥（uploadfile），不记录响应内容
+	if err := json.Unmarshal(body, response); err != nil {
+		slog.DebugContext(ctx, "云存储接口响应无法解析", "api", api, "status", resp.StatusCode, "size", len(body))
+		return fmt.Errorf("解析响应失败: %v", err)
+	}
+	responded = true
+	slog.DebugContext(ctx, "云存储接口响应", "api", api, "status", resp.StatusCode, "errcode", errcode())
+	return nil
+}
+
 // ValidateCloudID 验证云存储文件ID格式
 func (s *WechatCloudStorageService) ValidateCloudID(cloudID string) bool {
 	// 基本的云存储文件ID格式验证
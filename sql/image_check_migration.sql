@@ -28,3 +28,31 @@ UPDATE posts SET image_check_status = 2 WHERE images = '[]' OR images = '' OR im
 
 -- 4. 为有图片的帖子设置待检测状态
 UPDATE posts SET image_check_status = 0 WHERE images != '[]' AND images != '' AND images IS NOT NULL;
+
+-- 5. 超时重试：记录提交次数和最近一次提交时间，供超时检测任务判断是否需要重新提交
+ALTER TABLE image_checks
+    ADD COLUMN attempts INT DEFAULT 1 COMMENT '已提交检测的次数',
+    ADD COLUMN submitted_at TIMESTAMP NULL COMMENT '最近一次提交检测的时间',
+    ADD INDEX idx_status_submitted (status, submitted_at);
+
+-- 6. 回调去重：记录当前检测结果对应回调的推送时间，重复或更早的回调不再覆盖结果
+ALTER TABLE image_checks
+    ADD COLUMN result_time BIGINT DEFAULT 0 COMMENT '当前检测结果对应回调的推送时间（CreateTime）';
+
+-- 7. 各检测策略的结果改为记录在 image_check_details 表（服务启动时自动创建），以下字段不再写入，确认无需保留历史数据后可删除
+-- ALTER TABLE image_checks DROP COLUMN label, DROP COLUMN prob, DROP COLUMN strategy;
+
+-- 8. 头像检测：头像与帖子图片共用检测记录，user_id 为头像所属用户，此时 post_id 为0
+ALTER TABLE image_checks
+    ADD COLUMN user_id BIGINT DEFAULT 0 COMMENT '头像检测关联的用户ID，帖子图片检测为0',
+    ADD INDEX idx_user_id (user_id);
+
+-- 9. 语音检测：语音与图片共用检测记录，media_type 区分媒体类型，comment_id 为语音评论ID（帖子媒体检测为0）
+ALTER TABLE image_checks
+    ADD COLUMN media_type INT DEFAULT 2 COMMENT '媒体类型：1-语音 2-图片',
+    ADD COLUMN comment_id BIGINT DEFAULT 0 COMMENT '语音评论检测关联的评论ID，帖子媒体检测为0',
+    ADD INDEX idx_comment_id (comment_id);
+
+-- 10. 提交次数从0开始计数，首次提交成功后为1（此前新建记录默认为1，未提交的记录也计为已提交一次）
+ALTER TABLE image_checks ALTER COLUMN attempts SET DEFAULT 0;
+UPDATE image_checks SET attempts = 0 WHERE status = 0 AND trace_id = '';